@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/client"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// reconnectDelay is the wait before resuming a dropped tail.
+const reconnectDelay = 2 * time.Second
+
+// stringsFlag is a flag that can be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// outputFlags are the flags of the commands that print requests.
+type outputFlags struct {
+	format  string
+	filters stringsFlag
+	to      string
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", formatSummary, "how requests are printed: summary, curl, http or json")
+	fs.Var(&o.filters, "filter", `print only the requests matching a jq-like condition, e.g. '.Method == "POST"' or '.body.status ~ "^paid"', can be repeated`)
+	fs.StringVar(&o.to, "to", "", "base URL of the curl commands instead of the inbox, e.g. http://localhost:3000")
+}
+
+func (o *outputFlags) printer(c *cli) (*printer, error) {
+	filters := make([]filter, 0, len(o.filters))
+	for _, expr := range o.filters {
+		f, err := parseFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return newPrinter(c.stdout, o.format, c.color, c.apiURL, o.to, filters)
+}
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: inbox %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the flags wherever they are, as in "inbox tail <id> --format curl", and returns the arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func parseInboxID(arg string) (uuid.UUID, error) {
+	id, err := uuid.Parse(arg)
+	if err != nil {
+		return id, fmt.Errorf("invalid inbox ID %q: %w", arg, err)
+	}
+	return id, nil
+}
+
+func createCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("create", "")
+	private := fs.Bool("private", false, "only the owner of the API key can read the inbox")
+	status := fs.Int("status", http.StatusOK, "status code answered to the requests")
+	body := fs.String("body", "", "body answered to the requests")
+	asJSON := fs.Bool("json", false, "print the created inbox as JSON")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	inbox := model.NewInbox()
+	inbox.IsPrivate = *private
+	inbox.Response.Code = *status
+	if *body != "" {
+		inbox.Response.Body = *body
+	}
+	created, err := c.client.CreateInbox(ctx, inbox)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(c.stdout).Encode(created)
+	}
+	fmt.Fprintf(c.stdout, "Created inbox %s\nSend requests to %s\n", created.ID, c.client.InboxURL(created.ID))
+	return nil
+}
+
+func lsCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("ls", "[inbox-id]")
+	limit := fs.Int("limit", 20, "number of requests listed, the latest ones")
+	method := fs.String("method", "", "list only the requests with this method")
+	path := fs.String("path", "", "list only the requests whose path starts with this prefix")
+	var output outputFlags
+	output.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return listInboxes(ctx, c)
+	}
+
+	id, err := parseInboxID(positional[0])
+	if err != nil {
+		return err
+	}
+	p, err := output.printer(c)
+	if err != nil {
+		return err
+	}
+	page, err := c.client.ListRequests(ctx, id, client.ListRequestsOptions{
+		Limit:      *limit,
+		Order:      "desc",
+		Method:     *method,
+		PathPrefix: *path,
+	})
+	if err != nil {
+		return err
+	}
+	// Oldest first, like tail
+	for i := len(page.Results) - 1; i >= 0; i-- {
+		if err := p.print(page.Results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listInboxes(ctx context.Context, c *cli) error {
+	inboxes, err := c.client.ListInboxes(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(inboxes.Results, func(i, j int) bool {
+		return inboxes.Results[i].Timestamp > inboxes.Results[j].Timestamp
+	})
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPRIVATE\tCREATED")
+	for _, inbox := range inboxes.Results {
+		created := time.UnixMilli(inbox.Timestamp).Local().Format(time.DateTime)
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", inbox.ID, inbox.Name, inbox.IsPrivate, created)
+	}
+	return w.Flush()
+}
+
+func tailCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("tail", "<inbox-id>")
+	all := fs.Bool("all", false, "print the requests already received before waiting for new ones")
+	var output outputFlags
+	output.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("an inbox ID is required")
+	}
+	id, err := parseInboxID(positional[0])
+	if err != nil {
+		return err
+	}
+	p, err := output.printer(c)
+	if err != nil {
+		return err
+	}
+
+	last := -1
+	if *all {
+		requests, err := c.client.ListAllRequests(ctx, id, client.ListRequestsOptions{Order: "asc"})
+		if err != nil {
+			return err
+		}
+		for _, req := range requests {
+			if err := p.print(req); err != nil {
+				return err
+			}
+			last = req.ID
+		}
+	}
+	fmt.Fprintf(c.stderr, "Waiting for requests to %s (Ctrl+C to stop)\n", c.client.InboxURL(id))
+	for {
+		last, err = c.client.StreamRequests(ctx, id, last, p.print)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) {
+			return err
+		}
+		fmt.Fprintf(c.stderr, "Stream interrupted, reconnecting: %v\n", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func replayCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("replay", "<inbox-id> <request-id>")
+	to := fs.String("to", "", "URL the request is sent to, e.g. http://localhost:3000/webhook (required)")
+	body := fs.String("body", "", "body sent instead of the captured one")
+	var headers stringsFlag
+	fs.Var(&headers, "H", `header set over the captured ones, "Name: value", can be repeated`)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 || *to == "" {
+		fs.Usage()
+		return errors.New("an inbox ID, a request ID and --to are required")
+	}
+	id, err := parseInboxID(positional[0])
+	if err != nil {
+		return err
+	}
+	requestID, err := strconv.Atoi(positional[1])
+	if err != nil {
+		return fmt.Errorf("invalid request ID %q: %w", positional[1], err)
+	}
+
+	replay := model.ReplayRequest{ToURL: *to, Headers: map[string]string{}}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return fmt.Errorf(`invalid header %q, it must be "Name: value"`, h)
+		}
+		replay.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "body" {
+			replay.Body = body
+		}
+	})
+	resp, err := c.client.ReplayRequest(ctx, id, requestID, replay)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "%s %s -> %d %s\n", resp.Method, resp.URL, resp.Code, http.StatusText(resp.Code))
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.stdout, "%s: %s\n", name, resp.Headers[name])
+	}
+	if resp.Body != "" {
+		fmt.Fprintf(c.stdout, "\n%s\n", resp.Body)
+	}
+	return nil
+}
+
+func rmCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("rm", "<inbox-id>...")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		fs.Usage()
+		return errors.New("at least an inbox ID is required")
+	}
+	ids := make([]uuid.UUID, 0, len(positional))
+	for _, arg := range positional {
+		id, err := parseInboxID(arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		if err := c.client.DeleteInbox(ctx, id); err != nil {
+			return fmt.Errorf("error deleting inbox %s: %w", id, err)
+		}
+		fmt.Fprintf(c.stdout, "Deleted inbox %s\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/tidwall/gjson"
+)
+
+// bodyField is the path prefix of the filters on the JSON body of the request.
+const bodyField = "body"
+
+var filterExpr = regexp.MustCompile(`^\s*(\.\S*?)\s*(?:(==|!=|~|>=|<=|>|<)\s*(.+?))?\s*$`)
+
+// filter is a jq-like condition on a request:
+//
+//	.Method == "POST"
+//	.Headers.Content-Type ~ "json"
+//	.body.order.total > 100
+//	.body.coupon
+//
+// Paths are gjson paths over the request fields, the first segment is case insensitive, and ".body" reads the
+// body as JSON. Without an operator the field must exist and not be false, null or empty. Arrays match when
+// any of their values does.
+type filter struct {
+	body  bool
+	path  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func parseFilter(expr string) (filter, error) {
+	m := filterExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return filter{}, fmt.Errorf(`invalid filter %q, it must be like '.Field == "value"'`, expr)
+	}
+	f := filter{path: strings.TrimPrefix(m[1], "."), op: m[2], value: literal(m[3])}
+	first, rest, _ := strings.Cut(f.path, ".")
+	if strings.EqualFold(first, bodyField) {
+		f.body = true
+		f.path = rest
+	}
+	if f.op != "" && m[3] == "" {
+		return filter{}, fmt.Errorf("invalid filter %q, %s needs a value", expr, f.op)
+	}
+	if f.op == "~" {
+		re, err := regexp.Compile(f.value)
+		if err != nil {
+			return filter{}, fmt.Errorf("invalid filter %q: %w", expr, err)
+		}
+		f.re = re
+	}
+	return f, nil
+}
+
+// literal returns the value of a JSON string literal, other values are kept as written.
+func literal(v string) string {
+	if strings.HasPrefix(v, `"`) {
+		s := ""
+		if err := json.Unmarshal([]byte(v), &s); err == nil {
+			return s
+		}
+	}
+	if strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'") && len(v) > 1 {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// match reports whether the request matches, doc is the request encoded as JSON.
+func (f filter) match(req model.Request, doc string) bool {
+	var result gjson.Result
+	switch {
+	case f.body && f.path == "":
+		body := req.BodyText()
+		result = gjson.Result{Type: gjson.String, Str: body, Raw: strconv.Quote(body)}
+	case f.body:
+		result = gjson.Get(req.BodyText(), f.path)
+	case f.path == "":
+		result = gjson.Parse(doc)
+	default:
+		result = gjson.Get(doc, fieldPath(doc, f.path))
+	}
+	if !result.Exists() {
+		return f.op == "!="
+	}
+	if !result.IsArray() {
+		return f.compare(result)
+	}
+	values := result.Array()
+	if f.op == "!=" {
+		for _, v := range values {
+			if !f.compare(v) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if f.compare(v) {
+			return true
+		}
+	}
+	return f.op == "" && len(values) > 0
+}
+
+func (f filter) compare(v gjson.Result) bool {
+	switch f.op {
+	case "":
+		return v.Type != gjson.False && v.Type != gjson.Null && !(v.Type == gjson.String && v.Str == "")
+	case "==":
+		return equals(v, f.value)
+	case "!=":
+		return !equals(v, f.value)
+	case "~":
+		return f.re.MatchString(v.String())
+	}
+	want, err := strconv.ParseFloat(f.value, 64)
+	if err != nil {
+		return compareOrder(f.op, strings.Compare(v.String(), f.value))
+	}
+	got := v.Float()
+	if v.Type == gjson.String {
+		if got, err = strconv.ParseFloat(v.Str, 64); err != nil {
+			return false
+		}
+	}
+	switch {
+	case got < want:
+		return compareOrder(f.op, -1)
+	case got > want:
+		return compareOrder(f.op, 1)
+	default:
+		return compareOrder(f.op, 0)
+	}
+}
+
+func equals(v gjson.Result, want string) bool {
+	if v.Type == gjson.Number {
+		if n, err := strconv.ParseFloat(want, 64); err == nil {
+			return v.Num == n
+		}
+	}
+	return v.String() == want
+}
+
+func compareOrder(op string, cmp int) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// fieldPath replaces the first segment of the path by the request field with the same name in any case,
+// so ".method" works like ".Method", and header names by their canonical form.
+func fieldPath(doc string, path string) string {
+	first, rest, hasRest := strings.Cut(path, ".")
+	field := first
+	gjson.Parse(doc).ForEach(func(key, _ gjson.Result) bool {
+		if strings.EqualFold(key.Str, first) {
+			field = key.Str
+			return false
+		}
+		return true
+	})
+	if !hasRest {
+		return field
+	}
+	if field == "Headers" {
+		name, tail, hasTail := strings.Cut(rest, ".")
+		rest = textproto.CanonicalMIMEHeaderKey(name)
+		if hasTail {
+			rest += "." + tail
+		}
+	}
+	return field + "." + rest
+}
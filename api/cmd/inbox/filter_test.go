@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestFilter(t *testing.T) {
+	req := model.Request{
+		ID:      3,
+		Method:  "POST",
+		URI:     "/api/v1/inboxes/0194f1b6-5c8a-7c1e-9d4e-3a7d2c5b6e8f/in/orders",
+		Headers: map[string][]string{"Content-Type": {"application/json"}, "X-Tag": {"a", "b"}},
+		Body:    `{"total":20.5,"status":"paid","coupon":"","items":[{"sku":"x"}]}`,
+	}
+	doc, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		expr  string
+		match bool
+	}{
+		{`.Method == "POST"`, true},
+		{`.method=="POST"`, true},
+		{`.Method != "POST"`, false},
+		{`.ID >= 3`, true},
+		{`.ID < 3`, false},
+		{`.Headers.content-type ~ "json$"`, true},
+		{`.Headers.X-Tag == "b"`, true},
+		{`.Headers.X-Tag != "b"`, false},
+		{`.Headers.Authorization`, false},
+		{`.Headers.Authorization != "x"`, true},
+		{`.body.status == 'paid'`, true},
+		{`.body.total > 20`, true},
+		{`.body.total == 20.5`, true},
+		{`.body.items.0.sku == "x"`, true},
+		{`.body.items.#.sku == "x"`, true},
+		{`.body.coupon`, false},
+		{`.body.status`, true},
+		{`.body ~ "paid"`, true},
+		{`.URI ~ "/in/orders$"`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			f, err := parseFilter(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t_util.AssertEquals(t, f.match(req, string(doc)), tc.match)
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	testCases := []string{
+		`Method == "POST"`,
+		`.Method ==`,
+		`.body ~ "("`,
+		``,
+	}
+	for _, expr := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseFilter(expr)
+
+			t_util.AssertError(t, err)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	formatSummary = "summary"
+	formatCurl    = "curl"
+	formatHTTP    = "http"
+	formatJSON    = "json"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorPurple = "\033[35m"
+	colorCyan   = "\033[36m"
+)
+
+var methodColors = map[string]string{
+	http.MethodGet:    colorGreen,
+	http.MethodPost:   colorYellow,
+	http.MethodPut:    colorBlue,
+	http.MethodPatch:  colorCyan,
+	http.MethodDelete: colorRed,
+}
+
+// skippedCurlHeaders are set by curl itself from the command.
+var skippedCurlHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+}
+
+// printer writes the requests that match all its filters.
+type printer struct {
+	w       io.Writer
+	format  string
+	color   bool
+	apiURL  string
+	to      string
+	filters []filter
+}
+
+func newPrinter(w io.Writer, format string, color bool, apiURL string, to string, filters []filter) (*printer, error) {
+	switch format {
+	case formatSummary, formatCurl, formatHTTP, formatJSON:
+	default:
+		return nil, fmt.Errorf("unknown format %q, it must be %s, %s, %s or %s", format, formatSummary, formatCurl, formatHTTP, formatJSON)
+	}
+	return &printer{
+		w:       w,
+		format:  format,
+		color:   color,
+		apiURL:  strings.TrimRight(apiURL, "/"),
+		to:      strings.TrimRight(to, "/"),
+		filters: filters,
+	}, nil
+}
+
+func (p *printer) print(req model.Request) error {
+	doc, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("error encoding request %d: %w", req.ID, err)
+	}
+	for _, f := range p.filters {
+		if !f.match(req, string(doc)) {
+			return nil
+		}
+	}
+	switch p.format {
+	case formatCurl:
+		_, err = io.WriteString(p.w, curlCommand(req, p.requestURL(req))+"\n\n")
+	case formatHTTP:
+		_, err = io.WriteString(p.w, rawHTTP(req)+"\n\n")
+	case formatJSON:
+		_, err = fmt.Fprintf(p.w, "%s\n", doc)
+	default:
+		_, err = io.WriteString(p.w, p.summary(req)+"\n")
+	}
+	return err
+}
+
+// summary is a line like "#3  15:04:05  POST    /orders  application/json  17 B".
+func (p *printer) summary(req model.Request) string {
+	method := fmt.Sprintf("%-7s", req.Method)
+	id := fmt.Sprintf("#%d", req.ID)
+	if p.color {
+		c, ok := methodColors[req.Method]
+		if !ok {
+			c = colorPurple
+		}
+		method = c + method + colorReset
+		id = colorDim + id + colorReset
+	}
+	line := fmt.Sprintf("%s  %s  %s %s", id, time.UnixMilli(req.Timestamp).Local().Format(time.TimeOnly), method, inboxPath(req))
+	if contentType := firstHeader(req, "Content-Type"); contentType != "" {
+		line += "  " + contentType
+	}
+	if req.BodySize > 0 {
+		line += fmt.Sprintf("  %d B", req.BodySize)
+	}
+	return line
+}
+
+// requestURL is the URL of the curl commands, the inbox by default.
+func (p *printer) requestURL(req model.Request) string {
+	if p.to != "" {
+		return p.to + inboxPath(req)
+	}
+	return p.apiURL + req.URI
+}
+
+// inboxPath returns the path and query received by the inbox, after its "/in".
+func inboxPath(req model.Request) string {
+	path := req.InboxPath()
+	if path == "" {
+		path = "/"
+	}
+	if i := strings.Index(req.URI, "?"); i != -1 {
+		path += req.URI[i:]
+	}
+	return path
+}
+
+func firstHeader(req model.Request, name string) string {
+	if values := req.Headers[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func sortedHeaderNames(req model.Request) []string {
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// curlCommand returns a shell command that sends the request again to url.
+func curlCommand(req model.Request, url string) string {
+	parts := []string{"curl -X " + req.Method + " " + shellQuote(url)}
+	for _, name := range sortedHeaderNames(req) {
+		if skippedCurlHeaders[name] {
+			continue
+		}
+		for _, v := range req.Headers[name] {
+			parts = append(parts, "-H "+shellQuote(name+": "+v))
+		}
+	}
+	prefix := ""
+	if req.Body != "" {
+		if req.BodyEncoding == model.BodyEncodingBase64 {
+			// Binary bodies are piped so they are not mangled by the shell
+			prefix = "echo " + shellQuote(req.Body) + " | base64 -d | "
+			parts = append(parts, "--data-binary @-")
+		} else {
+			parts = append(parts, "--data-raw "+shellQuote(req.Body))
+		}
+	}
+	cmd := prefix + strings.Join(parts, " \\\n  ")
+	if req.BodyTruncated {
+		cmd = fmt.Sprintf("# the body was truncated, %d bytes were received\n", req.BodySize) + cmd
+	}
+	return cmd
+}
+
+// rawHTTP returns the request as it was received, binary bodies are written as they are.
+func rawHTTP(req model.Request) string {
+	protocol := req.Protocol
+	if protocol == "" {
+		protocol = "HTTP/1.1"
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %s %s\r\n", req.Method, req.URI, protocol)
+	if req.Host != "" {
+		fmt.Fprintf(b, "Host: %s\r\n", req.Host)
+	}
+	for _, name := range sortedHeaderNames(req) {
+		if name == "Host" {
+			continue
+		}
+		for _, v := range req.Headers[name] {
+			fmt.Fprintf(b, "%s: %s\r\n", name, v)
+		}
+	}
+	b.WriteString("\r\n")
+	body, err := req.RawBody()
+	if err != nil {
+		body = []byte(req.Body)
+	}
+	b.Write(body)
+	return b.String()
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
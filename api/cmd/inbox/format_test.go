@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+const testURI = "/api/v1/inboxes/0194f1b6-5c8a-7c1e-9d4e-3a7d2c5b6e8f/in/orders?page=1"
+
+func newTestRequest() model.Request {
+	return model.Request{
+		ID:           7,
+		Timestamp:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local).UnixMilli(),
+		Method:       "POST",
+		URI:          testURI,
+		Host:         "api.request-inbox.com",
+		Protocol:     "HTTP/1.1",
+		Headers:      map[string][]string{"Content-Type": {"application/json"}, "Content-Length": {"15"}, "X-Note": {"it's"}},
+		Body:         `{"name":"it's"}`,
+		BodyEncoding: model.BodyEncodingUTF8,
+		BodySize:     15,
+	}
+}
+
+func TestCurlCommand(t *testing.T) {
+	got := curlCommand(newTestRequest(), "http://localhost:3000/orders?page=1")
+
+	want := `curl -X POST 'http://localhost:3000/orders?page=1' \
+  -H 'Content-Type: application/json' \
+  -H 'X-Note: it'\''s' \
+  --data-raw '{"name":"it'\''s"}'`
+	t_util.AssertStringEquals(t, got, want)
+}
+
+func TestCurlCommandBinaryBody(t *testing.T) {
+	req := newTestRequest()
+	req.Headers = map[string][]string{}
+	req.Body = "AAEC"
+	req.BodyEncoding = model.BodyEncodingBase64
+	req.BodyTruncated = true
+	req.BodySize = 2048
+
+	got := curlCommand(req, "http://localhost:3000/")
+
+	want := "# the body was truncated, 2048 bytes were received\n" +
+		"echo 'AAEC' | base64 -d | curl -X POST 'http://localhost:3000/' \\\n  --data-binary @-"
+	t_util.AssertStringEquals(t, got, want)
+}
+
+func TestRawHTTP(t *testing.T) {
+	got := rawHTTP(newTestRequest())
+
+	want := "POST " + testURI + " HTTP/1.1\r\n" +
+		"Host: api.request-inbox.com\r\n" +
+		"Content-Length: 15\r\n" +
+		"Content-Type: application/json\r\n" +
+		"X-Note: it's\r\n" +
+		"\r\n" +
+		`{"name":"it's"}`
+	t_util.AssertStringEquals(t, got, want)
+}
+
+func TestPrinter(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		format  string
+		color   bool
+		to      string
+		filters []string
+		want    string
+	}{
+		{desc: "summary", format: formatSummary, want: "#7  03:04:05  POST    /orders?page=1  application/json  15 B\n"},
+		{desc: "colorized summary", format: formatSummary, color: true, want: colorDim + "#7" + colorReset + "  03:04:05  " + colorYellow + "POST   " + colorReset + " /orders?page=1  application/json  15 B\n"},
+		{desc: "curl to the inbox", format: formatCurl, want: "curl -X POST 'https://api.request-inbox.com" + testURI + "'"},
+		{desc: "curl to another URL", format: formatCurl, to: "http://localhost:3000/", want: "curl -X POST 'http://localhost:3000/orders?page=1'"},
+		{desc: "json", format: formatJSON, want: `{"ID":7,`},
+		{desc: "matching filters", format: formatSummary, filters: []string{`.method == "POST"`, `.body.name ~ "it"`}, want: "#7"},
+		{desc: "filtered out", format: formatSummary, filters: []string{`.method == "POST"`, `.body.name == "other"`}, want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			filters := []filter{}
+			for _, expr := range tc.filters {
+				f, err := parseFilter(expr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				filters = append(filters, f)
+			}
+			out := &bytes.Buffer{}
+			p, err := newPrinter(out, tc.format, tc.color, "https://api.request-inbox.com/", tc.to, filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := p.print(newTestRequest()); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.want == "" {
+				t_util.AssertStringEquals(t, out.String(), "")
+				return
+			}
+			t_util.AssertTrue(t, strings.HasPrefix(out.String(), tc.want), "output starts with "+tc.want+", got "+out.String())
+		})
+	}
+}
+
+func TestNewPrinterUnknownFormat(t *testing.T) {
+	_, err := newPrinter(&bytes.Buffer{}, "yaml", false, "", "", nil)
+
+	t_util.AssertError(t, err)
+}
@@ -0,0 +1,126 @@
+// Command inbox manages request inboxes from the terminal.
+//
+// It reads the API URL and key from the INBOX_API_URL and INBOX_API_KEY environment variables or from
+// the api_url and api_key fields of the config file, $XDG_CONFIG_HOME/request-inbox/config.yaml by default
+// or the one set in INBOX_CONFIG.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/jesusnoseq/request-inbox/pkg/client"
+	"github.com/spf13/viper"
+)
+
+const (
+	envPrefix     = "INBOX"
+	configEnv     = "INBOX_CONFIG"
+	apiURLKey     = "api_url"
+	apiKeyKey     = "api_key"
+	defaultAPIURL = "https://api.request-inbox.com"
+)
+
+const usage = `Usage: inbox <command> [flags] [arguments]
+
+Commands:
+  create                           create an inbox and print where it receives requests
+  ls [inbox-id]                    list your inboxes, or the requests of an inbox
+  tail <inbox-id>                  print the requests of an inbox as they arrive
+  replay <inbox-id> <request-id>   send a captured request again to another URL
+  rm <inbox-id>...                 delete inboxes
+
+Run "inbox <command> -h" to see the flags of a command.
+`
+
+type command func(ctx context.Context, cli *cli, args []string) error
+
+var commands = map[string]command{
+	"create": createCommand,
+	"ls":     lsCommand,
+	"tail":   tailCommand,
+	"replay": replayCommand,
+	"rm":     rmCommand,
+}
+
+// cli is what the commands share.
+type cli struct {
+	client *client.Client
+	apiURL string
+	stdout io.Writer
+	stderr io.Writer
+	// color is set when stdout is a terminal and NO_COLOR is not set
+	color bool
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "inbox: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	conf, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inbox: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	apiURL := conf.GetString(apiURLKey)
+	c := &cli{
+		client: client.New(apiURL, client.WithAPIKey(conf.GetString(apiKeyKey)), client.WithUserAgent("request-inbox-cli")),
+		apiURL: apiURL,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		color:  isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+	}
+	err = cmd(ctx, c, os.Args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "inbox %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig reads the config file, when there is one, and the environment, which takes precedence.
+func loadConfig() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetDefault(apiURLKey, defaultAPIURL)
+	v.SetDefault(apiKeyKey, "")
+	v.SetEnvPrefix(envPrefix)
+	v.AutomaticEnv()
+
+	path := os.Getenv(configEnv)
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return v, nil
+		}
+		path = filepath.Join(dir, "request-inbox", "config.yaml")
+	}
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	return v, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -17,12 +17,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
 	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/delivery"
 	"github.com/jesusnoseq/request-inbox/pkg/handler"
 	"github.com/jesusnoseq/request-inbox/pkg/handler/apikey"
+	"github.com/jesusnoseq/request-inbox/pkg/handler/email"
 	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
 	"github.com/jesusnoseq/request-inbox/pkg/login"
 	"github.com/jesusnoseq/request-inbox/pkg/login/provider"
+	"github.com/jesusnoseq/request-inbox/pkg/notify"
+	"github.com/jesusnoseq/request-inbox/pkg/retention"
 	"github.com/jesusnoseq/request-inbox/pkg/route"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
 )
 
 func main() {
@@ -71,7 +76,7 @@ func startServer() {
 		Addr:           ":" + config.GetString(config.APIHTTPPort),
 		Handler:        r,
 		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+		WriteTimeout:   handler.ServerWriteTimeout,
 		MaxHeaderBytes: 1 << 20,
 	}
 	go func() {
@@ -101,16 +106,31 @@ func getRouter() (*gin.Engine, func()) {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     config.GetStringSlice(config.CORSAllowOrigins),
 		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", handler.LastEventIDHeader},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           10 * time.Minute,
 	}))
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 	dao, err := database.NewRepository(ctx, database.GetDatabaseEngine(config.GetString(config.DBEngine)))
+	var queue delivery.Queue
+	var notifier *notify.Notifier
 	closer := func() {
-		err := dao.Close(ctx)
+		cancel()
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+		if queue != nil {
+			if err := queue.Close(shutdownCtx); err != nil {
+				slog.Error("error closing callback delivery queue", "error", err)
+			}
+		}
+		if notifier != nil {
+			if err := notifier.Close(shutdownCtx); err != nil {
+				slog.Error("error waiting for notifications", "error", err)
+			}
+		}
+		err := dao.Close(context.Background())
 		if err != nil {
 			log.Fatal("error closing DB:", err)
 		}
@@ -119,6 +139,22 @@ func getRouter() (*gin.Engine, func()) {
 		log.Fatal("failed to obtain Repository:", err)
 	}
 
+	// A lambda is frozen between invocations, so it relies on the pruning done on write
+	sweepInterval := config.GetInt(config.RetentionSweepIntervalSeconds)
+	if config.GetString(config.APIMode) == config.APIModeServer && sweepInterval > 0 {
+		retention.NewSweeper(dao, time.Duration(sweepInterval)*time.Second).Start(ctx)
+	}
+
+	hub := stream.NewHub()
+	queue = delivery.NewQueue(dao, hub)
+
+	emailNotifier := notify.NewEmailNotifier(dao, notify.NewMailer())
+	notifier = notify.NewNotifier(emailNotifier)
+	digestInterval := config.GetInt(config.EmailDigestSweepIntervalSeconds)
+	if emailNotifier != nil && config.GetString(config.APIMode) == config.APIModeServer && digestInterval > 0 {
+		emailNotifier.Start(ctx, time.Duration(digestInterval)*time.Second)
+	}
+
 	eventTracker, err := instrumentation.NewEventTracker()
 	if err != nil {
 		log.Fatal("failed to initialize EventTracker:", err)
@@ -131,12 +167,15 @@ func getRouter() (*gin.Engine, func()) {
 	lh := login.NewLoginHandler(dao, provider.NewProviderManager(), eventTracker)
 	route.SetLoginRoutes(r, lh)
 
-	ih := handler.NewInboxHandler(dao, eventTracker)
+	ih := handler.NewInboxHandler(dao, eventTracker, hub, queue, notifier)
 	route.SetInboxRoutes(r, ih)
 
 	akh := apikey.NewAPIKeyHandler(dao)
 	route.SetAPIKeyRoutes(r, akh)
 
+	eh := email.NewEmailHandler(dao)
+	route.SetEmailRoutes(r, eh)
+
 	route.SetUtilityRoutes(r, handler.NewHealthHandler(), handler.NewUtilityHandler())
 
 	return r, closer
@@ -0,0 +1,139 @@
+package assertion
+
+import (
+	"fmt"
+	"net/textproto"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/tidwall/gjson"
+)
+
+const missing = "<missing>"
+
+// Assertion is a model.Assertion with its header regular expressions compiled, so a wait evaluates it again
+// for every new request without compiling them each time.
+type Assertion struct {
+	model.Assertion
+	// headerRegexes are the compiled Regex of Matcher.Headers by index, nil when it is empty
+	headerRegexes []*regexp.Regexp
+}
+
+func Compile(a model.Assertion) (Assertion, error) {
+	compiled := Assertion{Assertion: a, headerRegexes: make([]*regexp.Regexp, len(a.Matcher.Headers))}
+	for i, h := range a.Matcher.Headers {
+		if h.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(h.Regex)
+		if err != nil {
+			return Assertion{}, fmt.Errorf("header %s regex is not valid: %w", h.Name, err)
+		}
+		compiled.headerRegexes[i] = re
+	}
+	return compiled, nil
+}
+
+// Match returns how the request differs from the matcher, nothing when it matches.
+func (a Assertion) Match(req model.Request) []model.Mismatch {
+	m := a.Matcher
+	diff := []model.Mismatch{}
+	if m.Method != "" && !strings.EqualFold(m.Method, req.Method) {
+		diff = append(diff, model.Mismatch{Field: "method", Expected: m.Method, Actual: req.Method})
+	}
+	if m.Path != "" {
+		p := req.InboxPath()
+		if p == "" {
+			p = "/"
+		}
+		if ok, _ := path.Match(m.Path, p); !ok {
+			diff = append(diff, model.Mismatch{Field: "path", Expected: m.Path, Actual: p})
+		}
+	}
+	for i, h := range m.Headers {
+		values, ok := req.Headers[textproto.CanonicalMIMEHeaderKey(h.Name)]
+		if !ok || !matchHeader(h, a.headerRegexes[i], values) {
+			actual := missing
+			if ok {
+				actual = strings.Join(values, ", ")
+			}
+			diff = append(diff, model.Mismatch{Field: "header " + h.Name, Expected: expectedHeader(h), Actual: actual})
+		}
+	}
+	if len(m.Body) > 0 {
+		body := req.BodyText()
+		for _, b := range m.Body {
+			result := gjson.Get(body, b.Path)
+			if result.Exists() && (b.Value == "" || result.String() == b.Value) {
+				continue
+			}
+			expected := b.Value
+			if expected == "" {
+				expected = "<present>"
+			}
+			actual := missing
+			if result.Exists() {
+				actual = result.String()
+			}
+			diff = append(diff, model.Mismatch{Field: "body " + b.Path, Expected: expected, Actual: actual})
+		}
+	}
+	return diff
+}
+
+func matchHeader(h model.HeaderMatcher, re *regexp.Regexp, values []string) bool {
+	for _, v := range values {
+		if (h.Equals == "" || v == h.Equals) && (re == nil || re.MatchString(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func expectedHeader(h model.HeaderMatcher) string {
+	switch {
+	case h.Equals != "" && h.Regex != "":
+		return h.Equals + " matching /" + h.Regex + "/"
+	case h.Equals != "":
+		return h.Equals
+	case h.Regex != "":
+		return "/" + h.Regex + "/"
+	default:
+		return "<present>"
+	}
+}
+
+// Evaluate checks the assertion against the requests, which must be ordered by ID.
+// When it fails, the closest request is the latest one with fewer differences.
+func (a Assertion) Evaluate(requests []model.Request) model.AssertionResult {
+	result := model.AssertionResult{
+		Expected: a.ExpectedCount(),
+		Requests: []model.Request{},
+	}
+	var closest *model.ClosestRequest
+	for _, req := range requests {
+		if a.Since > 0 && req.Timestamp < a.Since {
+			continue
+		}
+		diff := a.Match(req)
+		if len(diff) == 0 {
+			result.Requests = append(result.Requests, req)
+			continue
+		}
+		if closest == nil || len(diff) <= len(closest.Diff) {
+			closest = &model.ClosestRequest{Request: req, Diff: diff}
+		}
+	}
+	result.Matched = len(result.Requests)
+	if a.Exactly {
+		result.Passed = result.Matched == result.Expected
+	} else {
+		result.Passed = result.Matched >= result.Expected
+	}
+	if !result.Passed {
+		result.Closest = closest
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package assertion
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func newRequest(id int, method, path string, body string) model.Request {
+	return model.Request{
+		ID:        id,
+		Timestamp: int64(1000 + id),
+		Method:    method,
+		URI:       "/api/v1/inboxes/0194f1b6-5c8a-7c1e-9d4e-3a7d2c5b6e8f/in" + path,
+		Headers:   map[string][]string{"Content-Type": {"application/json"}, "X-Trace-Id": {"abc-123"}},
+		Body:      body,
+	}
+}
+
+func mustCompile(t *testing.T, a model.Assertion) Assertion {
+	t.Helper()
+	compiled, err := Compile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiled
+}
+
+func TestCompileInvalidRegex(t *testing.T) {
+	_, err := Compile(model.Assertion{Matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "X-Trace-Id", Regex: "("}}}})
+
+	t_util.AssertTrue(t, err != nil, "invalid regex is rejected")
+}
+
+func TestMatch(t *testing.T) {
+	req := newRequest(0, http.MethodPost, "/orders/7", `{"status":"paid","items":[{"sku":"a"}]}`)
+	testCases := []struct {
+		desc    string
+		matcher model.RequestMatcher
+		diff    []model.Mismatch
+	}{
+		{desc: "Empty matcher", matcher: model.RequestMatcher{}, diff: []model.Mismatch{}},
+		{desc: "Method is case insensitive", matcher: model.RequestMatcher{Method: "post"}, diff: []model.Mismatch{}},
+		{desc: "Path glob", matcher: model.RequestMatcher{Path: "/orders/*"}, diff: []model.Mismatch{}},
+		{desc: "Header equals", matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "x-trace-id", Equals: "abc-123"}}}, diff: []model.Mismatch{}},
+		{desc: "Header regex", matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "X-Trace-Id", Regex: "^abc-[0-9]+$"}}}, diff: []model.Mismatch{}},
+		{desc: "Body value", matcher: model.RequestMatcher{Body: []model.BodyMatcher{{Path: "items.0.sku", Value: "a"}, {Path: "status"}}}, diff: []model.Mismatch{}},
+		{
+			desc:    "Different method and path",
+			matcher: model.RequestMatcher{Method: http.MethodGet, Path: "/users/*"},
+			diff: []model.Mismatch{
+				{Field: "method", Expected: http.MethodGet, Actual: http.MethodPost},
+				{Field: "path", Expected: "/users/*", Actual: "/orders/7"},
+			},
+		},
+		{
+			desc:    "Missing header",
+			matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "Authorization"}}},
+			diff:    []model.Mismatch{{Field: "header Authorization", Expected: "<present>", Actual: "<missing>"}},
+		},
+		{
+			desc:    "Header regex does not match",
+			matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "X-Trace-Id", Regex: "^xyz"}}},
+			diff:    []model.Mismatch{{Field: "header X-Trace-Id", Expected: "/^xyz/", Actual: "abc-123"}},
+		},
+		{
+			desc:    "Body mismatches",
+			matcher: model.RequestMatcher{Body: []model.BodyMatcher{{Path: "status", Value: "refunded"}, {Path: "total"}}},
+			diff: []model.Mismatch{
+				{Field: "body status", Expected: "refunded", Actual: "paid"},
+				{Field: "body total", Expected: "<present>", Actual: "<missing>"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := mustCompile(t, model.Assertion{Matcher: tc.matcher}).Match(req)
+
+			t_util.AssertEqualsAsJson(t, got, tc.diff)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	requests := []model.Request{
+		newRequest(0, http.MethodPost, "/orders", `{"status":"paid"}`),
+		newRequest(1, http.MethodPost, "/orders", `{"status":"pending"}`),
+		newRequest(2, http.MethodGet, "/orders", ``),
+		newRequest(3, http.MethodPost, "/orders", `{"status":"paid"}`),
+	}
+	paid := model.RequestMatcher{Method: http.MethodPost, Body: []model.BodyMatcher{{Path: "status", Value: "paid"}}}
+	testCases := []struct {
+		desc      string
+		assertion model.Assertion
+		passed    bool
+		matched   []int
+		closest   int
+	}{
+		{desc: "At least one by default", assertion: model.Assertion{Matcher: paid}, passed: true, matched: []int{0, 3}},
+		{desc: "At least count", assertion: model.Assertion{Matcher: paid, Count: 3}, passed: false, matched: []int{0, 3}, closest: 1},
+		{desc: "Exactly count", assertion: model.Assertion{Matcher: paid, Count: 2, Exactly: true}, passed: true, matched: []int{0, 3}},
+		{desc: "Exactly too many", assertion: model.Assertion{Matcher: paid, Count: 1, Exactly: true}, passed: false, matched: []int{0, 3}, closest: 1},
+		{desc: "Exactly none", assertion: model.Assertion{Matcher: model.RequestMatcher{Method: http.MethodDelete}, Exactly: true}, passed: true, matched: []int{}},
+		{desc: "Since timestamp", assertion: model.Assertion{Matcher: paid, Since: 1002}, passed: true, matched: []int{3}},
+		{desc: "Closest is the latest with fewer differences", assertion: model.Assertion{Matcher: model.RequestMatcher{Method: http.MethodGet, Path: "/users"}}, passed: false, matched: []int{}, closest: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := mustCompile(t, tc.assertion).Evaluate(requests)
+
+			t_util.AssertEquals(t, got.Passed, tc.passed)
+			t_util.AssertEquals(t, got.Matched, len(tc.matched))
+			ids := []int{}
+			for _, req := range got.Requests {
+				ids = append(ids, req.ID)
+			}
+			t_util.AssertEqualsAsJson(t, ids, tc.matched)
+			if tc.passed {
+				t_util.AssertTrue(t, got.Closest == nil, "no closest request")
+				return
+			}
+			t_util.AssertEquals(t, got.Closest.Request.ID, tc.closest)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package body
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+var ErrTooManyEncodings = errors.New("too many content encodings")
+
+// Read reads r into the body fields of req keeping up to maxSize bytes, a maxSize lower than 1 means no limit.
+// When contentEncoding is set the decompressed body is also stored, decoding errors leave it empty.
+func Read(req *model.Request, r io.Reader, contentEncoding string, maxSize int64) error {
+	raw, size, truncated, err := readLimited(r, maxSize)
+	if err != nil {
+		return err
+	}
+	req.Body, req.BodyEncoding = model.EncodeBody(raw)
+	req.BodySize = size
+	req.BodyTruncated = truncated
+	req.DecodedBody, req.DecodedBodyEncoding, req.DecodedBodyTruncated = "", "", false
+
+	// A truncated stream can not be decompressed
+	if truncated || !hasContentEncoding(contentEncoding) {
+		return nil
+	}
+	decoded, decodedTruncated, err := Decode(raw, contentEncoding, maxSize)
+	if err != nil {
+		return nil
+	}
+	req.DecodedBody, req.DecodedBodyEncoding = model.EncodeBody(decoded)
+	req.DecodedBodyTruncated = decodedTruncated
+	return nil
+}
+
+// maxEncodings is the number of stacked content encodings that are decoded, each one can multiply the size.
+const maxEncodings = 2
+
+// Decode decompresses b following the Content-Encoding header value, keeping up to maxSize bytes.
+// Every decoding step is limited, so a small body can not decompress into a huge one.
+func Decode(b []byte, contentEncoding string, maxSize int64) ([]byte, bool, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	if len(encodings) > maxEncodings {
+		return nil, false, fmt.Errorf("%w: %q", ErrTooManyEncodings, contentEncoding)
+	}
+	var r io.Reader = bytes.NewReader(b)
+	limits := make([]*io.LimitedReader, 0, len(encodings))
+	// Encodings are listed in the order they were applied
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoder, err := newDecoder(strings.ToLower(strings.TrimSpace(encodings[i])), r)
+		if err != nil {
+			return nil, false, err
+		}
+		r = decoder
+		if maxSize > 0 {
+			limit := &io.LimitedReader{R: r, N: maxSize + 1}
+			limits = append(limits, limit)
+			r = limit
+		}
+	}
+	decoded, err := io.ReadAll(r)
+	truncated := false
+	for _, limit := range limits {
+		truncated = truncated || limit.N == 0
+	}
+	// A step cut at the limit leaves the next one with an incomplete stream
+	if err != nil && !truncated {
+		return nil, false, fmt.Errorf("error decoding %s body: %w", contentEncoding, err)
+	}
+	if maxSize > 0 && int64(len(decoded)) > maxSize {
+		return decoded[:maxSize], true, nil
+	}
+	return decoded, truncated, nil
+}
+
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, encoding)
+	}
+}
+
+// newDeflateReader reads zlib streams as the RFC says, but some clients send raw deflate data.
+// The zlib header is peeked so the stream is not buffered.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && isZlibHeader(header) {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// isZlibHeader reports whether h starts a zlib stream of deflate data without a preset dictionary (RFC 1950).
+func isZlibHeader(h []byte) bool {
+	cmf, flg := h[0], h[1]
+	return cmf&0x0f == 8 && cmf>>4 <= 7 && flg&0x20 == 0 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
+
+func hasContentEncoding(contentEncoding string) bool {
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			return true
+		}
+	}
+	return false
+}
+
+// readLimited reads up to maxSize bytes and returns the total size of r.
+func readLimited(r io.Reader, maxSize int64) ([]byte, int64, bool, error) {
+	if maxSize < 1 {
+		b, err := io.ReadAll(r)
+		return b, int64(len(b)), false, err
+	}
+	b, err := io.ReadAll(io.LimitReader(r, maxSize))
+	if err != nil {
+		return nil, 0, false, err
+	}
+	rest, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return b, int64(len(b)) + rest, rest > 0, nil
+}
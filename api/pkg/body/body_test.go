@@ -0,0 +1,220 @@
+package body_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/jesusnoseq/request-inbox/pkg/body"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+func compress(t *testing.T, encoding string, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	text := []byte(`{"message":"hello"}`)
+	binary := []byte{0x00, 0xff, 0xfe, 0x01}
+	gzipped := compress(t, "gzip", text)
+
+	testCases := []struct {
+		desc            string
+		body            []byte
+		contentEncoding string
+		maxSize         int64
+		expected        model.Request
+	}{
+		{
+			desc:     "text body",
+			body:     text,
+			expected: model.Request{Body: string(text), BodyEncoding: model.BodyEncodingUTF8, BodySize: int64(len(text))},
+		},
+		{
+			desc:     "binary body",
+			body:     binary,
+			expected: model.Request{Body: "AP/+AQ==", BodyEncoding: model.BodyEncodingBase64, BodySize: 4},
+		},
+		{
+			desc:     "truncated body",
+			body:     text,
+			maxSize:  5,
+			expected: model.Request{Body: `{"mes`, BodyEncoding: model.BodyEncodingUTF8, BodySize: int64(len(text)), BodyTruncated: true},
+		},
+		{
+			desc:            "gzip body",
+			body:            gzipped,
+			contentEncoding: "gzip",
+			expected: model.Request{
+				BodyEncoding: model.BodyEncodingBase64, BodySize: int64(len(gzipped)),
+				DecodedBody: string(text), DecodedBodyEncoding: model.BodyEncodingUTF8,
+			},
+		},
+		{
+			desc:            "truncated gzip body is not decoded",
+			body:            gzipped,
+			contentEncoding: "gzip",
+			maxSize:         5,
+			expected:        model.Request{BodyEncoding: model.BodyEncodingBase64, BodySize: int64(len(gzipped)), BodyTruncated: true},
+		},
+		{
+			desc:            "unsupported encoding is not decoded",
+			body:            text,
+			contentEncoding: "compress",
+			expected:        model.Request{Body: string(text), BodyEncoding: model.BodyEncodingUTF8, BodySize: int64(len(text))},
+		},
+		{
+			desc:            "invalid gzip body is not decoded",
+			body:            text,
+			contentEncoding: "gzip",
+			expected:        model.Request{Body: string(text), BodyEncoding: model.BodyEncodingUTF8, BodySize: int64(len(text))},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req := model.Request{}
+			err := body.Read(&req, bytes.NewReader(tc.body), tc.contentEncoding, tc.maxSize)
+			if err != nil {
+				t.Fatalf("Read() unexpected error: %v", err)
+			}
+			if tc.expected.BodyEncoding == model.BodyEncodingUTF8 && req.Body != tc.expected.Body {
+				t.Errorf("Read() Body = %q, want %q", req.Body, tc.expected.Body)
+			}
+			if req.BodyEncoding != tc.expected.BodyEncoding {
+				t.Errorf("Read() BodyEncoding = %q, want %q", req.BodyEncoding, tc.expected.BodyEncoding)
+			}
+			if req.BodySize != tc.expected.BodySize {
+				t.Errorf("Read() BodySize = %d, want %d", req.BodySize, tc.expected.BodySize)
+			}
+			if req.BodyTruncated != tc.expected.BodyTruncated {
+				t.Errorf("Read() BodyTruncated = %t, want %t", req.BodyTruncated, tc.expected.BodyTruncated)
+			}
+			if req.DecodedBody != tc.expected.DecodedBody {
+				t.Errorf("Read() DecodedBody = %q, want %q", req.DecodedBody, tc.expected.DecodedBody)
+			}
+			if req.DecodedBodyEncoding != tc.expected.DecodedBodyEncoding {
+				t.Errorf("Read() DecodedBodyEncoding = %q, want %q", req.DecodedBodyEncoding, tc.expected.DecodedBodyEncoding)
+			}
+			raw, err := req.RawBody()
+			if err != nil {
+				t.Fatalf("RawBody() unexpected error: %v", err)
+			}
+			if !bytes.HasPrefix(tc.body, raw) {
+				t.Errorf("RawBody() = %v, want a prefix of %v", raw, tc.body)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	text := []byte(strings.Repeat("request inbox ", 10))
+	testCases := []struct {
+		desc            string
+		body            []byte
+		contentEncoding string
+	}{
+		{"gzip", compress(t, "gzip", text), "gzip"},
+		{"x-gzip", compress(t, "gzip", text), "x-gzip"},
+		{"deflate", compress(t, "deflate", text), "deflate"},
+		{"raw deflate", compress(t, "raw-deflate", text), "deflate"},
+		{"brotli", compress(t, "br", text), "br"},
+		{"identity", text, "identity"},
+		{"several encodings", compress(t, "br", compress(t, "gzip", text)), "gzip, BR"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			decoded, truncated, err := body.Decode(tc.body, tc.contentEncoding, 0)
+			if err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if truncated {
+				t.Error("Decode() should not truncate without limit")
+			}
+			if !bytes.Equal(decoded, text) {
+				t.Errorf("Decode() = %q, want %q", decoded, text)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncates(t *testing.T) {
+	text := bytes.Repeat([]byte("a"), 1000)
+	decoded, truncated, err := body.Decode(compress(t, "gzip", text), "gzip", 10)
+	if err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if !truncated {
+		t.Error("Decode() should be truncated")
+	}
+	if len(decoded) != 10 {
+		t.Errorf("Decode() len = %d, want %d", len(decoded), 10)
+	}
+}
+
+func TestDecodeNestedBomb(t *testing.T) {
+	bomb := compress(t, "gzip", compress(t, "deflate", make([]byte, 32<<20)))
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	decoded, truncated, err := body.Decode(bomb, "deflate, gzip", 1024)
+
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if !truncated {
+		t.Error("Decode() should be truncated")
+	}
+	if len(decoded) != 1024 {
+		t.Errorf("Decode() len = %d, want %d", len(decoded), 1024)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 4<<20 {
+		t.Errorf("Decode() allocated %d bytes for a %d bytes body", allocated, len(bomb))
+	}
+}
+
+func TestDecodeTooManyEncodings(t *testing.T) {
+	_, _, err := body.Decode([]byte("body"), "deflate, gzip, gzip", 0)
+	if !errors.Is(err, body.ErrTooManyEncodings) {
+		t.Errorf("Decode() error = %v, want %v", err, body.ErrTooManyEncodings)
+	}
+}
+
+func TestDecodeUnsupportedEncoding(t *testing.T) {
+	_, _, err := body.Decode([]byte("body"), "compress", 0)
+	if !errors.Is(err, body.ErrUnsupportedEncoding) {
+		t.Errorf("Decode() error = %v, want %v", err, body.ErrUnsupportedEncoding)
+	}
+}
@@ -0,0 +1,80 @@
+package body
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	FormURLEncodedContentType = "application/x-www-form-urlencoded"
+	MultipartFormContentType  = "multipart/form-data"
+)
+
+// ParseForm parses form bodies, it returns a nil form when the content type is not a form.
+// File contents are returned apart, in the same order as the form files.
+func ParseForm(contentType string, b []byte) (*model.Form, []model.RequestFile, error) {
+	if contentType == "" {
+		return nil, nil, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing content type: %w", err)
+	}
+	switch mediaType {
+	case FormURLEncodedContentType:
+		values, err := url.ParseQuery(string(b))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing form: %w", err)
+		}
+		return &model.Form{Fields: values, Files: []model.FormFile{}}, nil, nil
+	case MultipartFormContentType:
+		return parseMultipart(b, params["boundary"])
+	default:
+		return nil, nil, nil
+	}
+}
+
+func parseMultipart(b []byte, boundary string) (*model.Form, []model.RequestFile, error) {
+	if boundary == "" {
+		return nil, nil, errors.New("error parsing multipart form: missing boundary")
+	}
+	form := &model.Form{Fields: map[string][]string{}, Files: []model.FormFile{}}
+	files := []model.RequestFile{}
+	mr := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return form, files, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing multipart form: %w", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading multipart form part %q: %w", part.FormName(), err)
+		}
+		if part.FileName() == "" {
+			form.Fields[part.FormName()] = append(form.Fields[part.FormName()], string(content))
+			continue
+		}
+		sum := sha256.Sum256(content)
+		file := model.FormFile{
+			FieldName:   part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        int64(len(content)),
+			SHA256:      hex.EncodeToString(sum[:]),
+		}
+		form.Files = append(form.Files, file)
+		files = append(files, model.RequestFile{FormFile: file, Content: content})
+	}
+}
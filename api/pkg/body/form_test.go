@@ -0,0 +1,102 @@
+package body_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime/multipart"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jesusnoseq/request-inbox/pkg/body"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+func TestParseFormURLEncoded(t *testing.T) {
+	form, files, err := body.ParseForm("application/x-www-form-urlencoded; charset=utf-8", []byte("name=inbox&tag=a&tag=b"))
+	if err != nil {
+		t.Fatalf("ParseForm() unexpected error: %v", err)
+	}
+	expected := &model.Form{
+		Fields: map[string][]string{"name": {"inbox"}, "tag": {"a", "b"}},
+		Files:  []model.FormFile{},
+	}
+	if diff := cmp.Diff(expected, form); diff != "" {
+		t.Errorf("ParseForm() mismatch (-want +got):\n%s", diff)
+	}
+	if len(files) != 0 {
+		t.Errorf("ParseForm() files = %d, want 0", len(files))
+	}
+}
+
+func TestParseFormMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("name", "inbox"); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte{0x89, 0x50, 0x4e, 0x47}
+	fw, err := mw.CreateFormFile("image", "image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	form, files, err := body.ParseForm(mw.FormDataContentType(), buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseForm() unexpected error: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	expectedFile := model.FormFile{
+		FieldName:   "image",
+		Filename:    "image.png",
+		ContentType: "application/octet-stream",
+		Size:        int64(len(content)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	expected := &model.Form{
+		Fields: map[string][]string{"name": {"inbox"}},
+		Files:  []model.FormFile{expectedFile},
+	}
+	if diff := cmp.Diff(expected, form); diff != "" {
+		t.Errorf("ParseForm() mismatch (-want +got):\n%s", diff)
+	}
+	expectedFiles := []model.RequestFile{{FormFile: expectedFile, Content: content}}
+	if diff := cmp.Diff(expectedFiles, files); diff != "" {
+		t.Errorf("ParseForm() files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseFormNotAForm(t *testing.T) {
+	for _, contentType := range []string{"", "application/json", "text/plain; charset=utf-8"} {
+		form, files, err := body.ParseForm(contentType, []byte(`{"name":"inbox"}`))
+		if err != nil || form != nil || files != nil {
+			t.Errorf("ParseForm(%q) = %v, %v, %v, want nil values", contentType, form, files, err)
+		}
+	}
+}
+
+func TestParseFormInvalidMultipart(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		contentType string
+		body        string
+	}{
+		{"missing boundary", "multipart/form-data", "--boundary--"},
+		{"truncated body", "multipart/form-data; boundary=boundary", "--boundary\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\nval"},
+		{"invalid content type", "multipart/form-data; boundary", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, _, err := body.ParseForm(tc.contentType, []byte(tc.body))
+			if err == nil {
+				t.Error("ParseForm() expected an error")
+			}
+		})
+	}
+}
@@ -14,10 +14,19 @@ import (
 	"github.com/jesusnoseq/request-inbox/pkg/dynamic_response"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
 	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+	"github.com/jesusnoseq/request-inbox/pkg/signature"
 )
 
 func SendCallbacks(c context.Context, inbox model.Inbox, request model.Request) []model.CallbackResponse {
+	callbackResponse, _ := Deliver(c, inbox, request)
+	return callbackResponse
+}
+
+// Deliver sends the callbacks of the inbox with their retry policies. It returns the response of each callback
+// and the dead letters of the callbacks that failed every attempt, their RequestID must be set by the caller.
+func Deliver(c context.Context, inbox model.Inbox, request model.Request) ([]model.CallbackResponse, []model.DeadLetter) {
 	callbackResponse := make([]model.CallbackResponse, len(inbox.Callbacks))
+	deadLetters := make([]*model.DeadLetter, len(inbox.Callbacks))
 	var wg sync.WaitGroup
 
 	for k := range inbox.Callbacks {
@@ -48,7 +57,7 @@ func SendCallbacks(c context.Context, inbox model.Inbox, request model.Request)
 				return
 			}
 
-			cbResp := SendCallback(inbox, k, cb, request)
+			cbResp := SendCallbackWithRetry(c, inbox, k, cb, request)
 			slog.Info("callback response received",
 				"inbox_id", inbox.ID,
 				"callback_index", k,
@@ -56,13 +65,47 @@ func SendCallbacks(c context.Context, inbox model.Inbox, request model.Request)
 				"method", cbResp.Method,
 				"status_code", cbResp.Code,
 				"error", cbResp.Error,
+				"attempts", len(cbResp.Attempts),
 				"response_body", cbResp.Body)
 			callbackResponse[k] = cbResp
+			if IsRetryable(cb.Retry, cbResp) {
+				deadLetters[k] = &model.DeadLetter{
+					Timestamp:     time.Now().UnixMilli(),
+					CallbackIndex: k,
+					Callback:      withForwardedHeaders(cb, request),
+					Response:      cbResp,
+				}
+			}
 		}(k)
 	}
 	wg.Wait()
 
-	return callbackResponse
+	failed := []model.DeadLetter{}
+	for _, dl := range deadLetters {
+		if dl != nil {
+			failed = append(failed, *dl)
+		}
+	}
+	return callbackResponse, failed
+}
+
+// withForwardedHeaders copies the forwarded request headers into the callback, so it can be sent again without the request.
+func withForwardedHeaders(cb model.Callback, request model.Request) model.Callback {
+	if !cb.IsForwardingHeaders {
+		return cb
+	}
+	headers := make(map[string]string, len(request.Headers)+len(cb.Headers))
+	for key, values := range request.Headers {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	for key, value := range cb.Headers {
+		headers[key] = value
+	}
+	cb.Headers = headers
+	cb.IsForwardingHeaders = false
+	return cb
 }
 
 func SendCallback(inbox model.Inbox, k int, c model.Callback, request model.Request) model.CallbackResponse {
@@ -75,6 +118,24 @@ func SendCallback(inbox model.Inbox, k int, c model.Callback, request model.Requ
 		Headers: make(map[string]string),
 	}
 
+	resp, respBody, err := send(inbox, k, c, request)
+	if err != nil {
+		response.Error = err.Error()
+		return response
+	}
+
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			response.Headers[key] = values[0]
+		}
+	}
+	response.Code = resp.StatusCode
+	response.Body = string(respBody)
+	return response
+}
+
+// send sends the callback and returns the response, its body is already read and closed.
+func send(inbox model.Inbox, k int, c model.Callback, request model.Request) (*http.Response, []byte, error) {
 	callbackCopy := c
 
 	timeout := time.Duration(config.GetInt(config.CallbackTimeoutSeconds)) * time.Second
@@ -94,8 +155,7 @@ func SendCallback(inbox model.Inbox, k int, c model.Callback, request model.Requ
 
 	req, err := http.NewRequest(callbackCopy.Method, callbackCopy.ToURL, bodyReader)
 	if err != nil {
-		response.Error = fmt.Sprintf("Error creating callback request: %v", err)
-		return response
+		return nil, nil, fmt.Errorf("Error creating callback request: %v", err)
 	}
 
 	if callbackCopy.IsForwardingHeaders {
@@ -110,10 +170,17 @@ func SendCallback(inbox model.Inbox, k int, c model.Callback, request model.Requ
 		req.Header.Set(key, value)
 	}
 
+	if callbackCopy.Signing.IsEnabled {
+		header, value, err := signature.Sign(callbackCopy.Signing, []byte(callbackCopy.Body), time.Now())
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error signing callback request: %v", err)
+		}
+		req.Header.Set(header, value)
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
-		response.Error = fmt.Sprintf("Error sending callback request: %v", err)
-		return response
+		return nil, nil, fmt.Errorf("Error sending callback request: %v", err)
 	}
 	defer func() {
 		err := resp.Body.Close()
@@ -124,25 +191,7 @@ func SendCallback(inbox model.Inbox, k int, c model.Callback, request model.Requ
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		response.Error = fmt.Sprintf("Error reading callback response: %v", err)
-		return response
+		return nil, nil, fmt.Errorf("Error reading callback response: %v", err)
 	}
-
-	headers := make(map[string]string)
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
-	}
-
-	response = model.CallbackResponse{
-		URL:     callbackCopy.ToURL,
-		Method:  callbackCopy.Method,
-		Error:   "",
-		Code:    resp.StatusCode,
-		Body:    string(respBody),
-		Headers: headers,
-	}
-
-	return response
+	return resp, respBody, nil
 }
@@ -2,6 +2,7 @@ package callback
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/signature"
 	"github.com/jesusnoseq/request-inbox/pkg/t_util"
 )
 
@@ -757,3 +759,53 @@ func TestSendCallbacks_DynamicCallbacksError(t *testing.T) {
 		t.Error("Expected error message in response")
 	}
 }
+
+func TestSendCallback_Signed(t *testing.T) {
+	var gotSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Hub-Signature-256")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	cb := model.Callback{
+		IsEnabled: true,
+		ToURL:     server.URL,
+		Method:    "POST",
+		Body:      "Hello, World!",
+		Signing:   model.Signing{IsEnabled: true, Secret: "It's a Secret to Everybody", Format: model.SignatureFormatGitHub},
+	}
+
+	resp := SendCallback(model.NewInbox(), 0, cb, createTestRequest())
+
+	t_util.AssertStringEquals(t, resp.Error, "")
+	t_util.AssertStringEquals(t, gotSignature, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+}
+
+func TestSendCallbacks_SignedAfterTemplating(t *testing.T) {
+	var gotBody, gotSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotSignature = r.Header.Get("X-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	inbox := model.NewInbox()
+	inbox.Callbacks = []model.Callback{{
+		IsEnabled: true,
+		IsDynamic: true,
+		ToURL:     server.URL,
+		Method:    "POST",
+		Body:      `{{.Request.Method}}`,
+		Signing:   model.Signing{IsEnabled: true, Secret: "secret"},
+	}}
+
+	SendCallbacks(context.Background(), inbox, createTestRequest())
+
+	t_util.AssertStringEquals(t, gotBody, "POST")
+	_, expected, err := signature.Sign(inbox.Callbacks[0].Signing, []byte("POST"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, gotSignature, expected)
+}
@@ -0,0 +1,109 @@
+package callback
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+// proxyCallbackIndex and replayCallbackIndex identify the proxy and the replays in the logs of SendCallback,
+// real callbacks have positive indexes
+const (
+	proxyCallbackIndex  = -1
+	replayCallbackIndex = -2
+)
+
+// hopHeaders only apply to one connection, so they are neither forwarded to the upstream nor returned to the caller
+var hopHeaders = []string{
+	"Connection",
+	"Content-Length",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// SendProxy forwards the request to the upstream of the inbox proxy and returns its response.
+// The body is forwarded as it was received, so truncated bodies are forwarded truncated.
+func SendProxy(inbox model.Inbox, request model.Request) model.ProxyResponse {
+	cb, err := ProxyCallback(inbox.Proxy, request)
+	if err != nil {
+		return model.ProxyResponse{URL: inbox.Proxy.ToURL, Method: request.Method, Error: fmt.Sprintf("Error creating proxy request: %v", err)}
+	}
+	isValid, err := validation.IsValidCallbackURL(cb.ToURL)
+	if !isValid {
+		slog.Error("Invalid proxy URL", "error", err, "inbox_id", inbox.ID)
+		return model.ProxyResponse{URL: cb.ToURL, Method: cb.Method, Error: fmt.Sprintf("Invalid proxy URL: %v", err)}
+	}
+
+	response := model.ProxyResponse{URL: cb.ToURL, Method: cb.Method, Headers: map[string][]string{}}
+	resp, body, err := send(inbox, proxyCallbackIndex, cb, withoutHopHeaders(request))
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Code = resp.StatusCode
+		response.Body, response.BodyEncoding = model.EncodeBody(body)
+		for key, values := range resp.Header {
+			if !isHopHeader(key) {
+				response.Headers[key] = values
+			}
+		}
+	}
+	slog.Info("proxy response received",
+		"inbox_id", inbox.ID,
+		"url", response.URL,
+		"method", response.Method,
+		"status_code", response.Code,
+		"error", response.Error)
+	return response
+}
+
+// ProxyCallback returns the callback that forwards the request to the proxy upstream,
+// keeping the path sent after "/in" and the query of the request.
+func ProxyCallback(p model.Proxy, request model.Request) (model.Callback, error) {
+	uri, err := url.Parse(request.URI)
+	if err != nil {
+		return model.Callback{}, fmt.Errorf("error parsing request URI: %w", err)
+	}
+	body, err := request.RawBody()
+	if err != nil {
+		return model.Callback{}, fmt.Errorf("error reading request body: %w", err)
+	}
+	toURL := strings.TrimSuffix(p.ToURL, "/") + request.InboxPath()
+	if uri.RawQuery != "" {
+		toURL += "?" + uri.RawQuery
+	}
+	return model.Callback{
+		IsEnabled:           true,
+		ToURL:               toURL,
+		Method:              request.Method,
+		Headers:             p.Headers,
+		Body:                string(body),
+		IsForwardingHeaders: true,
+	}, nil
+}
+
+// withoutHopHeaders returns a copy of the request without the headers that can not be forwarded.
+func withoutHopHeaders(request model.Request) model.Request {
+	headers := make(map[string][]string, len(request.Headers))
+	for key, values := range request.Headers {
+		if !isHopHeader(key) {
+			headers[key] = values
+		}
+	}
+	request.Headers = headers
+	return request
+}
+
+func isHopHeader(key string) bool {
+	return collection.SliceContains(hopHeaders, http.CanonicalHeaderKey(key))
+}
@@ -0,0 +1,115 @@
+package callback
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestProxyCallback(t *testing.T) {
+	request := createTestRequest()
+	request.Method = http.MethodPut
+	request.URI = "/api/v1/inboxes/6e47f781-64cd-4e71-89d8-9a3754aa788c/in/orders/42?status=paid"
+	proxy := model.Proxy{IsEnabled: true, ToURL: "http://upstream.test/api/", Headers: map[string]string{"X-Proxy": "yes"}}
+
+	cb, err := ProxyCallback(proxy, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t_util.AssertStringEquals(t, cb.ToURL, "http://upstream.test/api/orders/42?status=paid")
+	t_util.AssertStringEquals(t, cb.Method, http.MethodPut)
+	t_util.AssertStringEquals(t, cb.Body, request.Body)
+	t_util.AssertStringEquals(t, cb.Headers["X-Proxy"], "yes")
+	t_util.AssertEquals(t, cb.IsForwardingHeaders, true)
+}
+
+func TestSendProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/api/orders" || r.URL.RawQuery != "page=2" {
+			t.Errorf("Unexpected upstream URL %s", r.URL)
+		}
+		if r.Header.Get("Connection") == "keep-alive-from-client" {
+			t.Error("Expected hop headers not to be forwarded")
+		}
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Proxy") != "yes" {
+			t.Errorf("Unexpected upstream headers %v", r.Header)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Upstream", "real")
+		w.WriteHeader(http.StatusAccepted)
+		t_util.MustWrite(t, w, []byte("upstream got "+string(b)))
+	}))
+	defer upstream.Close()
+	inbox := model.NewInbox()
+	inbox.Proxy = model.Proxy{IsEnabled: true, ToURL: upstream.URL + "/api", Headers: map[string]string{"X-Proxy": "yes"}}
+	request := createTestRequest()
+	request.URI = "/api/v1/inboxes/" + inbox.ID.String() + "/in/orders?page=2"
+	request.Headers["Connection"] = []string{"keep-alive-from-client"}
+
+	resp := SendProxy(inbox, request)
+
+	t_util.AssertStringEquals(t, resp.Error, "")
+	t_util.AssertEquals(t, resp.Code, http.StatusAccepted)
+	t_util.AssertStringEquals(t, resp.Body, "upstream got "+request.Body)
+	t_util.AssertStringEquals(t, resp.Headers["X-Upstream"][0], "real")
+	if _, ok := resp.Headers["Content-Length"]; ok {
+		t.Error("Expected hop headers not to be returned")
+	}
+}
+
+func TestSendProxyBinaryBodyAndRepeatedHeaders(t *testing.T) {
+	gzipped := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		t_util.MustWrite(t, w, gzipped)
+	}))
+	defer upstream.Close()
+	inbox := model.NewInbox()
+	inbox.Proxy = model.Proxy{IsEnabled: true, ToURL: upstream.URL}
+	request := createTestRequest()
+	request.URI = "/api/v1/inboxes/" + inbox.ID.String() + "/in"
+	// The caller accepts gzip, so the client does not decompress the upstream response
+	request.Headers["Accept-Encoding"] = []string{"gzip"}
+
+	resp := SendProxy(inbox, request)
+
+	t_util.AssertStringEquals(t, resp.Error, "")
+	t_util.AssertStringEquals(t, resp.BodyEncoding, model.BodyEncodingBase64)
+	body, err := resp.RawBody()
+	if err != nil {
+		t.Fatalf("RawBody() unexpected error: %v", err)
+	}
+	if !bytes.Equal(body, gzipped) {
+		t.Errorf("RawBody() = %v, want the upstream bytes %v", body, gzipped)
+	}
+	if diff := cmp.Diff([]string{"a=1", "b=2"}, resp.Headers["Set-Cookie"]); diff != "" {
+		t.Errorf("SendProxy() got unexpected Set-Cookie headers. Diff: %s", diff)
+	}
+}
+
+func TestSendProxyInvalidURL(t *testing.T) {
+	config.Set(config.EnableCallbackURLValidation, true)
+	defer config.Set(config.EnableCallbackURLValidation, false)
+	inbox := model.NewInbox()
+	inbox.Proxy = model.Proxy{IsEnabled: true, ToURL: "http://127.0.0.1:1"}
+	request := createTestRequest()
+	request.URI = "/api/v1/inboxes/" + inbox.ID.String() + "/in"
+
+	resp := SendProxy(inbox, request)
+
+	if !strings.HasPrefix(resp.Error, "Invalid proxy URL") {
+		t.Errorf("Expected an invalid proxy URL error, but got %q", resp.Error)
+	}
+}
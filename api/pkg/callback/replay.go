@@ -0,0 +1,41 @@
+package callback
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+// Replay resends a captured request of the inbox to the replay target and returns the target response.
+func Replay(inbox model.Inbox, request model.Request, replay model.ReplayRequest) model.CallbackResponse {
+	isValid, err := validation.IsValidCallbackURL(replay.ToURL)
+	if !isValid {
+		return model.CallbackResponse{URL: replay.ToURL, Method: request.Method, Error: fmt.Sprintf("Invalid replay URL: %v", err)}
+	}
+	body, err := request.RawBody()
+	if err != nil {
+		return model.CallbackResponse{URL: replay.ToURL, Method: request.Method, Error: fmt.Sprintf("Error reading request body: %v", err)}
+	}
+	if replay.Body != nil {
+		body = []byte(*replay.Body)
+	}
+	cb := model.Callback{
+		IsEnabled:           true,
+		ToURL:               replay.ToURL,
+		Method:              request.Method,
+		Headers:             replay.Headers,
+		Body:                string(body),
+		IsForwardingHeaders: true,
+	}
+	resp := SendCallback(inbox, replayCallbackIndex, cb, withoutHopHeaders(request))
+	slog.Info("replay response received",
+		"inbox_id", inbox.ID,
+		"request_id", request.ID,
+		"url", resp.URL,
+		"method", resp.Method,
+		"status_code", resp.Code,
+		"error", resp.Error)
+	return resp
+}
@@ -0,0 +1,68 @@
+package callback
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestReplay(t *testing.T) {
+	override := `{"replayed": true}`
+	testCases := []struct {
+		desc         string
+		replay       func(url string) model.ReplayRequest
+		expectedBody string
+		expectedTag  string
+	}{
+		{"original request", func(url string) model.ReplayRequest {
+			return model.ReplayRequest{ToURL: url}
+		}, `{"incoming": "request"}`, "original"},
+		{"with overrides", func(url string) model.ReplayRequest {
+			return model.ReplayRequest{ToURL: url, Headers: map[string]string{"X-Tag": "override"}, Body: &override}
+		}, override, "override"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPatch {
+					t.Errorf("Expected method %s, got %s", http.MethodPatch, r.Method)
+				}
+				t_util.AssertStringEquals(t, string(b), tc.expectedBody)
+				t_util.AssertStringEquals(t, r.Header.Get("X-Tag"), tc.expectedTag)
+				t_util.AssertStringEquals(t, r.Header.Get("Content-Type"), "application/json")
+				w.Header().Set("X-Target", "local")
+				w.WriteHeader(http.StatusAccepted)
+				t_util.MustWrite(t, w, []byte("replayed"))
+			}))
+			defer target.Close()
+			request := createTestRequest()
+			request.Method = http.MethodPatch
+			request.Headers["X-Tag"] = []string{"original"}
+
+			resp := Replay(model.NewInbox(), request, tc.replay(target.URL))
+
+			t_util.AssertStringEquals(t, resp.Error, "")
+			t_util.AssertEquals(t, resp.Code, http.StatusAccepted)
+			t_util.AssertStringEquals(t, resp.Body, "replayed")
+			t_util.AssertStringEquals(t, resp.Headers["X-Target"], "local")
+		})
+	}
+}
+
+func TestReplayInvalidURL(t *testing.T) {
+	config.Set(config.EnableCallbackURLValidation, true)
+	defer config.Set(config.EnableCallbackURLValidation, false)
+
+	resp := Replay(model.NewInbox(), createTestRequest(), model.ReplayRequest{ToURL: "http://localhost:8080"})
+
+	if !strings.HasPrefix(resp.Error, "Invalid replay URL") {
+		t.Errorf("Expected an invalid replay URL error, but got %q", resp.Error)
+	}
+}
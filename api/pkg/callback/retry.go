@@ -0,0 +1,104 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+const DefaultInitialBackoffMillis = 200
+
+// SendCallbackWithRetry sends the callback until it succeeds or its retry policy is exhausted.
+// A retry is not attempted when it could not end before the deadline of ctx.
+// The attempts are recorded in the returned response.
+func SendCallbackWithRetry(ctx context.Context, inbox model.Inbox, k int, cb model.Callback, request model.Request) model.CallbackResponse {
+	attempts := max(1, min(cb.Retry.MaxAttempts, config.GetInt(config.CallbackMaxAttempts)))
+	timeout := time.Duration(config.GetInt(config.CallbackTimeoutSeconds)) * time.Second
+	history := make([]model.CallbackAttempt, 0, attempts)
+	var resp model.CallbackResponse
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			backoff := Backoff(cb.Retry, i)
+			if !endsBeforeDeadline(ctx, backoff+timeout) || sleep(ctx, backoff) != nil {
+				break
+			}
+		}
+		start := time.Now()
+		resp = SendCallback(inbox, k, cb, request)
+		history = append(history, model.CallbackAttempt{
+			Timestamp:      start.UnixMilli(),
+			DurationMillis: time.Since(start).Milliseconds(),
+			Code:           resp.Code,
+			Error:          resp.Error,
+		})
+		if !IsRetryable(cb.Retry, resp) {
+			break
+		}
+	}
+	resp.Attempts = history
+	return resp
+}
+
+// InlineContext bounds the retries of the callbacks sent while a request waits for them to CallbackInlineRetryMillis.
+func InlineContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(config.GetInt(config.CallbackInlineRetryMillis))*time.Millisecond)
+}
+
+// RetryDeadLetter sends the callback of the dead letter again with its retry policy.
+func RetryDeadLetter(ctx context.Context, inbox model.Inbox, deadLetter model.DeadLetter) model.CallbackResponse {
+	if isValid, err := validation.IsValidCallbackURL(deadLetter.Callback.ToURL); !isValid {
+		return model.CallbackResponse{Error: fmt.Sprintf("Invalid callback URL: %v", err)}
+	}
+	// Forwarded headers were copied into the callback when the dead letter was created
+	return SendCallbackWithRetry(ctx, inbox, deadLetter.CallbackIndex, deadLetter.Callback, model.Request{})
+}
+
+// Backoff returns the wait before the given retry, starting at 1.
+func Backoff(p model.RetryPolicy, retry int) time.Duration {
+	initial := p.InitialBackoffMillis
+	if initial <= 0 {
+		initial = DefaultInitialBackoffMillis
+	}
+	limit := config.GetInt(config.CallbackMaxBackoffMillis)
+	if p.MaxBackoffMillis > 0 {
+		limit = min(limit, p.MaxBackoffMillis)
+	}
+	backoff := initial
+	for i := 1; i < retry && backoff < limit; i++ {
+		backoff *= 2
+	}
+	return time.Duration(min(backoff, limit)) * time.Millisecond
+}
+
+// IsRetryable reports whether the response is a failure that the policy retries.
+func IsRetryable(p model.RetryPolicy, resp model.CallbackResponse) bool {
+	if resp.Error != "" {
+		return true
+	}
+	if len(p.RetryOnStatus) > 0 {
+		return collection.SliceContains(p.RetryOnStatus, resp.Code)
+	}
+	return resp.Code == http.StatusTooManyRequests || resp.Code >= http.StatusInternalServerError
+}
+
+func endsBeforeDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || !time.Now().Add(d).After(deadline)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
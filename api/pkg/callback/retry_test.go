@@ -0,0 +1,131 @@
+package callback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func newFlakyServer(t *testing.T, codes ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		w.WriteHeader(codes[min(n, len(codes)-1)])
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func newRetryCallback(url string, retry model.RetryPolicy) model.Callback {
+	return model.Callback{IsEnabled: true, ToURL: url, Method: http.MethodPost, Retry: retry}
+}
+
+func TestSendCallbackWithRetry(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	cb := newRetryCallback(server.URL, model.RetryPolicy{MaxAttempts: 5, InitialBackoffMillis: 1})
+
+	resp := SendCallbackWithRetry(context.Background(), model.NewInbox(), 0, cb, createTestRequest())
+
+	t_util.AssertEquals(t, resp.Code, http.StatusOK)
+	t_util.AssertEquals(t, int(calls.Load()), 3)
+	t_util.AssertLen(t, resp.Attempts, 3)
+	t_util.AssertEquals(t, resp.Attempts[0].Code, http.StatusServiceUnavailable)
+	t_util.AssertEquals(t, resp.Attempts[2].Code, http.StatusOK)
+}
+
+func TestSendCallbackWithRetryExhausted(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusInternalServerError)
+	cb := newRetryCallback(server.URL, model.RetryPolicy{MaxAttempts: 3, InitialBackoffMillis: 1})
+
+	resp := SendCallbackWithRetry(context.Background(), model.NewInbox(), 0, cb, createTestRequest())
+
+	t_util.AssertEquals(t, resp.Code, http.StatusInternalServerError)
+	t_util.AssertEquals(t, int(calls.Load()), 3)
+	t_util.AssertLen(t, resp.Attempts, 3)
+}
+
+func TestSendCallbackWithRetryOnStatus(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusConflict, http.StatusOK)
+	cb := newRetryCallback(server.URL, model.RetryPolicy{MaxAttempts: 5, InitialBackoffMillis: 1, RetryOnStatus: []int{http.StatusConflict}})
+
+	resp := SendCallbackWithRetry(context.Background(), model.NewInbox(), 0, cb, createTestRequest())
+
+	t_util.AssertEquals(t, resp.Code, http.StatusServiceUnavailable)
+	t_util.AssertEquals(t, int(calls.Load()), 1)
+}
+
+func TestSendCallbackWithRetryWithoutPolicy(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusOK)
+
+	resp := SendCallbackWithRetry(context.Background(), model.NewInbox(), 0, newRetryCallback(server.URL, model.RetryPolicy{}), createTestRequest())
+
+	t_util.AssertEquals(t, resp.Code, http.StatusServiceUnavailable)
+	t_util.AssertEquals(t, int(calls.Load()), 1)
+	t_util.AssertLen(t, resp.Attempts, 1)
+}
+
+func TestSendCallbackWithRetryInline(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	cb := newRetryCallback(server.URL, model.RetryPolicy{MaxAttempts: 5, InitialBackoffMillis: 1})
+	// A retry could take the callback timeout, which is longer than the time left to the request
+	config.Set(config.CallbackInlineRetryMillis, 1000)
+	defer config.Set(config.CallbackInlineRetryMillis, config.CallbackInlineRetryMillisDefault)
+	ctx, cancel := InlineContext(context.Background())
+	defer cancel()
+
+	resp := SendCallbackWithRetry(ctx, model.NewInbox(), 0, cb, createTestRequest())
+
+	t_util.AssertEquals(t, resp.Code, http.StatusServiceUnavailable)
+	t_util.AssertEquals(t, int(calls.Load()), 1)
+	t_util.AssertLen(t, resp.Attempts, 1)
+}
+
+func TestBackoff(t *testing.T) {
+	policy := model.RetryPolicy{InitialBackoffMillis: 100, MaxBackoffMillis: 300}
+	t_util.AssertEquals(t, Backoff(policy, 1), 100*time.Millisecond)
+	t_util.AssertEquals(t, Backoff(policy, 2), 200*time.Millisecond)
+	t_util.AssertEquals(t, Backoff(policy, 3), 300*time.Millisecond)
+	t_util.AssertEquals(t, Backoff(model.RetryPolicy{}, 1), DefaultInitialBackoffMillis*time.Millisecond)
+}
+
+func TestDeliverDeadLetters(t *testing.T) {
+	failing, _ := newFlakyServer(t, http.StatusBadGateway)
+	working, _ := newFlakyServer(t, http.StatusOK)
+	inbox := model.NewInbox()
+	inbox.Callbacks = []model.Callback{
+		newRetryCallback(working.URL, model.RetryPolicy{}),
+		newRetryCallback(failing.URL, model.RetryPolicy{MaxAttempts: 2, InitialBackoffMillis: 1}),
+	}
+	inbox.Callbacks[1].IsForwardingHeaders = true
+	inbox.Callbacks[1].Headers = map[string]string{"X-Callback": "yes"}
+
+	responses, deadLetters := Deliver(context.Background(), inbox, createTestRequest())
+
+	t_util.AssertLen(t, responses, 2)
+	t_util.AssertEquals(t, responses[0].Code, http.StatusOK)
+	t_util.AssertLen(t, deadLetters, 1)
+	dl := deadLetters[0]
+	t_util.AssertEquals(t, dl.CallbackIndex, 1)
+	t_util.AssertLen(t, dl.Response.Attempts, 2)
+	t_util.AssertEquals(t, dl.Callback.IsForwardingHeaders, false)
+	t_util.AssertStringEquals(t, dl.Callback.Headers["Content-Type"], "application/json")
+	t_util.AssertStringEquals(t, dl.Callback.Headers["X-Callback"], "yes")
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	server, _ := newFlakyServer(t, http.StatusOK)
+	dl := model.DeadLetter{Callback: newRetryCallback(server.URL, model.RetryPolicy{})}
+
+	resp := RetryDeadLetter(context.Background(), model.NewInbox(), dl)
+
+	t_util.AssertEquals(t, resp.Code, http.StatusOK)
+	t_util.AssertEquals(t, IsRetryable(dl.Callback.Retry, resp), false)
+}
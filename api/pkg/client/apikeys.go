@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// API keys are managed with the session of WithAuthToken, the API rejects these calls made with an API key.
+
+// CreateAPIKey creates an API key with the name, expiry date and permissions of ak.
+// The returned key is the only time its secret is not masked.
+func (c *Client) CreateAPIKey(ctx context.Context, ak model.APIKey) (model.APIKey, error) {
+	created := model.APIKey{}
+	err := c.call(ctx, http.MethodPost, "/api-keys", nil, ak, &created)
+	return created, err
+}
+
+func (c *Client) GetAPIKey(ctx context.Context, id uuid.UUID) (model.APIKey, error) {
+	ak := model.APIKey{}
+	err := c.call(ctx, http.MethodGet, "/api-keys/"+id.String(), nil, nil, &ak)
+	return ak, err
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) (model.ItemList[model.APIKey], error) {
+	aks := model.ItemList[model.APIKey]{}
+	err := c.call(ctx, http.MethodGet, "/api-keys", nil, nil, &aks)
+	return aks, err
+}
+
+func (c *Client) DeleteAPIKey(ctx context.Context, id uuid.UUID) error {
+	return c.call(ctx, http.MethodDelete, "/api-keys/"+id.String(), nil, nil, nil)
+}
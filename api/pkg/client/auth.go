@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// ErrNotLoggedIn is returned by CurrentUser when the client has no web session.
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// CurrentUser returns the user of the web session set with WithAuthToken, API keys have no session.
+func (c *Client) CurrentUser(ctx context.Context) (model.User, error) {
+	if c.authToken == "" {
+		return model.User{}, ErrNotLoggedIn
+	}
+	user := model.User{}
+	err := c.call(ctx, http.MethodGet, "/auth/user", nil, nil, &user)
+	if err == nil && user.ID == uuid.Nil {
+		return user, ErrNotLoggedIn
+	}
+	return user, err
+}
@@ -0,0 +1,277 @@
+// Package client is a Go client of the request inbox API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	// APIBasePath is the prefix of the API routes, it must match route.APIBasePath
+	APIBasePath = "/api/v1"
+	// APIKeyHeader is the header that authenticates the requests made with an API key
+	APIKeyHeader = "X-API-KEY"
+	// AuthTokenCookieName is the cookie of the web sessions, it must match login.AuthTokenCookieName
+	AuthTokenCookieName = "auth_token"
+
+	DefaultTimeout        = 30 * time.Second
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+)
+
+// Client calls the request inbox API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	authToken  string
+	userAgent  string
+	httpClient *http.Client
+	// maxAttempts is the number of times a failed request is sent, including the first one
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+type Option func(*Client)
+
+// WithAPIKey authenticates the requests with an API key.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithAuthToken authenticates the requests with the JWT of a web session.
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		c.authToken = token
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetry sets how many times a request is sent when it fails, a value lower than 2 disables retries.
+// The wait between attempts starts at initialBackoff and doubles up to maxBackoff.
+func WithRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(1, maxAttempts)
+		c.initialBackoff = initialBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New returns a client of the API served at baseURL, e.g. "https://api.request-inbox.com".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		userAgent:      "request-inbox-go-client",
+		httpClient:     &http.Client{Timeout: DefaultTimeout},
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is an error answered by the API.
+type Error struct {
+	StatusCode int
+	Response   model.ErrorResponse
+	// Body is the raw response, some endpoints answer errors with their own types
+	Body []byte
+}
+
+func (e *Error) Error() string {
+	if e.Response.Message == "" {
+		return fmt.Sprintf("request inbox API error %d", e.StatusCode)
+	}
+	return fmt.Sprintf("request inbox API error %d: %s", e.StatusCode, e.Response.Message)
+}
+
+// IsNotFound reports whether err is an API error answered with 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an API error answered with 401 or 403.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// call sends a JSON request to the API path and decodes the JSON response into out when it is not nil.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in any, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("error encoding request: %w", err)
+		}
+	}
+	resp, err := c.do(ctx, method, APIBasePath+path, query, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// do sends the request until it gets an answer that is not retryable. Responses that are not 2XX are
+// returned as *Error, otherwise the caller must close the response body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = c.send(ctx, method, u, body)
+		if !c.isRetryable(method, resp, err) || attempt == c.maxAttempts-1 {
+			break
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error sending %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := c.newRequest(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	return c.httpClient.Do(req)
+}
+
+// newRequest returns a request with the client credentials.
+func (c *Client) newRequest(ctx context.Context, method, u string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		req.Header.Set(APIKeyHeader, c.apiKey)
+	}
+	if c.authToken != "" {
+		req.AddCookie(&http.Cookie{Name: AuthTokenCookieName, Value: c.authToken})
+	}
+	return req, nil
+}
+
+// isRetryable reports whether the request should be sent again. Only idempotent methods are retried on
+// network errors and server errors, a 429 is retried for any method because the request was not processed.
+func (c *Client) isRetryable(method string, resp *http.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !isIdempotent(method) {
+		return false
+	}
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the wait before the given retry, starting at 1.
+func (c *Client) backoff(retry int) time.Duration {
+	backoff := c.initialBackoff
+	for i := 1; i < retry && backoff < c.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, c.maxBackoff)
+}
+
+func readError(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	apiErr := &Error{StatusCode: resp.StatusCode, Body: body}
+	if err != nil || json.Unmarshal(body, &apiErr.Response) != nil || apiErr.Response.Message == "" {
+		apiErr.Response = model.ErrorResponse{Code: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return apiErr
+}
+
+func decodeJSON(body []byte, out any) error {
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
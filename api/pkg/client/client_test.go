@@ -0,0 +1,392 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/client"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/handler/apikey"
+	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
+	"github.com/jesusnoseq/request-inbox/pkg/login"
+	"github.com/jesusnoseq/request-inbox/pkg/login/provider"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/route"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+// mustGetServer serves the API routes like cmd/main.go and returns the user of the API key.
+func mustGetServer(t *testing.T) (*httptest.Server, model.User, model.APIKey) {
+	config.LoadConfig(config.Test)
+	ctx := context.Background()
+	dao, err := database.NewRepository(ctx, database.Badger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	et, err := instrumentation.NewEventTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(login.JWTMiddleware())
+	r.Use(login.APIKeyMiddleware(dao))
+	route.SetLoginRoutes(r, login.NewLoginHandler(dao, provider.NewProviderManager(), et))
+	route.SetInboxRoutes(r, handler.NewInboxHandler(dao, et, stream.NewHub(), nil, nil))
+	route.SetAPIKeyRoutes(r, apikey.NewAPIKeyHandler(dao))
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		srv.Close()
+		if err := dao.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+
+	user := model.GenerateUser()
+	if _, err := dao.UpsertUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	ak := model.GenerateAPIKey(user.ID)
+	ak.Permissions = model.NewFullAccessPermissions()
+	if err := dao.CreateAPIKey(ctx, ak); err != nil {
+		t.Fatal(err)
+	}
+	return srv, user, ak
+}
+
+func mustNewClient(srv *httptest.Server, opts ...client.Option) *client.Client {
+	opts = append([]client.Option{client.WithRetry(3, time.Millisecond, 10*time.Millisecond)}, opts...)
+	return client.New(srv.URL, opts...)
+}
+
+func mustSend(t *testing.T, c *client.Client, id uuid.UUID, method, path, body string) {
+	t.Helper()
+	req, err := http.NewRequest(method, c.InboxURL(id)+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestInboxes(t *testing.T) {
+	srv, user, ak := mustGetServer(t)
+	c := mustNewClient(srv, client.WithAPIKey(ak.APIKey))
+	ctx := context.Background()
+
+	created, err := c.CreateInbox(ctx, model.NewInbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, created.Name, created.ID.String())
+	t_util.AssertEquals(t, created.OwnerID, user.ID)
+
+	got, err := c.GetInbox(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, got.ID, created.ID)
+
+	got.Name = "payments"
+	updated, err := c.UpdateInbox(ctx, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, updated.Name, "payments")
+
+	inboxes, err := c.ListInboxes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, inboxes.Count, 1)
+	t_util.AssertEquals(t, inboxes.Results[0].ID, created.ID)
+
+	if err := c.DeleteInbox(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	inboxes, err = c.ListInboxes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, inboxes.Count, 0)
+}
+
+func TestRequests(t *testing.T) {
+	srv, _, ak := mustGetServer(t)
+	c := mustNewClient(srv, client.WithAPIKey(ak.APIKey))
+	ctx := context.Background()
+	inbox := model.NewInbox()
+	inbox.IsPrivate = true
+	inbox, err := c.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSend(t, c, inbox.ID, http.MethodPost, "/orders", `{"id":1}`)
+	mustSend(t, c, inbox.ID, http.MethodPost, "/orders", `{"id":2}`)
+	mustSend(t, c, inbox.ID, http.MethodGet, "/health", ``)
+
+	t.Run("list with filters", func(t *testing.T) {
+		page, err := c.ListRequests(ctx, inbox.ID, client.ListRequestsOptions{Method: http.MethodPost, Order: "desc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertEquals(t, page.Count, 2)
+		t_util.AssertStringEquals(t, page.Results[0].Body, `{"id":2}`)
+	})
+	t.Run("list all pages", func(t *testing.T) {
+		requests, err := c.ListAllRequests(ctx, inbox.ID, client.ListRequestsOptions{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertLen(t, requests, 3)
+		t_util.AssertEquals(t, requests[2].ID, 2)
+	})
+	t.Run("body", func(t *testing.T) {
+		body, err := c.GetRequestBody(ctx, inbox.ID, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertStringEquals(t, string(body), `{"id":2}`)
+
+		_, err = c.GetRequestBody(ctx, inbox.ID, 99, false)
+		t_util.AssertTrue(t, client.IsNotFound(err), "request not found")
+	})
+	t.Run("replay", func(t *testing.T) {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer target.Close()
+		config.Set(config.EnableCallbackURLValidation, false)
+		defer config.Set(config.EnableCallbackURLValidation, config.EnableCallbackURLValidationDefault)
+
+		resp, err := c.ReplayRequest(ctx, inbox.ID, 0, model.ReplayRequest{ToURL: target.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertEquals(t, resp.Code, http.StatusAccepted)
+	})
+	t.Run("assertions", func(t *testing.T) {
+		a := model.Assertion{Matcher: model.RequestMatcher{Method: http.MethodPost, Path: "/orders"}, Count: 2}
+		result, err := c.VerifyRequests(ctx, inbox.ID, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertTrue(t, result.Passed, "verify passed")
+
+		a.Count = 3
+		a.TimeoutSeconds = 1
+		result, err = c.WaitForRequests(ctx, inbox.ID, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertFalse(t, result.Passed, "wait timed out")
+		t_util.AssertEquals(t, result.Matched, 2)
+	})
+	t.Run("delete", func(t *testing.T) {
+		if err := c.DeleteRequests(ctx, inbox.ID); err != nil {
+			t.Fatal(err)
+		}
+		page, err := c.ListRequests(ctx, inbox.ID, client.ListRequestsOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertEquals(t, page.Count, 0)
+	})
+	t.Run("private inbox without API key", func(t *testing.T) {
+		_, err := mustNewClient(srv).ListRequests(ctx, inbox.ID, client.ListRequestsOptions{})
+
+		t_util.AssertTrue(t, client.IsNotFound(err), "private inbox is hidden")
+	})
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := c.ListRequests(ctx, inbox.ID, client.ListRequestsOptions{Order: "random"})
+
+		apiErr := &client.Error{}
+		t_util.AssertTrue(t, errors.As(err, &apiErr), "API error")
+		t_util.AssertEquals(t, apiErr.StatusCode, http.StatusBadRequest)
+		t_util.AssertStringContains(t, apiErr.Response.Message, "order must be")
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	srv, user, ak := mustGetServer(t)
+	ctx := context.Background()
+	token, err := login.GenerateJWT(user, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := mustNewClient(srv, client.WithAuthToken(token))
+
+	_, err = mustNewClient(srv, client.WithAPIKey(ak.APIKey)).CreateAPIKey(ctx, model.APIKey{Name: "escalated"})
+	t_util.AssertTrue(t, client.IsUnauthorized(err), "API keys can not create API keys")
+
+	created, err := c.CreateAPIKey(ctx, model.APIKey{Name: "ci", ExpiryDate: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, created.Name, "ci")
+	t_util.AssertEquals(t, len(created.APIKey), model.API_KEY_SIZE)
+
+	got, err := c.GetAPIKey(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, got.APIKey, created.WithMaskedKey().APIKey)
+
+	aks, err := c.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, aks.Count, 2)
+
+	if err := c.DeleteAPIKey(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = mustNewClient(srv, client.WithAPIKey(created.APIKey)).ListInboxes(ctx)
+	t_util.AssertTrue(t, client.IsUnauthorized(err), "deleted API key is rejected")
+}
+
+func TestCurrentUser(t *testing.T) {
+	srv, user, ak := mustGetServer(t)
+	ctx := context.Background()
+	token, err := login.GenerateJWT(user, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := mustNewClient(srv, client.WithAuthToken(token)).CurrentUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, got.ID, user.ID)
+
+	_, err = mustNewClient(srv, client.WithAPIKey(ak.APIKey)).CurrentUser(ctx)
+	t_util.AssertTrue(t, errors.Is(err, client.ErrNotLoggedIn), "API keys have no session")
+
+	_, err = mustNewClient(srv, client.WithAuthToken("invalid")).CurrentUser(ctx)
+	t_util.AssertTrue(t, client.IsUnauthorized(err), "invalid token is rejected")
+}
+
+func TestRetry(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		method   string
+		failures []int
+		attempts int32
+		code     int
+	}{
+		{"GET succeeds after server errors", http.MethodGet, []int{http.StatusServiceUnavailable, http.StatusBadGateway}, 3, http.StatusOK},
+		{"GET gives up after max attempts", http.MethodGet, []int{500, 500, 500, 500}, 3, http.StatusInternalServerError},
+		{"POST is not retried on server errors", http.MethodPost, []int{http.StatusServiceUnavailable}, 1, http.StatusServiceUnavailable},
+		{"POST is retried when throttled", http.MethodPost, []int{http.StatusTooManyRequests}, 2, http.StatusOK},
+		{"client errors are not retried", http.MethodDelete, []int{http.StatusNotFound}, 1, http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+				if n <= len(tc.failures) {
+					w.WriteHeader(tc.failures[n-1])
+					t_util.MustWrite(t, w, t_util.MustJson(t, model.ErrorResponse{Code: tc.failures[n-1], Message: "failed"}))
+					return
+				}
+				t_util.MustWrite(t, w, []byte(`{"ID":"`+uuid.NewString()+`"}`))
+			}))
+			defer srv.Close()
+			c := mustNewClient(srv)
+
+			var err error
+			if tc.method == http.MethodGet {
+				_, err = c.GetInbox(context.Background(), uuid.New())
+			} else if tc.method == http.MethodPost {
+				_, err = c.CreateInbox(context.Background(), model.NewInbox())
+			} else {
+				err = c.DeleteInbox(context.Background(), uuid.New())
+			}
+
+			t_util.AssertEquals(t, attempts.Load(), tc.attempts)
+			if tc.code == http.StatusOK {
+				t_util.AssertNoError(t, err)
+				return
+			}
+			apiErr := &client.Error{}
+			t_util.AssertTrue(t, errors.As(err, &apiErr), "API error")
+			t_util.AssertEquals(t, apiErr.StatusCode, tc.code)
+			t_util.AssertStringEquals(t, apiErr.Response.Message, "failed")
+		})
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	c := client.New(srv.URL, client.WithRetry(10, time.Second, time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.ListInboxes(ctx)
+
+	t_util.AssertTrue(t, errors.Is(err, context.DeadlineExceeded), "context error")
+	t_util.AssertTrue(t, time.Since(start) < time.Second, "did not wait for the backoff")
+}
+
+func TestStreamRequests(t *testing.T) {
+	srv, _, ak := mustGetServer(t)
+	c := mustNewClient(srv, client.WithAPIKey(ak.APIKey))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	inbox, err := c.CreateInbox(ctx, model.NewInbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSend(t, c, inbox.ID, http.MethodPost, "/first", ``)
+	mustSend(t, c, inbox.ID, http.MethodPost, "/missed", ``)
+
+	errDone := errors.New("done")
+	paths := []string{}
+	last, err := c.StreamRequests(ctx, inbox.ID, 0, func(req model.Request) error {
+		paths = append(paths, req.URI[strings.LastIndex(req.URI, "/"):])
+		if req.ID == 1 {
+			// The missed requests are sent once the stream is subscribed, so this one arrives live
+			go func() {
+				if resp, err := http.Post(c.InboxURL(inbox.ID)+"/live", "text/plain", nil); err == nil {
+					_ = resp.Body.Close()
+				}
+			}()
+			return nil
+		}
+		return errDone
+	})
+
+	t_util.AssertTrue(t, errors.Is(err, errDone), "stream stopped by the callback")
+	t_util.AssertEquals(t, last, 1)
+	t_util.AssertEqualsAsJson(t, paths, []string{"/missed", "/live"})
+}
+
+func TestStreamRequestsOfUnknownInbox(t *testing.T) {
+	srv, _, ak := mustGetServer(t)
+	c := mustNewClient(srv, client.WithAPIKey(ak.APIKey))
+
+	last, err := c.StreamRequests(context.Background(), uuid.Nil, -1, func(model.Request) error { return nil })
+
+	t_util.AssertEquals(t, last, -1)
+	apiErr := &client.Error{}
+	t_util.AssertTrue(t, errors.As(err, &apiErr), "API error")
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// ListInboxes returns the inboxes of the authenticated user, or the public ones when the server lists them.
+func (c *Client) ListInboxes(ctx context.Context) (model.ItemList[model.Inbox], error) {
+	inboxes := model.ItemList[model.Inbox]{}
+	err := c.call(ctx, http.MethodGet, "/inboxes", nil, nil, &inboxes)
+	return inboxes, err
+}
+
+// CreateInbox creates the inbox, the server sets its ID, owner and timestamp.
+func (c *Client) CreateInbox(ctx context.Context, inbox model.Inbox) (model.Inbox, error) {
+	created := model.Inbox{}
+	err := c.call(ctx, http.MethodPost, "/inboxes", nil, inbox, &created)
+	return created, err
+}
+
+func (c *Client) GetInbox(ctx context.Context, id uuid.UUID) (model.Inbox, error) {
+	inbox := model.Inbox{}
+	err := c.call(ctx, http.MethodGet, "/inboxes/"+id.String(), nil, nil, &inbox)
+	return inbox, err
+}
+
+func (c *Client) UpdateInbox(ctx context.Context, inbox model.Inbox) (model.Inbox, error) {
+	updated := model.Inbox{}
+	err := c.call(ctx, http.MethodPut, "/inboxes/"+inbox.ID.String(), nil, inbox, &updated)
+	return updated, err
+}
+
+func (c *Client) DeleteInbox(ctx context.Context, id uuid.UUID) error {
+	return c.call(ctx, http.MethodDelete, "/inboxes/"+id.String(), nil, nil, nil)
+}
+
+// InboxURL returns the URL where the inbox receives requests.
+func (c *Client) InboxURL(id uuid.UUID) string {
+	return c.baseURL + APIBasePath + "/inboxes/" + id.String() + "/in"
+}
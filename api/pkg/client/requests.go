@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// ListRequestsOptions filters and paginates the requests of an inbox, zero values are ignored.
+type ListRequestsOptions struct {
+	Limit  int
+	Cursor string
+	// Order is "asc" or "desc"
+	Order      string
+	Method     string
+	PathPrefix string
+	From       time.Time
+	To         time.Time
+	// Headers are "Name: value" filters, "Name" alone only requires the header
+	Headers      []string
+	BodyContains string
+}
+
+func (o ListRequestsOptions) query() url.Values {
+	q := url.Values{}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if o.Order != "" {
+		q.Set("order", o.Order)
+	}
+	if o.Method != "" {
+		q.Set("method", o.Method)
+	}
+	if o.PathPrefix != "" {
+		q.Set("path", o.PathPrefix)
+	}
+	if !o.From.IsZero() {
+		q.Set("from", strconv.FormatInt(o.From.UnixMilli(), 10))
+	}
+	if !o.To.IsZero() {
+		q.Set("to", strconv.FormatInt(o.To.UnixMilli(), 10))
+	}
+	for _, h := range o.Headers {
+		q.Add("header", h)
+	}
+	if o.BodyContains != "" {
+		q.Set("body", o.BodyContains)
+	}
+	return q
+}
+
+// ListRequests returns a page of the requests received by the inbox, the next one is read with its NextCursor.
+func (c *Client) ListRequests(ctx context.Context, id uuid.UUID, opts ListRequestsOptions) (model.Page[model.Request], error) {
+	page := model.Page[model.Request]{}
+	err := c.call(ctx, http.MethodGet, "/inboxes/"+id.String()+"/requests", opts.query(), nil, &page)
+	return page, err
+}
+
+// ListAllRequests follows the pages of the requests of the inbox, opts.Cursor is the first one read.
+func (c *Client) ListAllRequests(ctx context.Context, id uuid.UUID, opts ListRequestsOptions) ([]model.Request, error) {
+	requests := []model.Request{}
+	for {
+		page, err := c.ListRequests(ctx, id, opts)
+		if err != nil {
+			return requests, err
+		}
+		requests = append(requests, page.Results...)
+		if page.NextCursor == "" {
+			return requests, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// GetRequestBody returns the raw body of a request, decoded from its Content-Encoding when decoded is true.
+func (c *Client) GetRequestBody(ctx context.Context, id uuid.UUID, requestID int, decoded bool) ([]byte, error) {
+	path := fmt.Sprintf("%s/inboxes/%s/requests/%d/body", APIBasePath, id, requestID)
+	resp, err := c.do(ctx, http.MethodGet, path, url.Values{"decoded": {strconv.FormatBool(decoded)}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading body of request %d: %w", requestID, err)
+	}
+	return body, nil
+}
+
+func (c *Client) DeleteRequests(ctx context.Context, id uuid.UUID) error {
+	return c.call(ctx, http.MethodDelete, "/inboxes/"+id.String()+"/requests", nil, nil, nil)
+}
+
+// ReplayRequest resends a captured request and returns the response of its target.
+func (c *Client) ReplayRequest(ctx context.Context, id uuid.UUID, requestID int, replay model.ReplayRequest) (model.CallbackResponse, error) {
+	resp := model.CallbackResponse{}
+	err := c.call(ctx, http.MethodPost, fmt.Sprintf("/inboxes/%s/requests/%d/replay", id, requestID), nil, replay, &resp)
+	return resp, err
+}
+
+// VerifyRequests checks the assertion against the requests already received by the inbox.
+func (c *Client) VerifyRequests(ctx context.Context, id uuid.UUID, a model.Assertion) (model.AssertionResult, error) {
+	result := model.AssertionResult{}
+	err := c.call(ctx, http.MethodPost, "/inboxes/"+id.String()+"/assertions/verify", nil, a, &result)
+	return result, err
+}
+
+// WaitForRequests blocks until the inbox receives the requests expected by the assertion or its timeout
+// expires, a timeout is not an error and returns the result with Passed false.
+func (c *Client) WaitForRequests(ctx context.Context, id uuid.UUID, a model.Assertion) (model.AssertionResult, error) {
+	result := model.AssertionResult{}
+	err := c.call(ctx, http.MethodPost, "/inboxes/"+id.String()+"/assertions/wait", nil, a, &result)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestTimeout && len(apiErr.Body) > 0 {
+		return result, decodeJSON(apiErr.Body, &result)
+	}
+	return result, err
+}
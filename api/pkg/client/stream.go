@@ -0,0 +1,101 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	// LastEventIDHeader resumes a stream after the request with that ID
+	LastEventIDHeader = "Last-Event-ID"
+	// RequestEventName is the server-sent event of a received request
+	RequestEventName = "request"
+)
+
+// ErrStreamClosed is returned by StreamRequests when the server ends the stream.
+var ErrStreamClosed = errors.New("stream closed by the server")
+
+// StreamRequests calls fn with the requests received by the inbox as they arrive, until ctx is done,
+// the stream ends or fn fails. When lastRequestID is not negative the requests received after it are sent first.
+// It returns the ID of the last request passed to fn, or lastRequestID, so a dropped stream can be resumed.
+// Streams are not retried and ignore the timeout of the HTTP client.
+func (c *Client) StreamRequests(ctx context.Context, id uuid.UUID, lastRequestID int, fn func(model.Request) error) (int, error) {
+	u := c.baseURL + APIBasePath + "/inboxes/" + id.String() + "/stream"
+	req, err := c.newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return lastRequestID, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastRequestID >= 0 {
+		req.Header.Set(LastEventIDHeader, strconv.Itoa(lastRequestID))
+	}
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return lastRequestID, fmt.Errorf("error opening stream of inbox %s: %w", id, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return lastRequestID, readError(resp)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	for {
+		event, data, err := readEvent(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return lastRequestID, ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return lastRequestID, ErrStreamClosed
+			}
+			return lastRequestID, fmt.Errorf("error reading stream of inbox %s: %w", id, err)
+		}
+		if event != RequestEventName {
+			continue
+		}
+		request := model.Request{}
+		if err := json.Unmarshal([]byte(data), &request); err != nil {
+			return lastRequestID, fmt.Errorf("error decoding request event: %w", err)
+		}
+		if err := fn(request); err != nil {
+			return lastRequestID, err
+		}
+		lastRequestID = request.ID
+	}
+}
+
+// readEvent returns the name and data of the next server-sent event, comments are skipped.
+func readEvent(r *bufio.Reader) (string, string, error) {
+	event := ""
+	data := []string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if event != "" || len(data) > 0 {
+				return event, strings.Join(data, "\n"), nil
+			}
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(line[len("event:"):])
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(line[len("data:"):], " "))
+		}
+	}
+}
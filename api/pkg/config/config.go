@@ -47,6 +47,70 @@ const (
 	CallbackTimeoutSeconds        Key = "CALLBACK_TIMEOUT_SECONDS"
 	CallbackTimeoutSecondsDefault int = 5
 
+	// Callback retry policies are capped by these limits
+	CallbackMaxAttempts             Key = "CALLBACK_MAX_ATTEMPTS"
+	CallbackMaxAttemptsDefault      int = 5
+	CallbackMaxBackoffMillis        Key = "CALLBACK_MAX_BACKOFF_MILLIS"
+	CallbackMaxBackoffMillisDefault int = 5000
+	CallbackMaxDeadLetters          Key = "CALLBACK_MAX_DEAD_LETTERS"
+	CallbackMaxDeadLettersDefault   int = 100
+	// Callbacks sent while the request waits are not retried past this time, so the response is written before
+	// the server write timeout and the API Gateway timeout. The first attempt is always sent.
+	CallbackInlineRetryMillis        Key = "CALLBACK_INLINE_RETRY_MILLIS"
+	CallbackInlineRetryMillisDefault int = 8000
+
+	// Async delivery answers the request before its callbacks are sent, their responses are written back to the request
+	CallbackDeliveryMode     Key    = "CALLBACK_DELIVERY_MODE"
+	CallbackDeliverySync     string = "sync"
+	CallbackDeliveryAsync    string = "async"
+	CallbackWorkers          Key    = "CALLBACK_WORKERS"
+	CallbackWorkersDefault   int    = 4
+	CallbackQueueSize        Key    = "CALLBACK_QUEUE_SIZE"
+	CallbackQueueSizeDefault int    = 1000
+
+	// Notification throttling is kept in memory, each server or lambda instance throttles on its own
+	NotificationThrottleSeconds        Key = "NOTIFICATION_THROTTLE_SECONDS"
+	NotificationThrottleSecondsDefault int = 60
+
+	// Emails are disabled when SMTPHost is empty
+	SMTPHost                               Key    = "SMTP_HOST"
+	SMTPPort                               Key    = "SMTP_PORT"
+	SMTPPortDefault                        int    = 587
+	SMTPUsername                           Key    = "SMTP_USERNAME"
+	SMTPPassword                           Key    = "SMTP_PASSWORD"
+	SMTPFrom                               Key    = "SMTP_FROM"
+	SMTPFromDefault                        string = "Request Inbox <no-reply@request-inbox.com>"
+	EmailUnsubscribeURL                    Key    = "EMAIL_UNSUBSCRIBE_URL"
+	EmailUnsubscribeURLDefault             string = "https://api.request-inbox.com/api/v1/email/unsubscribe"
+	EmailAlertThrottleSeconds              Key    = "EMAIL_ALERT_THROTTLE_SECONDS"
+	EmailAlertThrottleSecondsDefault       int    = 300
+	EmailDigestSweepIntervalSeconds        Key    = "EMAIL_DIGEST_SWEEP_INTERVAL_SECONDS"
+	EmailDigestSweepIntervalSecondsDefault int    = 300
+
+	VerificationToleranceSeconds        Key = "VERIFICATION_TOLERANCE_SECONDS"
+	VerificationToleranceSecondsDefault int = 5 * 60
+
+	// Assertion waits extend the write timeout of the server, the maximum must stay below the API Gateway timeout (29s)
+	AssertionTimeoutSeconds           Key = "ASSERTION_TIMEOUT_SECONDS"
+	AssertionTimeoutSecondsDefault    int = 10
+	AssertionMaxTimeoutSeconds        Key = "ASSERTION_MAX_TIMEOUT_SECONDS"
+	AssertionMaxTimeoutSecondsDefault int = 25
+
+	StreamHeartbeatSeconds        Key = "STREAM_HEARTBEAT_SECONDS"
+	StreamHeartbeatSecondsDefault int = 15
+
+	// Request bodies bigger than the limit are truncated, decompressed bodies have the same limit
+	RequestBodyMaxBytes        Key = "REQUEST_BODY_MAX_BYTES"
+	RequestBodyMaxBytesDefault int = 100 * 1024
+
+	// Retention defaults are also the maximum values an inbox can configure, 0 means unlimited
+	RetentionMaxRequests                 Key = "RETENTION_MAX_REQUESTS"
+	RetentionMaxRequestsDefault          int = 1000
+	RetentionMaxAgeSeconds               Key = "RETENTION_MAX_AGE_SECONDS"
+	RetentionMaxAgeSecondsDefault        int = 30 * 24 * 60 * 60
+	RetentionSweepIntervalSeconds        Key = "RETENTION_SWEEP_INTERVAL_SECONDS"
+	RetentionSweepIntervalSecondsDefault int = 60 * 60
+
 	LogLevel      Key    = "LOG_LEVEL"
 	LogFormat     Key    = "LOG_FORMATER"
 	LogFormatJSON string = "json"
@@ -95,6 +159,18 @@ const (
 	EnabledMonitoringDefault             bool = false
 	MaxCallbacksKey                      Key  = "MAX_CALLBACKS"
 	MaxCallbacksDefault                  int  = 3
+	MaxNotificationsKey                  Key  = "MAX_NOTIFICATIONS"
+	MaxNotificationsDefault              int  = 3
+	MaxEmailAlertsKey                    Key  = "MAX_EMAIL_ALERTS"
+	MaxEmailAlertsDefault                int  = 10
+	MaxResponseRulesKey                  Key  = "MAX_RESPONSE_RULES"
+	MaxResponseRulesDefault              int  = 20
+	MaxResponseSequenceKey               Key  = "MAX_RESPONSE_SEQUENCE"
+	MaxResponseSequenceDefault           int  = 20
+	MaxResponseDelayMillisKey            Key  = "MAX_RESPONSE_DELAY_MILLIS"
+	MaxResponseDelayMillisDefault        int  = 10000
+	MaxSlowBodyMillisKey                 Key  = "MAX_SLOW_BODY_MILLIS"
+	MaxSlowBodyMillisDefault             int  = 10000
 	EnableCallbackURLValidation          Key  = "ENABLE_CALLBACK_URL_VALIDATION"
 	EnableCallbackURLValidationDefault   bool = true
 	EnableCallbackFollowRedirects        Key  = "ENABLE_CALLBACK_FOLLOW_REDIRECTS"
@@ -141,6 +217,30 @@ func setDefaults(app App) {
 
 	setDefault(HTTPClientTimeoutSeconds, HTTPClientTimeoutSecondsDefault)
 	setDefault(CallbackTimeoutSeconds, CallbackTimeoutSecondsDefault)
+	setDefault(CallbackMaxAttempts, CallbackMaxAttemptsDefault)
+	setDefault(CallbackMaxBackoffMillis, CallbackMaxBackoffMillisDefault)
+	setDefault(CallbackMaxDeadLetters, CallbackMaxDeadLettersDefault)
+	setDefault(CallbackInlineRetryMillis, CallbackInlineRetryMillisDefault)
+	setDefault(CallbackDeliveryMode, CallbackDeliverySync)
+	setDefault(CallbackWorkers, CallbackWorkersDefault)
+	setDefault(CallbackQueueSize, CallbackQueueSizeDefault)
+	setDefault(NotificationThrottleSeconds, NotificationThrottleSecondsDefault)
+	setDefault(SMTPHost, "")
+	setDefault(SMTPPort, SMTPPortDefault)
+	setDefault(SMTPUsername, "")
+	setDefault(SMTPPassword, "")
+	setDefault(SMTPFrom, SMTPFromDefault)
+	setDefault(EmailUnsubscribeURL, EmailUnsubscribeURLDefault)
+	setDefault(EmailAlertThrottleSeconds, EmailAlertThrottleSecondsDefault)
+	setDefault(EmailDigestSweepIntervalSeconds, EmailDigestSweepIntervalSecondsDefault)
+	setDefault(VerificationToleranceSeconds, VerificationToleranceSecondsDefault)
+	setDefault(AssertionTimeoutSeconds, AssertionTimeoutSecondsDefault)
+	setDefault(AssertionMaxTimeoutSeconds, AssertionMaxTimeoutSecondsDefault)
+	setDefault(StreamHeartbeatSeconds, StreamHeartbeatSecondsDefault)
+	setDefault(RequestBodyMaxBytes, RequestBodyMaxBytesDefault)
+	setDefault(RetentionMaxRequests, RetentionMaxRequestsDefault)
+	setDefault(RetentionMaxAgeSeconds, RetentionMaxAgeSecondsDefault)
+	setDefault(RetentionSweepIntervalSeconds, RetentionSweepIntervalSecondsDefault)
 	setDefault(BackendApplicationDomain, BackendApplicationDomainDefault)
 
 	// AUTH
@@ -161,6 +261,12 @@ func setDefaults(app App) {
 	setDefault(EnablePrintConfig, EnableListingInboxDefault)
 	setDefault(EnabledMonitoring, EnabledMonitoringDefault)
 	setDefault(MaxCallbacksKey, MaxCallbacksDefault)
+	setDefault(MaxNotificationsKey, MaxNotificationsDefault)
+	setDefault(MaxEmailAlertsKey, MaxEmailAlertsDefault)
+	setDefault(MaxResponseRulesKey, MaxResponseRulesDefault)
+	setDefault(MaxResponseSequenceKey, MaxResponseSequenceDefault)
+	setDefault(MaxResponseDelayMillisKey, MaxResponseDelayMillisDefault)
+	setDefault(MaxSlowBodyMillisKey, MaxSlowBodyMillisDefault)
 	setDefault(EnableCallbackURLValidation, EnableCallbackURLValidationDefault)
 	setDefault(EnableCallbackFollowRedirects, EnableCallbackFollowRedirectsDefault)
 }
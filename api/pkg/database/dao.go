@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
 
@@ -16,7 +17,23 @@ type Repository interface {
 	ListInbox(context.Context) ([]model.Inbox, error)
 	ListInboxByUser(context.Context, uuid.UUID) ([]model.Inbox, error)
 	DeleteInboxRequests(ctx context.Context, ID uuid.UUID) error
-	AddRequestToInbox(context.Context, uuid.UUID, model.Request) error
+	// AddRequestToInbox stores the request with the contents of its form files and returns the assigned request ID
+	AddRequestToInbox(ctx context.Context, ID uuid.UUID, req model.Request, files ...model.RequestFile) (int, error)
+	ListInboxRequests(context.Context, uuid.UUID, ...option.ListRequestsOption) (model.Page[model.Request], error)
+	GetInboxRequest(ctx context.Context, ID uuid.UUID, requestID int) (model.Request, error)
+	// UpdateInboxRequest replaces a stored request, keeping its expiration
+	UpdateInboxRequest(ctx context.Context, ID uuid.UUID, req model.Request) error
+	PruneInboxRequests(context.Context, uuid.UUID, model.RetentionPolicy) (int, error)
+	GetRequestFile(ctx context.Context, ID uuid.UUID, requestID int, index int) (model.RequestFile, error)
+	// UpdateInboxScenario atomically replaces the scenario of the inbox with the one returned by update.
+	// update can be called more than once when the scenario is updated concurrently.
+	UpdateInboxScenario(ctx context.Context, ID uuid.UUID, update func(model.Scenario) model.Scenario) (model.Scenario, error)
+	// PutDeadLetter stores the dead letter, assigning its ID when it has none, and drops the oldest ones of the inbox
+	// beyond the configured maximum
+	PutDeadLetter(ctx context.Context, ID uuid.UUID, deadLetter model.DeadLetter) (model.DeadLetter, error)
+	ListDeadLetters(ctx context.Context, ID uuid.UUID) ([]model.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, ID uuid.UUID, deadLetterID uuid.UUID) (model.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, ID uuid.UUID, deadLetterID uuid.UUID) error
 
 	UpsertUser(context.Context, model.User) (bool, error)
 	GetUser(context.Context, uuid.UUID) (model.User, error)
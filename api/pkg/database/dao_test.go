@@ -2,13 +2,19 @@ package database_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
 	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
 	"github.com/jesusnoseq/request-inbox/pkg/database/embedded"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
 
@@ -49,6 +55,8 @@ func TestDBCreateInbox(t *testing.T) {
 	inboxWithID.ID = created.ID
 	inboxWithID.Name = created.ID.String()
 	inboxWithID.Timestamp = created.Timestamp
+	// Requests are only stored with AddRequestToInbox
+	inboxWithID.Requests = []model.Request{}
 	if diff := cmp.Diff(inboxWithID, created); diff != "" {
 		t.Errorf("NewRepository(ctx, inbox) = created, want inboxWithID. Diff: %s", diff)
 	}
@@ -124,9 +132,12 @@ func TestUpdateInbox(t *testing.T) {
 	db, close := MustGetDB()
 	defer close(ctx)
 	inDBInbox := MustCreateInbox(ctx, db, model.GenerateInbox())
+	// Requests are only stored with AddRequestToInbox
 	modDBInbox := model.GenerateInbox()
 	modDBInbox.ID = inDBInbox.ID
+	modDBInbox.Requests = []model.Request{}
 	newInbox := model.GenerateInbox()
+	newInbox.Requests = []model.Request{}
 	t.Run("Modify item that exists", func(t *testing.T) {
 		got, err := db.UpdateInbox(ctx, modDBInbox)
 		if err != nil {
@@ -192,6 +203,391 @@ func TestDeleteInbox(t *testing.T) {
 	}
 }
 
+func TestListInboxRequests(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	inbox = MustCreateInbox(ctx, db, inbox)
+	base := time.Now().Add(-time.Hour).UnixMilli()
+	requests := []model.Request{
+		{ID: 0, Timestamp: base + 1000, Method: "GET", URI: "/api/v1/inboxes/" + inbox.ID.String() + "/in/users", Body: ""},
+		{ID: 1, Timestamp: base + 2000, Method: "POST", URI: "/api/v1/inboxes/" + inbox.ID.String() + "/in/users", Body: `{"name":"foo"}`,
+			Headers: map[string][]string{"X-Signature": {"abc"}}},
+		{ID: 2, Timestamp: base + 3000, Method: "POST", URI: "/api/v1/inboxes/" + inbox.ID.String() + "/in/orders", Body: `{"name":"bar"}`},
+		{ID: 3, Timestamp: base + 4000, Method: "DELETE", URI: "/api/v1/inboxes/" + inbox.ID.String() + "/in/users/1", Body: ""},
+	}
+	for _, r := range requests {
+		if _, err := db.AddRequestToInbox(ctx, inbox.ID, r); err != nil {
+			t.Fatalf("Expected no error adding request, but got an error: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		desc    string
+		options []option.ListRequestsOption
+		wantIDs []int
+	}{
+		{desc: "No filters", wantIDs: []int{0, 1, 2, 3}},
+		{desc: "Descending order", options: []option.ListRequestsOption{option.WithOrder(option.OrderDesc)}, wantIDs: []int{3, 2, 1, 0}},
+		{desc: "By method", options: []option.ListRequestsOption{option.WithMethod("post")}, wantIDs: []int{1, 2}},
+		{desc: "By path prefix", options: []option.ListRequestsOption{option.WithPathPrefix("/users")}, wantIDs: []int{0, 1, 3}},
+		{desc: "By time range", options: []option.ListRequestsOption{option.WithTimeRange(base+2000, base+3000)}, wantIDs: []int{1, 2}},
+		{desc: "By header", options: []option.ListRequestsOption{option.WithHeader("x-signature")}, wantIDs: []int{1}},
+		{desc: "By body", options: []option.ListRequestsOption{option.WithBodyContains(`"bar"`)}, wantIDs: []int{2}},
+		{desc: "Without matches", options: []option.ListRequestsOption{option.WithMethod("PATCH")}, wantIDs: []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			page, err := db.ListInboxRequests(ctx, inbox.ID, tc.options...)
+			if err != nil {
+				t.Fatalf("Expected no error, but got an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantIDs, requestIDs(page.Results)); diff != "" {
+				t.Errorf("ListInboxRequests(ctx, %v) got unexpected requests. Diff: %s", inbox.ID, diff)
+			}
+			if page.Count != len(tc.wantIDs) {
+				t.Errorf("Expected count %d, but got %d", len(tc.wantIDs), page.Count)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("Expected no next cursor, but got %q", page.NextCursor)
+			}
+		})
+	}
+
+	for _, order := range []option.Order{option.OrderAsc, option.OrderDesc} {
+		t.Run("Paginate "+string(order), func(t *testing.T) {
+			got := []int{}
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(requests) {
+					t.Fatalf("Pagination did not finish")
+				}
+				page, err := db.ListInboxRequests(ctx, inbox.ID,
+					option.WithLimit(3), option.WithCursor(cursor), option.WithOrder(order), option.WithMethod("POST"))
+				if err != nil {
+					t.Fatalf("Expected no error, but got an error: %v", err)
+				}
+				got = append(got, requestIDs(page.Results)...)
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+			want := []int{1, 2}
+			if order == option.OrderDesc {
+				want = []int{2, 1}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Paginated requests are not the expected ones. Diff: %s", diff)
+			}
+		})
+	}
+
+	t.Run("Paginate by one", func(t *testing.T) {
+		page, err := db.ListInboxRequests(ctx, inbox.ID, option.WithLimit(1))
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if page.NextCursor == "" {
+			t.Fatalf("Expected a next cursor")
+		}
+		page, err = db.ListInboxRequests(ctx, inbox.ID, option.WithLimit(1), option.WithCursor(page.NextCursor))
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if diff := cmp.Diff([]int{1}, requestIDs(page.Results)); diff != "" {
+			t.Errorf("Second page is not the expected one. Diff: %s", diff)
+		}
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		_, err := db.ListInboxRequests(ctx, inbox.ID, option.WithCursor("%%%"))
+		if !errors.Is(err, dberrors.ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor, but got %v", err)
+		}
+	})
+
+	t.Run("Not existing inbox", func(t *testing.T) {
+		_, err := db.ListInboxRequests(ctx, uuid.New())
+		if err == nil {
+			t.Errorf("Expected an error but got nil.")
+		}
+	})
+}
+
+func TestAddRequestToInboxEnforcesRetention(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	inbox.Retention = model.RetentionPolicy{MaxRequests: 2}
+	inbox = MustCreateInbox(ctx, db, inbox)
+
+	for i := 0; i < 4; i++ {
+		if _, err := db.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(i)); err != nil {
+			t.Fatalf("Expected no error adding request, but got an error: %v", err)
+		}
+	}
+
+	got, err := db.GetInboxWithRequests(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff([]int{2, 3}, requestIDs(got.Requests)); diff != "" {
+		t.Errorf("Expected the newest requests to be kept. Diff: %s", diff)
+	}
+}
+
+func TestGetInboxRequest(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := MustCreateInbox(ctx, db, model.GenerateInbox())
+	requests := []model.Request{}
+	for i := 0; i < 3; i++ {
+		req := model.GenerateRequest(0)
+		id, err := db.AddRequestToInbox(ctx, inbox.ID, req)
+		if err != nil {
+			t.Fatalf("Expected no error adding request, but got an error: %v", err)
+		}
+		req.ID = id
+		requests = append(requests, req)
+	}
+
+	got, err := db.GetInboxRequest(ctx, inbox.ID, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff(requests[1], got); diff != "" {
+		t.Errorf("GetInboxRequest() mismatch. Diff: %s", diff)
+	}
+	for _, requestID := range []int{-1, 3} {
+		_, err = db.GetInboxRequest(ctx, inbox.ID, requestID)
+		if !errors.Is(err, dberrors.ErrItemNotFound) {
+			t.Errorf("GetInboxRequest(%d) expected not found error, but got %v", requestID, err)
+		}
+	}
+}
+
+func TestUpdateInboxRequest(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := MustCreateInbox(ctx, db, model.GenerateInbox())
+	req := model.GenerateRequest(0)
+	req.CallbacksPending = true
+	id, err := db.AddRequestToInbox(ctx, inbox.ID, req)
+	if err != nil {
+		t.Fatalf("Expected no error adding request, but got an error: %v", err)
+	}
+	req.ID = id
+	req.CallbacksPending = false
+	req.CallbackResponses = []model.CallbackResponse{{Code: 200, Body: "done"}}
+
+	if err := db.UpdateInboxRequest(ctx, inbox.ID, req); err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	got, err := db.GetInboxRequest(ctx, inbox.ID, id)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff(req, got); diff != "" {
+		t.Errorf("UpdateInboxRequest() mismatch. Diff: %s", diff)
+	}
+
+	req.ID = id + 1
+	err = db.UpdateInboxRequest(ctx, inbox.ID, req)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected not found error, but got %v", err)
+	}
+}
+
+func TestRequestFiles(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := model.GenerateInbox()
+	inbox.Retention = model.RetentionPolicy{MaxRequests: 2}
+	inbox = MustCreateInbox(ctx, db, inbox)
+
+	files := []model.RequestFile{}
+	for i := 0; i < 3; i++ {
+		file := model.RequestFile{
+			FormFile: model.FormFile{FieldName: "file", Filename: fmt.Sprintf("%d.txt", i)},
+			Content:  []byte(fmt.Sprintf("content %d", i)),
+		}
+		files = append(files, file)
+		if _, err := db.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(i), file); err != nil {
+			t.Fatalf("Expected no error adding request, but got an error: %v", err)
+		}
+	}
+
+	_, err := db.GetRequestFile(ctx, inbox.ID, 0, 0)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected files of pruned requests to be deleted, but got %v", err)
+	}
+	got, err := db.GetRequestFile(ctx, inbox.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff(files[2], got); diff != "" {
+		t.Errorf("GetRequestFile() mismatch. Diff: %s", diff)
+	}
+	_, err = db.GetRequestFile(ctx, inbox.ID, 2, 1)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected not found error, but got %v", err)
+	}
+
+	if _, err := db.PruneInboxRequests(ctx, inbox.ID, model.RetentionPolicy{MaxRequests: 1}); err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	_, err = db.GetRequestFile(ctx, inbox.ID, 1, 0)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected files of pruned requests to be deleted, but got %v", err)
+	}
+	if _, err := db.GetRequestFile(ctx, inbox.ID, 2, 0); err != nil {
+		t.Errorf("Expected files of kept requests to exist, but got %v", err)
+	}
+}
+
+func TestPruneInboxRequests(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	inbox = MustCreateInbox(ctx, db, inbox)
+	now := time.Now()
+	for i, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, time.Minute, 0} {
+		req := model.GenerateRequest(i)
+		req.Timestamp = now.Add(-age).UnixMilli()
+		if _, err := db.AddRequestToInbox(ctx, inbox.ID, req); err != nil {
+			t.Fatalf("Expected no error adding request, but got an error: %v", err)
+		}
+	}
+
+	removed, err := db.PruneInboxRequests(ctx, inbox.ID, model.RetentionPolicy{MaxAgeSeconds: 60 * 60})
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 removed requests, but got %d", removed)
+	}
+	removed, err = db.PruneInboxRequests(ctx, inbox.ID, model.RetentionPolicy{MaxRequests: 1})
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed request, but got %d", removed)
+	}
+
+	got, err := db.GetInboxWithRequests(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff([]int{3}, requestIDs(got.Requests)); diff != "" {
+		t.Errorf("Unexpected requests after pruning. Diff: %s", diff)
+	}
+}
+
+func TestAddRequestToInboxConcurrently(t *testing.T) {
+	ctx := context.Background()
+	db, closeDB := MustGetDB()
+	defer closeDB(ctx)
+	inbox := model.GenerateInbox()
+	inbox.Retention = model.RetentionPolicy{}
+	inbox = MustCreateInbox(ctx, db, inbox)
+
+	const total = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, total)
+	ids := make(chan int, total)
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every request has the same timestamp and ID, the repository must assign them
+			id, err := db.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(0))
+			errs <- err
+			ids <- id
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	close(ids)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error adding request, but got an error: %v", err)
+		}
+	}
+	assigned := map[int]bool{}
+	for id := range ids {
+		if assigned[id] {
+			t.Errorf("Expected unique request IDs, but %d was assigned twice", id)
+		}
+		assigned[id] = true
+	}
+
+	got, err := db.GetInboxWithRequests(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if len(got.Requests) != total {
+		t.Fatalf("Expected %d requests, but got %d", total, len(got.Requests))
+	}
+	for i, req := range got.Requests {
+		if req.ID != i {
+			t.Errorf("Expected request %d to have ID %d, but got %d", i, i, req.ID)
+		}
+	}
+}
+
+func TestGetInboxDoesNotLoadRequests(t *testing.T) {
+	ctx := context.Background()
+	db, close := MustGetDB()
+	defer close(ctx)
+	inbox := MustCreateInbox(ctx, db, model.GenerateInbox())
+	if _, err := db.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(0)); err != nil {
+		t.Fatalf("Expected no error adding request, but got an error: %v", err)
+	}
+
+	got, err := db.GetInbox(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if len(got.Requests) != 0 {
+		t.Errorf("Expected no requests, but got %d", len(got.Requests))
+	}
+	list, err := db.ListInbox(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff([]model.Inbox{got}, list); diff != "" {
+		t.Errorf("Expected only the inbox to be listed. Diff: %s", diff)
+	}
+
+	err = db.DeleteInbox(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	page, err := db.ListInboxRequests(ctx, inbox.ID)
+	if err == nil {
+		t.Errorf("Expected an error but got %v", page)
+	}
+}
+
+func requestIDs(requests []model.Request) []int {
+	ids := []int{}
+	for _, r := range requests {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
 func TestClose(t *testing.T) {
 	ctx := context.Background()
 	db, _ := MustGetDB()
@@ -213,3 +609,150 @@ func TestClose(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateInboxScenario(t *testing.T) {
+	ctx := context.Background()
+	db, closeDB := MustGetDB()
+	defer closeDB(ctx)
+	inbox := MustCreateInbox(ctx, db, model.GenerateInbox())
+
+	const total = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, total)
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.UpdateInboxScenario(ctx, inbox.ID, func(s model.Scenario) model.Scenario {
+				next := model.CopyScenario(s)
+				next.State = "running"
+				next.Calls["default"]++
+				return next
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error updating the scenario, but got an error: %v", err)
+		}
+	}
+
+	got, err := db.GetInbox(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	expected := model.Scenario{State: "running", Calls: map[string]int{"default": total}}
+	if diff := cmp.Diff(expected, got.Scenario); diff != "" {
+		t.Errorf("Expected every update to be applied. Diff: %s", diff)
+	}
+
+	t.Run("Inbox updates keep the scenario", func(t *testing.T) {
+		got.Name = "updated"
+		if _, err := db.UpdateInbox(ctx, got); err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		updated, err := db.GetInboxWithRequests(ctx, inbox.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if diff := cmp.Diff(expected, updated.Scenario); diff != "" {
+			t.Errorf("Expected the scenario to be kept. Diff: %s", diff)
+		}
+	})
+	t.Run("Not existing inbox", func(t *testing.T) {
+		_, err := db.UpdateInboxScenario(ctx, uuid.New(), func(s model.Scenario) model.Scenario { return s })
+		if !errors.Is(err, dberrors.ErrItemNotFound) {
+			t.Errorf("Expected not found error, but got %v", err)
+		}
+	})
+}
+
+func TestDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	db, closeDB := MustGetDB()
+	defer closeDB(ctx)
+	config.Set(config.CallbackMaxDeadLetters, 3)
+	inbox := MustCreateInbox(ctx, db, model.GenerateInbox())
+
+	stored := []model.DeadLetter{}
+	for i := 0; i < 5; i++ {
+		dl, err := db.PutDeadLetter(ctx, inbox.ID, model.DeadLetter{
+			RequestID: i,
+			Callback:  model.Callback{ToURL: "https://example.com"},
+			Response:  model.CallbackResponse{Code: 503},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if dl.ID == uuid.Nil || dl.Timestamp == 0 {
+			t.Fatalf("Expected the dead letter ID and timestamp to be set, got %v", dl)
+		}
+		stored = append(stored, dl)
+	}
+
+	list, err := db.ListDeadLetters(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got an error: %v", err)
+	}
+	if diff := cmp.Diff(stored[2:], list); diff != "" {
+		t.Errorf("Expected only the newest dead letters. Diff: %s", diff)
+	}
+
+	t.Run("Get dead letter", func(t *testing.T) {
+		got, err := db.GetDeadLetter(ctx, inbox.ID, stored[4].ID)
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if diff := cmp.Diff(stored[4], got); diff != "" {
+			t.Errorf("Unexpected dead letter. Diff: %s", diff)
+		}
+	})
+	t.Run("Update dead letter", func(t *testing.T) {
+		updated := stored[4]
+		updated.Response.Code = 500
+		if _, err := db.PutDeadLetter(ctx, inbox.ID, updated); err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		got, err := db.GetDeadLetter(ctx, inbox.ID, updated.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if got.Response.Code != 500 {
+			t.Errorf("Expected the dead letter to be updated, got %v", got)
+		}
+	})
+	t.Run("Delete dead letter", func(t *testing.T) {
+		if err := db.DeleteDeadLetter(ctx, inbox.ID, stored[4].ID); err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		_, err := db.GetDeadLetter(ctx, inbox.ID, stored[4].ID)
+		if !errors.Is(err, dberrors.ErrItemNotFound) {
+			t.Errorf("Expected not found error, but got %v", err)
+		}
+		err = db.DeleteDeadLetter(ctx, inbox.ID, stored[4].ID)
+		if !errors.Is(err, dberrors.ErrItemNotFound) {
+			t.Errorf("Expected not found error, but got %v", err)
+		}
+	})
+	t.Run("Deleting the inbox requests keeps the dead letters", func(t *testing.T) {
+		if err := db.DeleteInboxRequests(ctx, inbox.ID); err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		list, err := db.ListDeadLetters(ctx, inbox.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, but got an error: %v", err)
+		}
+		if len(list) != 2 {
+			t.Errorf("Expected 2 dead letters, got %d", len(list))
+		}
+	})
+	t.Run("Not existing inbox", func(t *testing.T) {
+		_, err := db.PutDeadLetter(ctx, uuid.New(), model.DeadLetter{})
+		if !errors.Is(err, dberrors.ErrItemNotFound) {
+			t.Errorf("Expected not found error, but got %v", err)
+		}
+	})
+}
@@ -3,3 +3,5 @@ package dberrors
 import "errors"
 
 var ErrItemNotFound = errors.New("item not found")
+
+var ErrInvalidCursor = errors.New("invalid cursor")
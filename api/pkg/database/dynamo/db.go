@@ -2,8 +2,10 @@ package dynamo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,11 +16,15 @@ import (
 	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
 	"github.com/jesusnoseq/request-inbox/pkg/database/option"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/retention"
 )
 
 // const inboxIDAnnotationKey = "InboxID"
 const MaxBatchItems = 25
 
+// maxScenarioUpdateAttempts bounds the optimistic updates of a scenario changed concurrently
+const maxScenarioUpdateAttempts = 10
+
 type DB struct {
 	tableName string
 	dbclient  *dynamodb.Client
@@ -64,7 +70,7 @@ func (d *DB) GetInbox(ctx context.Context, ID uuid.UUID) (model.Inbox, error) {
 		return model.Inbox{}, fmt.Errorf("failed to unmarshal DynamoDB inbox item: %w", err)
 	}
 
-	return inboxItem.Inbox, nil
+	return toInboxModel(inboxItem), nil
 }
 
 func (d *DB) ListInboxByUser(ctx context.Context, userID uuid.UUID) ([]model.Inbox, error) {
@@ -97,7 +103,7 @@ func (d *DB) ListInboxByUser(ctx context.Context, userID uuid.UUID) ([]model.Inb
 				if err != nil {
 					return inboxes, fmt.Errorf("unmarshal request failed: %w", err)
 				}
-				inboxes = append(inboxes, inboxItem.Inbox)
+				inboxes = append(inboxes, toInboxModel(inboxItem))
 			}
 		}
 	}
@@ -122,6 +128,7 @@ func (d *DB) GetInboxWithRequests(ctx context.Context, id uuid.UUID) (model.Inbo
 	})
 	in := InboxItem{}
 	requests := []model.Request{}
+	now := time.Now()
 	for queryPaginator.HasMorePages() {
 		response, err := queryPaginator.NextPage(ctx)
 		if err != nil {
@@ -140,6 +147,9 @@ func (d *DB) GetInboxWithRequests(ctx context.Context, id uuid.UUID) (model.Inbo
 				if err != nil {
 					return model.Inbox{}, fmt.Errorf("unmarshal request failed: %w", err)
 				}
+				if requestItem.isExpired(now) {
+					continue
+				}
 				requests = append(requests, requestItem.Request)
 			}
 		}
@@ -153,6 +163,170 @@ func (d *DB) GetInboxWithRequests(ctx context.Context, id uuid.UUID) (model.Inbo
 	return toInboxModel(in), nil
 }
 
+func (d *DB) ListInboxRequests(ctx context.Context, id uuid.UUID, options ...option.ListRequestsOption) (model.Page[model.Request], error) {
+	opts := option.NewListRequestsOptions(options...)
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, _ := GenInboxKey(id)
+	from, to := GenRequestSKRange(opts.From, opts.To)
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("PK = :PK AND SK BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK":   &types.AttributeValueMemberS{Value: pk},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+		},
+		ScanIndexForward: aws.Bool(!opts.IsDescending()),
+	}
+	if opts.Cursor != "" {
+		sk, err := option.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return model.Page[model.Request]{}, err
+		}
+		if !isRequestSK(sk) {
+			return model.Page[model.Request]{}, fmt.Errorf("%w: %q is not a request key", dberrors.ErrInvalidCursor, sk)
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		}
+	}
+
+	queryPaginator := dynamodb.NewQueryPaginator(d.dbclient, input)
+	results := []model.Request{}
+	lastSK := ""
+	nextCursor := ""
+	now := time.Now()
+	for queryPaginator.HasMorePages() && nextCursor == "" {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return model.Page[model.Request]{}, fmt.Errorf("list inbox requests failed: %w", err)
+		}
+		for _, item := range response.Items {
+			requestItem := RequestItem{}
+			err = attributevalue.UnmarshalMap(item, &requestItem)
+			if err != nil {
+				return model.Page[model.Request]{}, fmt.Errorf("unmarshal request failed: %w", err)
+			}
+			if requestItem.isExpired(now) || !opts.Match(requestItem.Request) {
+				continue
+			}
+			if len(results) == opts.Limit {
+				nextCursor = option.EncodeCursor(lastSK)
+				break
+			}
+			results = append(results, requestItem.Request)
+			lastSK = requestItem.SK
+		}
+	}
+	return model.NewPage(results, nextCursor), nil
+}
+
+// GetInboxRequest reads the request through its ref item, because request sort keys start with the timestamp.
+func (d *DB) GetInboxRequest(ctx context.Context, id uuid.UUID, requestID int) (model.Request, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, sk := GenRequestRefKey(id, requestID)
+	result, err := d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return model.Request{}, fmt.Errorf("failed to get request ref item from DynamoDB: %w", err)
+	}
+	if result.Item == nil {
+		return d.getLegacyInboxRequest(ctx, id, requestID)
+	}
+	refItem := RequestRefItem{}
+	err = attributevalue.UnmarshalMap(result.Item, &refItem)
+	if err != nil {
+		return model.Request{}, fmt.Errorf("failed to unmarshal DynamoDB request ref item: %w", err)
+	}
+
+	result, err = d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: refItem.RequestSK},
+		},
+	})
+	if err != nil {
+		return model.Request{}, fmt.Errorf("failed to get request item from DynamoDB: %w", err)
+	}
+	if result.Item == nil {
+		return model.Request{}, dberrors.ErrItemNotFound
+	}
+	requestItem := RequestItem{}
+	err = attributevalue.UnmarshalMap(result.Item, &requestItem)
+	if err != nil {
+		return model.Request{}, fmt.Errorf("unmarshal request failed: %w", err)
+	}
+	if requestItem.isExpired(time.Now()) {
+		return model.Request{}, dberrors.ErrItemNotFound
+	}
+	return requestItem.Request, nil
+}
+
+// getLegacyInboxRequest looks for the request in the partition of inboxes created before ref items,
+// they get them with their request counter, so the others do not pay for the query.
+func (d *DB) getLegacyInboxRequest(ctx context.Context, id uuid.UUID, requestID int) (model.Request, error) {
+	pk, sk := GenInboxKey(id)
+	result, err := d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(d.tableName),
+		ConsistentRead:       aws.Bool(true),
+		ProjectionExpression: aws.String("PK, " + RequestCounterKey),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return model.Request{}, fmt.Errorf("failed to get inbox item from DynamoDB: %w", err)
+	}
+	if _, seeded := result.Item[RequestCounterKey]; result.Item == nil || seeded {
+		return model.Request{}, dberrors.ErrItemNotFound
+	}
+
+	from, to := GenRequestSKRange(0, 0)
+	queryPaginator := dynamodb.NewQueryPaginator(d.dbclient, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("PK = :PK AND SK BETWEEN :from AND :to"),
+		FilterExpression:       aws.String("doc.ID = :ID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK":   &types.AttributeValueMemberS{Value: pk},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+			":ID":   &types.AttributeValueMemberN{Value: strconv.Itoa(requestID)},
+		},
+		// Recent requests are looked up more often
+		ScanIndexForward: aws.Bool(false),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return model.Request{}, fmt.Errorf("get inbox request failed: %w", err)
+		}
+		for _, item := range response.Items {
+			requestItem := RequestItem{}
+			err = attributevalue.UnmarshalMap(item, &requestItem)
+			if err != nil {
+				return model.Request{}, fmt.Errorf("unmarshal request failed: %w", err)
+			}
+			return requestItem.Request, nil
+		}
+	}
+	return model.Request{}, dberrors.ErrItemNotFound
+}
+
 func (d *DB) CreateInbox(
 	ctx context.Context,
 	in model.Inbox,
@@ -187,14 +361,21 @@ func (d *DB) CreateInbox(
 	return in, err
 }
 
-func (d *DB) AddRequestToInbox(ctx context.Context, id uuid.UUID, req model.Request) error {
+func (d *DB) AddRequestToInbox(ctx context.Context, id uuid.UUID, req model.Request, files ...model.RequestFile) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
-	reqItem := toRequestItem(id, req)
+	inboxItem, err := d.incrementRequestCounter(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	req.ID = int(inboxItem.RequestCounter - 1)
+	policy := retention.EffectivePolicy(inboxItem.Inbox.Retention)
+	expiresAt := retention.ExpiresAt(policy, req.Timestamp)
+	reqItem := toRequestItem(id, req, expiresAt)
 
 	item, err := attributevalue.MarshalMap(reqItem)
 	if err != nil {
-		return fmt.Errorf("error marshaling request to db: %w", err)
+		return 0, fmt.Errorf("error marshaling request to db: %w", err)
 	}
 
 	_, err = d.dbclient.PutItem(ctx, &dynamodb.PutItemInput{
@@ -203,9 +384,447 @@ func (d *DB) AddRequestToInbox(ctx context.Context, id uuid.UUID, req model.Requ
 		ReturnValues: types.ReturnValueAllOld,
 	})
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	refItem, err := attributevalue.MarshalMap(toRequestRefItem(id, req.ID, reqItem.SK, expiresAt))
+	if err != nil {
+		return 0, fmt.Errorf("error marshaling request ref to db: %w", err)
+	}
+	puts := []types.WriteRequest{{PutRequest: &types.PutRequest{Item: refItem}}}
+	for i, file := range files {
+		fileItem, err := attributevalue.MarshalMap(toRequestFileItem(id, req.ID, i, file, expiresAt))
+		if err != nil {
+			return 0, fmt.Errorf("error marshaling request file to db: %w", err)
+		}
+		puts = append(puts, types.WriteRequest{PutRequest: &types.PutRequest{Item: fileItem}})
+	}
+	err = d.batchWrite(ctx, puts)
+	if err != nil {
+		return 0, fmt.Errorf("error adding request ref and files: %w", err)
+	}
+	// Expired requests are removed by the TTL, and each request only pushes out the one that leaves the
+	// MaxRequests window, the retention sweeper prunes the rest when the policy is lowered
+	if policy.MaxRequests > 0 && req.ID >= policy.MaxRequests {
+		err = d.deleteInboxRequest(ctx, id, req.ID-policy.MaxRequests)
+	}
+	return req.ID, err
+}
+
+// deleteInboxRequest deletes a request, its ref and its files, it is not an error when it is already gone.
+func (d *DB) deleteInboxRequest(ctx context.Context, id uuid.UUID, requestID int) error {
+	pk, refSK := GenRequestRefKey(id, requestID)
+	result, err := d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: refSK},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting inbox request(get): %w", err)
+	}
+	if result.Item == nil {
+		return nil
+	}
+	refItem := RequestRefItem{}
+	err = attributevalue.UnmarshalMap(result.Item, &refItem)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal DynamoDB request ref item: %w", err)
+	}
+	deletes, err := d.listRequestFileDeletes(ctx, pk, genRequestFileSKPrefix(requestID), map[int]bool{requestID: true})
+	if err != nil {
+		return fmt.Errorf("error deleting inbox request: %w", err)
+	}
+	for _, sk := range []string{refItem.RequestSK, refSK} {
+		deletes = append(deletes, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: pk},
+				"SK": &types.AttributeValueMemberS{Value: sk},
+			}},
+		})
+	}
+	err = d.batchWrite(ctx, deletes)
+	if err != nil {
+		return fmt.Errorf("error deleting inbox request: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) UpdateInboxRequest(ctx context.Context, id uuid.UUID, req model.Request) error {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	reqAttr, err := attributevalue.MarshalMap(req)
+	if err != nil {
+		return fmt.Errorf("can not marshal request: %w", err)
+	}
+	pk, sk := GenRequestKey(id, req.Timestamp, req.ID)
+	// Only the document is set, so the TTL of the request is kept
+	_, err = d.dbclient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		UpdateExpression:    aws.String(inUpdateExpresion),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":doc": &types.AttributeValueMemberM{Value: reqAttr},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return dberrors.ErrItemNotFound
+		}
+		return fmt.Errorf("error updating request: %w", err)
+	}
+	return nil
+}
+
+// incrementRequestCounter atomically increments the request counter of the inbox and returns the updated inbox item.
+func (d *DB) incrementRequestCounter(ctx context.Context, id uuid.UUID) (InboxItem, error) {
+	pk, sk := GenInboxKey(id)
+	out, err := d.dbclient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConditionExpression: aws.String("attribute_exists(" + RequestCounterKey + ")"),
+		UpdateExpression:    aws.String("ADD " + RequestCounterKey + " :one"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return d.seedRequestCounter(ctx, id)
+		}
+		return InboxItem{}, fmt.Errorf("error incrementing inbox request counter: %w", err)
+	}
+	inboxItem := InboxItem{}
+	err = attributevalue.UnmarshalMap(out.Attributes, &inboxItem)
+	if err != nil {
+		return InboxItem{}, fmt.Errorf("failed to unmarshal DynamoDB inbox item: %w", err)
+	}
+	return inboxItem, nil
+}
+
+// seedRequestCounter starts the request counter of inboxes created before it existed after the IDs of their
+// requests, so new requests do not reuse them, and increments it.
+// Concurrent seeds are safe because the counter is only set when it does not exist yet.
+func (d *DB) seedRequestCounter(ctx context.Context, id uuid.UUID) (InboxItem, error) {
+	pk, sk := GenInboxKey(id)
+	next, err := d.migrateLegacyRequests(ctx, id)
+	if err != nil {
+		return InboxItem{}, err
+	}
+	out, err := d.dbclient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		UpdateExpression:    aws.String("SET " + RequestCounterKey + " = if_not_exists(" + RequestCounterKey + ", :next) + :one"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":next": &types.AttributeValueMemberN{Value: strconv.Itoa(next)},
+			":one":  &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return InboxItem{}, dberrors.ErrItemNotFound
+		}
+		return InboxItem{}, fmt.Errorf("error seeding inbox request counter: %w", err)
+	}
+	inboxItem := InboxItem{}
+	err = attributevalue.UnmarshalMap(out.Attributes, &inboxItem)
+	if err != nil {
+		return InboxItem{}, fmt.Errorf("failed to unmarshal DynamoDB inbox item: %w", err)
+	}
+	return inboxItem, nil
+}
+
+// migrateLegacyRequests adds the ref items of the requests keyed only by timestamp and returns the ID after
+// the highest one stored in the inbox partition.
+func (d *DB) migrateLegacyRequests(ctx context.Context, id uuid.UUID) (int, error) {
+	pk, _ := GenInboxKey(id)
+	from, to := GenRequestSKRange(0, 0)
+	queryPaginator := dynamodb.NewQueryPaginator(d.dbclient, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("PK = :PK AND SK BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK":   &types.AttributeValueMemberS{Value: pk},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+		},
+		ProjectionExpression: aws.String("PK, SK, doc.ID"),
+	})
+	next := 0
+	putRefs := []types.WriteRequest{}
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("error seeding inbox request counter(query): %w", err)
+		}
+		for _, item := range response.Items {
+			requestItem := RequestItem{}
+			err = attributevalue.UnmarshalMap(item, &requestItem)
+			if err != nil {
+				return 0, fmt.Errorf("unmarshal request failed: %w", err)
+			}
+			if requestItem.Request.ID >= next {
+				next = requestItem.Request.ID + 1
+			}
+			if _, ok := requestSKID(requestItem.SK); ok {
+				continue
+			}
+			refItem, err := attributevalue.MarshalMap(toRequestRefItem(id, requestItem.Request.ID, requestItem.SK, 0))
+			if err != nil {
+				return 0, fmt.Errorf("error marshaling request ref to db: %w", err)
+			}
+			putRefs = append(putRefs, types.WriteRequest{PutRequest: &types.PutRequest{Item: refItem}})
+		}
+	}
+	err := d.batchWrite(ctx, putRefs)
+	if err != nil {
+		return 0, fmt.Errorf("error adding request refs: %w", err)
+	}
+	return next, nil
+}
+
+func (d *DB) UpdateInboxScenario(ctx context.Context, id uuid.UUID, update func(model.Scenario) model.Scenario) (model.Scenario, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, sk := GenInboxKey(id)
+	key := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: pk},
+		"SK": &types.AttributeValueMemberS{Value: sk},
+	}
+	// Optimistic concurrency: the update only succeeds when nobody changed the scenario since it was read
+	for attempt := 1; ; attempt++ {
+		out, err := d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:            aws.String(d.tableName),
+			Key:                  key,
+			ConsistentRead:       aws.Bool(true),
+			ProjectionExpression: aws.String("PK, " + ScenarioKey + ", " + ScenarioVersionKey),
+		})
+		if err != nil {
+			return model.Scenario{}, fmt.Errorf("error getting inbox scenario: %w", err)
+		}
+		if out.Item == nil {
+			return model.Scenario{}, dberrors.ErrItemNotFound
+		}
+		inboxItem := InboxItem{}
+		err = attributevalue.UnmarshalMap(out.Item, &inboxItem)
+		if err != nil {
+			return model.Scenario{}, fmt.Errorf("failed to unmarshal DynamoDB inbox item: %w", err)
+		}
+		current := model.Scenario{}
+		if inboxItem.Scenario != nil {
+			current = *inboxItem.Scenario
+		}
+		next := update(current)
+		scenarioAttr, err := attributevalue.Marshal(next)
+		if err != nil {
+			return model.Scenario{}, fmt.Errorf("can not marshal scenario: %w", err)
+		}
+		_, err = d.dbclient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(d.tableName),
+			Key:       key,
+			ConditionExpression: aws.String("attribute_exists(PK) AND (attribute_not_exists(" +
+				ScenarioVersionKey + ") OR " + ScenarioVersionKey + " = :version)"),
+			UpdateExpression: aws.String("SET " + ScenarioKey + " = :scenario, " + ScenarioVersionKey + " = :next"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":scenario": scenarioAttr,
+				":version":  &types.AttributeValueMemberN{Value: strconv.FormatInt(inboxItem.ScenarioVersion, 10)},
+				":next":     &types.AttributeValueMemberN{Value: strconv.FormatInt(inboxItem.ScenarioVersion+1, 10)},
+			},
+		})
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			if ctx.Err() != nil {
+				return model.Scenario{}, ctx.Err()
+			}
+			if attempt >= maxScenarioUpdateAttempts {
+				return model.Scenario{}, fmt.Errorf("error updating inbox scenario after %d attempts: %w", attempt, err)
+			}
+			continue
+		}
+		if err != nil {
+			return model.Scenario{}, fmt.Errorf("error updating inbox scenario: %w", err)
+		}
+		return next, nil
+	}
+}
+
+func (d *DB) PruneInboxRequests(ctx context.Context, id uuid.UUID, policy model.RetentionPolicy) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	return d.pruneInboxRequests(ctx, id, policy)
+}
+
+func (d *DB) pruneInboxRequests(ctx context.Context, id uuid.UUID, policy model.RetentionPolicy) (int, error) {
+	pk, _ := GenInboxKey(id)
+	from, to := GenRequestSKRange(0, 0)
+	cutoff := retention.Cutoff(policy, time.Now())
+
+	// Newest first, so everything after MaxRequests keys is removed
+	queryPaginator := dynamodb.NewQueryPaginator(d.dbclient, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("PK = :PK AND SK BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK":   &types.AttributeValueMemberS{Value: pk},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+		},
+		ProjectionExpression: aws.String("PK, SK, doc.ID"),
+		ScanIndexForward:     aws.Bool(false),
+	})
+	deleteRequests := []types.WriteRequest{}
+	prunedIDs := map[int]bool{}
+	kept := 0
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("error pruning inbox requests(query): %w", err)
+		}
+		for _, item := range response.Items {
+			sk := item["SK"].(*types.AttributeValueMemberS).Value
+			ts, err := requestSKTimestamp(sk)
+			if err != nil {
+				return 0, fmt.Errorf("error pruning inbox requests: %w", err)
+			}
+			if (policy.MaxRequests <= 0 || kept < policy.MaxRequests) && ts >= cutoff {
+				kept++
+				continue
+			}
+			requestItem := RequestItem{}
+			err = attributevalue.UnmarshalMap(item, &requestItem)
+			if err != nil {
+				return 0, fmt.Errorf("unmarshal request failed: %w", err)
+			}
+			prunedIDs[requestItem.Request.ID] = true
+			deleteRequests = append(deleteRequests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+					"PK": item["PK"],
+					"SK": item["SK"],
+				}},
+			})
+		}
+	}
+
+	deleteFiles, err := d.listRequestFileDeletes(ctx, pk, FileKey+KS, prunedIDs)
+	if err != nil {
+		return 0, fmt.Errorf("error pruning inbox requests: %w", err)
+	}
+	deleteRefs := make([]types.WriteRequest, 0, len(prunedIDs))
+	for requestID := range prunedIDs {
+		_, refSK := GenRequestRefKey(id, requestID)
+		deleteRefs = append(deleteRefs, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: pk},
+				"SK": &types.AttributeValueMemberS{Value: refSK},
+			}},
+		})
+	}
+	err = d.batchWrite(ctx, append(append(deleteRequests, deleteFiles...), deleteRefs...))
+	if err != nil {
+		return 0, fmt.Errorf("error pruning inbox requests: %w", err)
+	}
+	return len(deleteRequests), nil
+}
+
+// listRequestFileDeletes returns the delete requests of the files of the given request IDs among those whose
+// sort key starts with skPrefix.
+func (d *DB) listRequestFileDeletes(ctx context.Context, pk string, skPrefix string, requestIDs map[int]bool) ([]types.WriteRequest, error) {
+	deletes := []types.WriteRequest{}
+	if len(requestIDs) == 0 {
+		return deletes, nil
+	}
+	queryPaginator := dynamodb.NewQueryPaginator(d.dbclient, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("PK = :PK AND begins_with(SK, :SK)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK": &types.AttributeValueMemberS{Value: pk},
+			":SK": &types.AttributeValueMemberS{Value: skPrefix},
+		},
+		ProjectionExpression: aws.String("PK, SK"),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing request files: %w", err)
+		}
+		for _, item := range response.Items {
+			sk := item["SK"].(*types.AttributeValueMemberS).Value
+			if requestID, ok := fileSKRequestID(sk); !ok || !requestIDs[requestID] {
+				continue
+			}
+			deletes = append(deletes, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+					"PK": item["PK"],
+					"SK": item["SK"],
+				}},
+			})
+		}
+	}
+	return deletes, nil
+}
+
+func (d *DB) GetRequestFile(ctx context.Context, id uuid.UUID, requestID int, index int) (model.RequestFile, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, sk := GenRequestFileKey(id, requestID, index)
+	result, err := d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return model.RequestFile{}, fmt.Errorf("failed to get request file item from DynamoDB: %w", err)
+	}
+	if result.Item == nil {
+		return model.RequestFile{}, dberrors.ErrItemNotFound
+	}
+
+	var fileItem RequestFileItem
+	err = attributevalue.UnmarshalMap(result.Item, &fileItem)
+	if err != nil {
+		return model.RequestFile{}, fmt.Errorf("failed to unmarshal DynamoDB request file item: %w", err)
+	}
+	if fileItem.TTL > 0 && fileItem.TTL < time.Now().Unix() {
+		return model.RequestFile{}, dberrors.ErrItemNotFound
 	}
-	return err
+	return fileItem.File, nil
+}
+
+func (d *DB) batchWrite(ctx context.Context, writeRequests []types.WriteRequest) error {
+	lenWriteRequests := len(writeRequests)
+	for i := 0; i < lenWriteRequests; i += MaxBatchItems {
+		end := i + MaxBatchItems
+		if end > lenWriteRequests {
+			end = lenWriteRequests
+		}
+		_, err := d.dbclient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{
+				d.tableName: writeRequests[i:end],
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (d *DB) Close(context.Context) error {
@@ -217,6 +836,7 @@ func (d *DB) UpdateInbox(
 	in model.Inbox,
 ) (model.Inbox, error) {
 	in.Requests = []model.Request{}
+	in.Scenario = model.Scenario{}
 	inboxAttr, err := attributevalue.MarshalMap(in)
 	if err != nil {
 		return in, fmt.Errorf("can not marshal inbox: %w", err)
@@ -324,22 +944,10 @@ func (d *DB) deleteInboxWithFilter(ctx context.Context, id uuid.UUID, toDeletefi
 		return dberrors.ErrItemNotFound
 	}
 
-	lenDeleteRequests := len(deleteRequests)
-	for i := 0; i < lenDeleteRequests; i += MaxBatchItems {
-		end := i + MaxBatchItems
-		if end > lenDeleteRequests {
-			end = lenDeleteRequests
-		}
-		_, err := d.dbclient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-			RequestItems: map[string][]types.WriteRequest{
-				d.tableName: deleteRequests[i:end],
-			},
-		})
-		if err != nil {
-			return fmt.Errorf("error deleting inbox: %w", err)
-		}
+	err := d.batchWrite(ctx, deleteRequests)
+	if err != nil {
+		return fmt.Errorf("error deleting inbox: %w", err)
 	}
-
 	return nil
 }
 
@@ -352,7 +960,7 @@ func (d *DB) DeleteInbox(ctx context.Context, id uuid.UUID) error {
 func (d *DB) DeleteInboxRequests(ctx context.Context, id uuid.UUID) error {
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
-	return d.deleteInboxWithFilter(ctx, id, func(pk, sk string) bool { return isRequestSK(sk) })
+	return d.deleteInboxWithFilter(ctx, id, func(pk, sk string) bool { return isRequestSK(sk) || isFileSK(sk) || isRequestRefSK(sk) })
 }
 
 func MustMarshallUUID(id uuid.UUID) []byte {
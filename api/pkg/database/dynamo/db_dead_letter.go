@@ -0,0 +1,164 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+func (d *DB) PutDeadLetter(ctx context.Context, id uuid.UUID, dl model.DeadLetter) (model.DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	if dl.ID == uuid.Nil {
+		dl.ID = uuid.Must(uuid.NewV7())
+	}
+	if dl.Timestamp == 0 {
+		dl.Timestamp = time.Now().UnixMilli()
+	}
+	item, err := attributevalue.MarshalMap(toDeadLetterItem(id, dl))
+	if err != nil {
+		return model.DeadLetter{}, fmt.Errorf("error marshaling dead letter to db: %w", err)
+	}
+	_, err = d.dbclient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return model.DeadLetter{}, fmt.Errorf("error storing dead letter: %w", err)
+	}
+	err = d.pruneDeadLetters(ctx, id, config.GetInt(config.CallbackMaxDeadLetters))
+	if err != nil {
+		return model.DeadLetter{}, err
+	}
+	return dl, nil
+}
+
+// pruneDeadLetters deletes the oldest dead letters of the inbox beyond limit, 0 means unlimited.
+func (d *DB) pruneDeadLetters(ctx context.Context, id uuid.UUID, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	pk, _ := GenInboxKey(id)
+	paginator := dynamodb.NewQueryPaginator(d.dbclient, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("PK = :PK AND begins_with(SK, :SK)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK": &types.AttributeValueMemberS{Value: pk},
+			":SK": &types.AttributeValueMemberS{Value: DeadLetterKey + KS},
+		},
+		ProjectionExpression: aws.String("PK, SK"),
+		ScanIndexForward:     aws.Bool(false),
+	})
+	kept := 0
+	deletes := []types.WriteRequest{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error querying dead letters: %w", err)
+		}
+		for _, item := range page.Items {
+			kept++
+			if kept <= limit {
+				continue
+			}
+			deletes = append(deletes, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+					"PK": item["PK"],
+					"SK": item["SK"],
+				}},
+			})
+		}
+	}
+	err := d.batchWrite(ctx, deletes)
+	if err != nil {
+		return fmt.Errorf("error pruning dead letters: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) ListDeadLetters(ctx context.Context, id uuid.UUID) ([]model.DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, _ := GenInboxKey(id)
+	paginator := dynamodb.NewQueryPaginator(d.dbclient, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("PK = :PK AND begins_with(SK, :SK)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":PK": &types.AttributeValueMemberS{Value: pk},
+			":SK": &types.AttributeValueMemberS{Value: DeadLetterKey + KS},
+		},
+	})
+	deadLetters := []model.DeadLetter{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error querying dead letters: %w", err)
+		}
+		for _, item := range page.Items {
+			dlItem := DeadLetterItem{}
+			err = attributevalue.UnmarshalMap(item, &dlItem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal DynamoDB dead letter item: %w", err)
+			}
+			deadLetters = append(deadLetters, dlItem.DeadLetter)
+		}
+	}
+	return deadLetters, nil
+}
+
+func (d *DB) GetDeadLetter(ctx context.Context, id uuid.UUID, deadLetterID uuid.UUID) (model.DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, sk := GenDeadLetterKey(id, deadLetterID)
+	result, err := d.dbclient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return model.DeadLetter{}, fmt.Errorf("failed to get dead letter item from DynamoDB: %w", err)
+	}
+	if result.Item == nil {
+		return model.DeadLetter{}, dberrors.ErrItemNotFound
+	}
+	dlItem := DeadLetterItem{}
+	err = attributevalue.UnmarshalMap(result.Item, &dlItem)
+	if err != nil {
+		return model.DeadLetter{}, fmt.Errorf("failed to unmarshal DynamoDB dead letter item: %w", err)
+	}
+	return dlItem.DeadLetter, nil
+}
+
+func (d *DB) DeleteDeadLetter(ctx context.Context, id uuid.UUID, deadLetterID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	pk, sk := GenDeadLetterKey(id, deadLetterID)
+	_, err := d.dbclient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return dberrors.ErrItemNotFound
+		}
+		return fmt.Errorf("error deleting dead letter: %w", err)
+	}
+	return nil
+}
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
@@ -109,7 +113,7 @@ func TestListInbox(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
-	err = inboxDAO.AddRequestToInbox(ctx, createdInbox2.ID, model.GenerateRequest(0))
+	_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox2.ID, model.GenerateRequest(0))
 	if err != nil {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
@@ -190,12 +194,12 @@ func TestCreateInboxAndAddRequests(t *testing.T) {
 	defer deleteInbox(t, inboxDAO, createdInbox.ID)
 
 	req := model.GenerateRequest(1)
-	err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, req)
+	_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, req)
 	if err != nil {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
 	req = model.GenerateRequest(2)
-	err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, req)
+	_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, req)
 	if err != nil {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
@@ -208,6 +212,258 @@ func TestCreateInboxAndAddRequests(t *testing.T) {
 	}
 }
 
+func TestAddRequestsInTheSameMillisecond(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	createdInbox, err := inboxDAO.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	defer deleteInbox(t, inboxDAO, createdInbox.ID)
+
+	req := model.GenerateRequest(0)
+	req.Timestamp = time.Now().UnixMilli()
+	for i := 0; i < 3; i++ {
+		id, err := inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, req)
+		if err != nil {
+			t.Errorf("Expected no error error but got %s.", err)
+		}
+		if id != i {
+			t.Errorf("Expected request ID %d but got %d.", i, id)
+		}
+	}
+
+	inbox, err = inboxDAO.GetInboxWithRequests(ctx, createdInbox.ID)
+	if err != nil {
+		t.Errorf("Expected no error but got %s.", err)
+	}
+	if len(inbox.Requests) != 3 {
+		t.Errorf("Expected 3 request but got %d.", len(inbox.Requests))
+	}
+}
+
+func TestGetInboxRequest(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	inbox := model.GenerateInbox()
+	createdInbox, err := inboxDAO.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	defer deleteInbox(t, inboxDAO, createdInbox.ID)
+
+	req := model.GenerateRequest(0)
+	for i := 0; i < 2; i++ {
+		req.ID, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, req)
+		if err != nil {
+			t.Errorf("Expected no error error but got %s.", err)
+		}
+	}
+
+	got, err := inboxDAO.GetInboxRequest(ctx, createdInbox.ID, 1)
+	if err != nil {
+		t.Errorf("Expected no error but got %s.", err)
+	}
+	expectJSONEquals(t, got, req)
+
+	_, err = inboxDAO.GetInboxRequest(ctx, createdInbox.ID, 2)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected %s error but got %v.", dberrors.ErrItemNotFound, err)
+	}
+}
+
+func TestAddRequestToInboxThatDoesNotExists(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	_, err := inboxDAO.AddRequestToInbox(ctx, uuid.New(), model.GenerateRequest(0))
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected error %s but got %v.", dberrors.ErrItemNotFound, err)
+	}
+}
+
+func TestAddRequestToLegacyInbox(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	inbox := model.GenerateInbox()
+	inbox.ID = uuid.New()
+	inbox.Requests = []model.Request{}
+	// Inboxes created before the request counter have none and their requests are keyed by timestamp
+	pk, sk := dynamo.GenInboxKey(inbox.ID)
+	putLegacyItem(t, ctx, map[string]any{"PK": pk, "SK": sk, "OWNER_ID": "USER#" + uuid.Nil.String(), "doc": inbox})
+	defer deleteInbox(t, inboxDAO, inbox.ID)
+	base := time.Now().Add(-time.Minute).UnixMilli()
+	for i := 0; i < 2; i++ {
+		req := model.GenerateRequest(i)
+		req.Timestamp = base + int64(i)
+		putLegacyItem(t, ctx, map[string]any{"PK": pk, "SK": dynamo.RequestKey + dynamo.KS + strconv.FormatInt(req.Timestamp, 10), "doc": req})
+	}
+	expectRequestID := func(requestID int) {
+		t.Helper()
+		req, err := inboxDAO.GetInboxRequest(ctx, inbox.ID, requestID)
+		if err != nil {
+			t.Errorf("Expected no error getting request %d but got %s.", requestID, err)
+		}
+		t_util.AssertEquals(t, req.ID, requestID)
+	}
+	expectRequestID(1)
+
+	for want := 2; want < 4; want++ {
+		id, err := inboxDAO.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(0))
+		if err != nil {
+			t.Fatalf("Expected no error error but got %s.", err)
+		}
+		if id != want {
+			t.Errorf("Expected request ID %d but got %d.", want, id)
+		}
+	}
+	got, err := inboxDAO.GetInboxWithRequests(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if len(got.Requests) != 4 {
+		t.Errorf("Expected 4 request but got %d.", len(got.Requests))
+	}
+	expectRequestID(1)
+	expectRequestID(3)
+}
+
+func putLegacyItem(t *testing.T, ctx context.Context, item map[string]any) {
+	t.Helper()
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		t.Fatalf("Expected no error marshaling item but got %s.", err)
+	}
+	s, err := dynamo.GetSession(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error getting session but got %s.", err)
+	}
+	_, err = dynamo.NewDynamoClient(s).PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("request-inbox-test"),
+		Item:      av,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error putting item but got %s.", err)
+	}
+}
+
+func TestUpdateInboxScenario(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	createdInbox, err := inboxDAO.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	defer deleteInbox(t, inboxDAO, createdInbox.ID)
+
+	for i := 0; i < 3; i++ {
+		_, err = inboxDAO.UpdateInboxScenario(ctx, createdInbox.ID, func(s model.Scenario) model.Scenario {
+			next := model.CopyScenario(s)
+			next.State = "running"
+			next.Calls["default"]++
+			return next
+		})
+		if err != nil {
+			t.Fatalf("Expected no error error but got %s.", err)
+		}
+	}
+	createdInbox.Name = "updated"
+	if _, err = inboxDAO.UpdateInbox(ctx, createdInbox); err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+
+	got, err := inboxDAO.GetInbox(ctx, createdInbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	expectJSONEquals(t, got.Scenario, model.Scenario{State: "running", Calls: map[string]int{"default": 3}})
+
+	_, err = inboxDAO.UpdateInboxScenario(ctx, uuid.New(), func(s model.Scenario) model.Scenario { return s })
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected error %s but got %v.", dberrors.ErrItemNotFound, err)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	config.Set(config.CallbackMaxDeadLetters, 2)
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	createdInbox, err := inboxDAO.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	defer deleteInbox(t, inboxDAO, createdInbox.ID)
+
+	stored := []model.DeadLetter{}
+	for i := 0; i < 3; i++ {
+		dl, err := inboxDAO.PutDeadLetter(ctx, createdInbox.ID, model.DeadLetter{RequestID: i, Response: model.CallbackResponse{Code: 503}})
+		if err != nil {
+			t.Fatalf("Expected no error error but got %s.", err)
+		}
+		stored = append(stored, dl)
+	}
+
+	list, err := inboxDAO.ListDeadLetters(ctx, createdInbox.ID)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	expectJSONEquals(t, list, stored[1:])
+
+	got, err := inboxDAO.GetDeadLetter(ctx, createdInbox.ID, stored[2].ID)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	expectJSONEquals(t, got, stored[2])
+
+	if err = inboxDAO.DeleteDeadLetter(ctx, createdInbox.ID, stored[2].ID); err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	_, err = inboxDAO.GetDeadLetter(ctx, createdInbox.ID, stored[2].ID)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected error %s but got %v.", dberrors.ErrItemNotFound, err)
+	}
+	err = inboxDAO.DeleteDeadLetter(ctx, createdInbox.ID, stored[2].ID)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected error %s but got %v.", dberrors.ErrItemNotFound, err)
+	}
+}
+
+func TestAddRequestsWithRetention(t *testing.T) {
+	inboxDAO, ctx := setupTest()
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{}
+	inbox.Retention = model.RetentionPolicy{MaxRequests: 2, MaxAgeSeconds: 60}
+	createdInbox, err := inboxDAO.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatalf("Expected no error error but got %s.", err)
+	}
+	defer deleteInbox(t, inboxDAO, createdInbox.ID)
+
+	for i := 0; i < 3; i++ {
+		_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(i))
+		if err != nil {
+			t.Errorf("Expected no error error but got %s.", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	inbox, err = inboxDAO.GetInboxWithRequests(ctx, createdInbox.ID)
+	if err != nil {
+		t.Errorf("Expected no error but got %s.", err)
+	}
+	if len(inbox.Requests) != 2 {
+		t.Errorf("Expected 2 request but got %d.", len(inbox.Requests))
+	}
+	_, err = inboxDAO.GetInboxRequest(ctx, createdInbox.ID, 0)
+	if !errors.Is(err, dberrors.ErrItemNotFound) {
+		t.Errorf("Expected %s error for the pushed out request but got %v.", dberrors.ErrItemNotFound, err)
+	}
+	removed, err := inboxDAO.PruneInboxRequests(ctx, createdInbox.ID, model.RetentionPolicy{MaxRequests: 1})
+	if err != nil {
+		t.Errorf("Expected no error but got %s.", err)
+	}
+	t_util.AssertEquals(t, removed, 1)
+}
+
 func TestDeleteInbox(t *testing.T) {
 	inboxDAO, ctx := setupTest()
 	t.Run("Inbox without request", func(t *testing.T) {
@@ -287,11 +543,11 @@ func TestDeleteInboxRequests(t *testing.T) {
 			t.Errorf("Expected no error error but got %s.", err)
 		}
 		defer deleteInbox(t, inboxDAO, createdInbox.ID)
-		err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(1))
+		_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(1))
 		if err != nil {
 			t.Errorf("Expected no error error but got %s.", err)
 		}
-		err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(2))
+		_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(2))
 		if err != nil {
 			t.Errorf("Expected no error error but got %s.", err)
 		}
@@ -360,7 +616,7 @@ func TestGetInbox(t *testing.T) {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
 	defer deleteInbox(t, inboxDAO, createdInbox.ID)
-	err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(0))
+	_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox.ID, model.GenerateRequest(0))
 	if err != nil {
 		t.Errorf("Expected no error but got %s.", err)
 	}
@@ -413,7 +669,7 @@ func TestListInboxByUser(t *testing.T) {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
 	defer deleteInbox(t, inboxDAO, createdInbox1.ID)
-	err = inboxDAO.AddRequestToInbox(ctx, createdInbox1.ID, model.GenerateRequest(0))
+	_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox1.ID, model.GenerateRequest(0))
 	if err != nil {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
@@ -424,7 +680,7 @@ func TestListInboxByUser(t *testing.T) {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
 	defer deleteInbox(t, inboxDAO, createdInbox2.ID)
-	err = inboxDAO.AddRequestToInbox(ctx, createdInbox2.ID, model.GenerateRequest(0))
+	_, err = inboxDAO.AddRequestToInbox(ctx, createdInbox2.ID, model.GenerateRequest(0))
 	if err != nil {
 		t.Errorf("Expected no error error but got %s.", err)
 	}
@@ -1,6 +1,7 @@
 package dynamo
 
 import (
+	"fmt"
 	"log"
 	"strconv"
 	"strings"
@@ -22,12 +23,41 @@ type InboxItem struct {
 	SK    string      `dynamodbav:"SK"`
 	OWNER string      `dynamodbav:"OWNER_ID"`
 	Inbox model.Inbox `dynamodbav:"doc"`
+	// RequestCounter is the next request ID of the inbox, it is only changed with atomic updates.
+	// Inboxes created before it existed do not have it until their first new request seeds it.
+	RequestCounter int64 `dynamodbav:"REQUEST_COUNTER"`
+	// Scenario is kept out of doc so inbox updates do not overwrite it, ScenarioVersion guards its conditional updates
+	Scenario        *model.Scenario `dynamodbav:"SCENARIO,omitempty"`
+	ScenarioVersion int64           `dynamodbav:"SCENARIO_VERSION,omitempty"`
 }
 
 type RequestItem struct {
 	PK      string        `dynamodbav:"PK"`
 	SK      string        `dynamodbav:"SK"`
 	Request model.Request `dynamodbav:"doc"`
+	// TTL is the native DynamoDB expiration time in unix seconds, the table must use it as TTL attribute
+	TTL int64 `dynamodbav:"TTL,omitempty"`
+}
+
+type RequestFileItem struct {
+	PK   string            `dynamodbav:"PK"`
+	SK   string            `dynamodbav:"SK"`
+	File model.RequestFile `dynamodbav:"doc"`
+	TTL  int64             `dynamodbav:"TTL,omitempty"`
+}
+
+// RequestRefItem points to a request by its ID, because request sort keys start with the timestamp.
+type RequestRefItem struct {
+	PK        string `dynamodbav:"PK"`
+	SK        string `dynamodbav:"SK"`
+	RequestSK string `dynamodbav:"REQUEST_SK"`
+	TTL       int64  `dynamodbav:"TTL,omitempty"`
+}
+
+type DeadLetterItem struct {
+	PK         string           `dynamodbav:"PK"`
+	SK         string           `dynamodbav:"SK"`
+	DeadLetter model.DeadLetter `dynamodbav:"doc"`
 }
 
 type UserItem struct {
@@ -46,9 +76,15 @@ type APIKeyItem struct {
 
 const InboxKey = "INBOX"
 const RequestKey = "REQUEST"
+const FileKey = "FILE"
+const RequestRefKey = "REF"
 const UserKey = "USER"
 const OWNERKey = "OWNER_ID"
 const APIKeyKey = "API_KEY"
+const RequestCounterKey = "REQUEST_COUNTER"
+const ScenarioKey = "SCENARIO"
+const ScenarioVersionKey = "SCENARIO_VERSION"
+const DeadLetterKey = "DEAD_LETTER"
 const KS = "#" // Key Separator
 
 func GenAPIKeyKey(id uuid.UUID) (string, string) {
@@ -71,12 +107,87 @@ func GenInboxKey(id uuid.UUID) (string, string) {
 	return InboxKey + KS + id.String(), InboxKey
 }
 
-func GenRequestKey(id uuid.UUID) (string, string) {
-	return InboxKey + KS + id.String(), RequestKey + KS + strconv.FormatInt(time.Now().UnixMilli(), 10)
+// GenRequestKey returns the keys of a request, the sort key is ordered by timestamp (unix milliseconds)
+// and the request ID makes it unique when several requests arrive in the same millisecond.
+func GenRequestKey(id uuid.UUID, timestamp int64, requestID int) (string, string) {
+	return InboxKey + KS + id.String(), RequestKey + KS + fmt.Sprintf("%013d", timestamp) + KS + fmt.Sprintf("%010d", requestID)
+}
+
+// GenRequestFileKey returns the keys of the content of the index-th file of a request.
+func GenRequestFileKey(id uuid.UUID, requestID int, index int) (string, string) {
+	return InboxKey + KS + id.String(), genRequestFileSKPrefix(requestID) + fmt.Sprintf("%05d", index)
+}
+
+// genRequestFileSKPrefix returns the start of the sort keys of the files of a request.
+func genRequestFileSKPrefix(requestID int) string {
+	return FileKey + KS + fmt.Sprintf("%010d", requestID) + KS
+}
+
+// GenRequestRefKey returns the keys of the item that points to a request by its ID.
+func GenRequestRefKey(id uuid.UUID, requestID int) (string, string) {
+	return InboxKey + KS + id.String(), RequestRefKey + KS + fmt.Sprintf("%010d", requestID)
+}
+
+// GenDeadLetterKey returns the keys of a dead letter, its ID is a UUID v7 so the sort keys are ordered by creation.
+func GenDeadLetterKey(id uuid.UUID, deadLetterID uuid.UUID) (string, string) {
+	return InboxKey + KS + id.String(), DeadLetterKey + KS + deadLetterID.String()
+}
+
+// GenRequestSKRange returns the inclusive sort key bounds of the requests received between from and to (unix milliseconds).
+// Zero values leave the range open on that side.
+func GenRequestSKRange(from, to int64) (string, string) {
+	start := RequestKey + KS
+	end := RequestKey + KS + "~"
+	if from > 0 {
+		start = RequestKey + KS + fmt.Sprintf("%013d", from)
+	}
+	if to > 0 {
+		end = RequestKey + KS + fmt.Sprintf("%013d", to) + KS + "~"
+	}
+	return start, end
+}
+
+// requestSKTimestamp returns the unix milliseconds encoded in a request sort key.
+func requestSKTimestamp(sk string) (int64, error) {
+	parts := strings.Split(sk, KS)
+	if len(parts) < 2 || parts[0] != RequestKey {
+		return 0, fmt.Errorf("%q is not a request key", sk)
+	}
+	return strconv.ParseInt(parts[1], 10, 64)
+}
+
+// requestSKID returns the request ID encoded in a request sort key, old keys only have the timestamp.
+func requestSKID(sk string) (int, bool) {
+	parts := strings.Split(sk, KS)
+	if len(parts) < 3 || parts[0] != RequestKey {
+		return 0, false
+	}
+	id, err := strconv.Atoi(parts[2])
+	return id, err == nil
+}
+
+// fileSKRequestID returns the request ID encoded in a file sort key.
+func fileSKRequestID(sk string) (int, bool) {
+	parts := strings.Split(sk, KS)
+	if len(parts) < 3 || parts[0] != FileKey {
+		return 0, false
+	}
+	id, err := strconv.Atoi(parts[1])
+	return id, err == nil
+}
+
+// isExpired reports whether the item is past its TTL, DynamoDB can take a while to delete expired items.
+func (ri RequestItem) isExpired(now time.Time) bool {
+	return ri.TTL > 0 && ri.TTL < now.Unix()
 }
 
 func toInboxModel(inI InboxItem) model.Inbox {
-	return inI.Inbox
+	in := inI.Inbox
+	in.Scenario = model.Scenario{}
+	if inI.Scenario != nil {
+		in.Scenario = *inI.Scenario
+	}
+	return in
 }
 
 func isInboxSK(sk string) bool {
@@ -87,9 +198,18 @@ func isRequestSK(sk string) bool {
 	return strings.HasPrefix(sk, RequestKey)
 }
 
+func isFileSK(sk string) bool {
+	return strings.HasPrefix(sk, FileKey)
+}
+
+func isRequestRefSK(sk string) bool {
+	return strings.HasPrefix(sk, RequestRefKey)
+}
+
 func toInboxItem(in model.Inbox) InboxItem {
 	pk, sk := GenInboxKey(in.ID)
 	in.Requests = []model.Request{}
+	in.Scenario = model.Scenario{}
 	owner, _ := GenUserKey(uuid.Nil)
 	if in.OwnerID != uuid.Nil {
 		owner, _ = GenUserKey(in.OwnerID)
@@ -102,12 +222,46 @@ func toInboxItem(in model.Inbox) InboxItem {
 	}
 }
 
-func toRequestItem(id uuid.UUID, req model.Request) RequestItem {
-	pk, sk := GenRequestKey(id)
+func toRequestItem(id uuid.UUID, req model.Request, expiresAt int64) RequestItem {
+	timestamp := req.Timestamp
+	if timestamp <= 0 {
+		timestamp = time.Now().UnixMilli()
+	}
+	pk, sk := GenRequestKey(id, timestamp, req.ID)
 	return RequestItem{
 		PK:      pk,
 		SK:      sk,
 		Request: req,
+		TTL:     expiresAt,
+	}
+}
+
+func toRequestFileItem(id uuid.UUID, requestID int, index int, file model.RequestFile, expiresAt int64) RequestFileItem {
+	pk, sk := GenRequestFileKey(id, requestID, index)
+	return RequestFileItem{
+		PK:   pk,
+		SK:   sk,
+		File: file,
+		TTL:  expiresAt,
+	}
+}
+
+func toRequestRefItem(id uuid.UUID, requestID int, requestSK string, expiresAt int64) RequestRefItem {
+	pk, sk := GenRequestRefKey(id, requestID)
+	return RequestRefItem{
+		PK:        pk,
+		SK:        sk,
+		RequestSK: requestSK,
+		TTL:       expiresAt,
+	}
+}
+
+func toDeadLetterItem(id uuid.UUID, dl model.DeadLetter) DeadLetterItem {
+	pk, sk := GenDeadLetterKey(id, dl.ID)
+	return DeadLetterItem{
+		PK:         pk,
+		SK:         sk,
+		DeadLetter: dl,
 	}
 }
 
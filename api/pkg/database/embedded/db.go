@@ -2,20 +2,55 @@ package embedded
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
 	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
 	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/retention"
 )
 
 const inboxPrefix = "inbox#"
 const userPrefix = "user#"
 const apiKeyPrefix = "apiKey#"
 
+// Requests are stored next to their inbox, as Dynamo does, so writing a request does not rewrite the inbox:
+//
+//	inbox#<id>                inbox metadata, without requests
+//	inbox#<id>#seq            next request sequence, leased by a badger.Sequence
+//	inbox#<id>#scenario       scenario state, updated apart from the inbox metadata
+//	inbox#<id>#req#<seq>      one request, seq is a big endian uint64 so keys sort by arrival
+//	inbox#<id>#file#<seq><n>  content of the n-th file of a request, n is a big endian uint32
+//	inbox#<id>#dead#<uuid>    callback dead letter, uuid is a v7 so keys sort by creation
+const requestInfix = "#req#"
+const fileInfix = "#file#"
+const sequenceSuffix = "#seq"
+const scenarioSuffix = "#scenario"
+const deadLetterInfix = "#dead#"
+
+// Conflicting transactions are retried up to maxUpdateAttempts times, waiting a bit longer each time
+const maxUpdateAttempts = 10
+const updateRetryDelay = 2 * time.Millisecond
+
+// sequenceBandwidth is the number of request sequences leased at once. Leasing one at a time keeps the
+// sequences without gaps when the process stops without releasing them.
+const sequenceBandwidth = 1
+
 type InboxBadger struct {
 	db *badger.DB
+	// sequences allocate the request IDs of each inbox, there must be only one per inbox
+	mu        sync.Mutex
+	sequences map[uuid.UUID]*badger.Sequence
 }
 
 func NewInboxDB(path string, memoryOnly bool) (*InboxBadger, error) {
@@ -24,15 +59,99 @@ func NewInboxDB(path string, memoryOnly bool) (*InboxBadger, error) {
 		return nil, fmt.Errorf("error opening badger DB: %w", err)
 	}
 
-	return &InboxBadger{
-		db: db,
-	}, nil
+	ib := &InboxBadger{
+		db:        db,
+		sequences: map[uuid.UUID]*badger.Sequence{},
+	}
+	err = ib.migrateInboxRequests()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating badger DB: %w", err)
+	}
+	return ib, nil
+}
+
+// migrateInboxRequests moves the requests of inboxes stored with them, as they were before requests had their
+// own keys, to request keys. Their IDs were their position, so it is also their sequence.
+// Each inbox is rewritten without requests after its requests are stored, so an interrupted migration is resumed.
+func (ib *InboxBadger) migrateInboxRequests() error {
+	inboxes := []model.Inbox{}
+	err := ib.db.View(func(txn *badger.Txn) error {
+		return ib.forEachInbox(txn, func(item *badger.Item) error {
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			inbox, err := decode[model.Inbox](data)
+			if err != nil {
+				return err
+			}
+			if len(inbox.Requests) > 0 {
+				inboxes = append(inboxes, inbox)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, inbox := range inboxes {
+		wb := ib.db.NewWriteBatch()
+		policy := retention.EffectivePolicy(inbox.Retention)
+		for i, req := range inbox.Requests {
+			req.ID = i
+			data, err := encode(req)
+			if err != nil {
+				wb.Cancel()
+				return err
+			}
+			e := badger.NewEntry(ib.getRequestKey(inbox.ID, uint64(i)), data)
+			if expiresAt := retention.ExpiresAt(policy, req.Timestamp); expiresAt > 0 {
+				e = e.WithTTL(time.Until(time.Unix(expiresAt, 0)))
+			}
+			if err := wb.SetEntry(e); err != nil {
+				wb.Cancel()
+				return err
+			}
+		}
+		next := binary.BigEndian.AppendUint64(nil, uint64(len(inbox.Requests)))
+		if err := wb.Set(ib.getSequenceKey(inbox.ID), next); err != nil {
+			wb.Cancel()
+			return err
+		}
+		if err := wb.Flush(); err != nil {
+			return err
+		}
+
+		inbox.Requests = []model.Request{}
+		data, err := encode(inbox)
+		if err != nil {
+			return err
+		}
+		err = ib.db.Update(func(txn *badger.Txn) error {
+			return txn.Set(ib.getInboxKey(inbox.ID), data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (ib *InboxBadger) Close(ctx context.Context) error {
 	if ib == nil {
 		return nil
 	}
+	ib.mu.Lock()
+	for ID, seq := range ib.sequences {
+		if err := seq.Release(); err != nil {
+			ib.mu.Unlock()
+			return fmt.Errorf("error releasing request sequence of inbox %v: %w", ID, err)
+		}
+	}
+	ib.sequences = map[uuid.UUID]*badger.Sequence{}
+	ib.mu.Unlock()
 	err := ib.db.Close()
 	if err != nil {
 		return fmt.Errorf("error closing badger DB: %w", err)
@@ -44,6 +163,50 @@ func (ib *InboxBadger) getInboxKey(id uuid.UUID) []byte {
 	return append([]byte(inboxPrefix), id[:]...)
 }
 
+func (ib *InboxBadger) isInboxKey(key []byte) bool {
+	return len(key) == len(inboxPrefix)+len(uuid.UUID{})
+}
+
+func (ib *InboxBadger) getSequenceKey(id uuid.UUID) []byte {
+	return append(ib.getInboxKey(id), sequenceSuffix...)
+}
+
+func (ib *InboxBadger) getScenarioKey(id uuid.UUID) []byte {
+	return append(ib.getInboxKey(id), scenarioSuffix...)
+}
+
+func (ib *InboxBadger) getRequestPrefix(id uuid.UUID) []byte {
+	return append(ib.getInboxKey(id), requestInfix...)
+}
+
+func (ib *InboxBadger) getRequestKey(id uuid.UUID, seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(ib.getRequestPrefix(id), seq)
+}
+
+func (ib *InboxBadger) requestKeySequence(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(key)-8:])
+}
+
+func (ib *InboxBadger) getFilePrefix(id uuid.UUID) []byte {
+	return append(ib.getInboxKey(id), fileInfix...)
+}
+
+func (ib *InboxBadger) getFileKey(id uuid.UUID, seq uint64, index int) []byte {
+	return binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint64(ib.getFilePrefix(id), seq), uint32(index))
+}
+
+func (ib *InboxBadger) fileKeySequence(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(key)-12 : len(key)-4])
+}
+
+func (ib *InboxBadger) getDeadLetterPrefix(id uuid.UUID) []byte {
+	return append(ib.getInboxKey(id), deadLetterInfix...)
+}
+
+func (ib *InboxBadger) getDeadLetterKey(id uuid.UUID, deadLetterID uuid.UUID) []byte {
+	return append(ib.getDeadLetterPrefix(id), deadLetterID[:]...)
+}
+
 func (ib *InboxBadger) getUserKey(id uuid.UUID) []byte {
 	return append([]byte(userPrefix), id[:]...)
 }
@@ -52,10 +215,30 @@ func (ib *InboxBadger) getAPIKeyKey(id uuid.UUID) []byte {
 	return append([]byte(apiKeyPrefix), id[:]...)
 }
 
+// update runs fn in a read-write transaction and retries it while it conflicts with concurrent writes,
+// up to maxUpdateAttempts times.
+func (ib *InboxBadger) update(ctx context.Context, fn func(txn *badger.Txn) error) error {
+	for attempt := 1; ; attempt++ {
+		err := ib.db.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) || attempt == maxUpdateAttempts {
+			return err
+		}
+		// The jitter keeps conflicting writers from retrying at the same time again
+		delay := time.Duration(attempt)*updateRetryDelay + rand.N(updateRetryDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
 func (ib *InboxBadger) CreateInbox(ctx context.Context, inbox model.Inbox) (model.Inbox, error) {
 	inbox.ID = uuid.New()
 	inbox.Name = inbox.ID.String()
 	inbox.Timestamp = time.Now().UnixMilli()
+	inbox.Requests = []model.Request{}
+	inbox.Scenario = model.Scenario{}
 	data, err := encode(inbox)
 	if err != nil {
 		return model.Inbox{}, err
@@ -71,54 +254,555 @@ func (ib *InboxBadger) CreateInbox(ctx context.Context, inbox model.Inbox) (mode
 }
 
 func (ib *InboxBadger) UpdateInbox(ctx context.Context, inbox model.Inbox) (model.Inbox, error) {
+	inbox.Requests = []model.Request{}
+	inbox.Scenario = model.Scenario{}
 	data, err := encode(inbox)
 	if err != nil {
 		return inbox, err
 	}
 
-	err = ib.db.Update(func(txn *badger.Txn) error {
+	err = ib.update(ctx, func(txn *badger.Txn) error {
 		return txn.Set(ib.getInboxKey(inbox.ID), data)
 	})
 	return inbox, err
 }
 
-func (ib *InboxBadger) AddRequestToInbox(ctx context.Context, ID uuid.UUID, req model.Request) error {
-	inbox, err := ib.GetInboxWithRequests(ctx, ID)
+// AddRequestToInbox stores the request without reading keys written by other requests, so concurrent requests
+// do not conflict. The requests leaving the retention window are removed afterwards.
+func (ib *InboxBadger) AddRequestToInbox(ctx context.Context, ID uuid.UUID, req model.Request, files ...model.RequestFile) (int, error) {
+	inbox, err := ib.GetInbox(ctx, ID)
+	if err != nil {
+		return 0, err
+	}
+	sequence, err := ib.sequence(ID)
+	if err != nil {
+		return 0, err
+	}
+	seq, err := sequence.Next()
+	if err != nil {
+		return 0, fmt.Errorf("error allocating request ID of inbox %v: %w", ID, err)
+	}
+	req.ID = int(seq)
+	data, err := encode(req)
+	if err != nil {
+		return 0, err
+	}
+
+	policy := retention.EffectivePolicy(inbox.Retention)
+	expiresAt := retention.ExpiresAt(policy, req.Timestamp)
+	err = ib.db.Update(func(txn *badger.Txn) error {
+		err := ib.setEntry(txn, ib.getRequestKey(ID, seq), data, expiresAt)
+		if err != nil {
+			return err
+		}
+		for i, file := range files {
+			fileData, err := encode(file)
+			if err != nil {
+				return err
+			}
+			err = ib.setEntry(txn, ib.getFileKey(ID, seq, i), fileData, expiresAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Expired requests are removed by the entry TTL, only the count needs to be enforced
+	if policy.MaxRequests > 0 && seq >= uint64(policy.MaxRequests) {
+		if err := ib.deleteRequestsUpTo(ID, seq-uint64(policy.MaxRequests)); err != nil {
+			return 0, fmt.Errorf("error removing requests of inbox %v beyond its retention: %w", ID, err)
+		}
+	}
+	return req.ID, nil
+}
+
+// setEntry sets the value of key, expiresAt is the unix time in seconds when it is deleted, 0 keeps it forever.
+func (ib *InboxBadger) setEntry(txn *badger.Txn, key []byte, data []byte, expiresAt int64) error {
+	e := badger.NewEntry(key, data)
+	if expiresAt > 0 {
+		e = e.WithTTL(time.Until(time.Unix(expiresAt, 0)))
+	}
+	return txn.SetEntry(e)
+}
+
+// sequence returns the request sequence of the inbox. Badger sequences lease IDs in their own transactions,
+// so they are allocated without conflicts.
+func (ib *InboxBadger) sequence(ID uuid.UUID) (*badger.Sequence, error) {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+	if seq, ok := ib.sequences[ID]; ok {
+		return seq, nil
+	}
+	seq, err := ib.db.GetSequence(ib.getSequenceKey(ID), sequenceBandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("error getting request sequence of inbox %v: %w", ID, err)
+	}
+	ib.sequences[ID] = seq
+	return seq, nil
+}
+
+// releaseSequence stops allocating request IDs of the inbox, before its keys are deleted.
+func (ib *InboxBadger) releaseSequence(ID uuid.UUID) error {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+	seq, ok := ib.sequences[ID]
+	if !ok {
+		return nil
+	}
+	delete(ib.sequences, ID)
+	return seq.Release()
+}
+
+func (ib *InboxBadger) deleteRequestsUpTo(ID uuid.UUID, maxSeq uint64) error {
+	var keys [][]byte
+	err := ib.db.View(func(txn *badger.Txn) error {
+		keys = ib.listKeysUpTo(txn, ib.getRequestPrefix(ID), maxSeq, ib.requestKeySequence)
+		keys = append(keys, ib.listKeysUpTo(txn, ib.getFilePrefix(ID), maxSeq, ib.fileKeySequence)...)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	inbox.Requests = append(inbox.Requests, req)
-	_, err = ib.UpdateInbox(ctx, inbox)
-	return err
+	return ib.deleteKeys(keys)
 }
 
-func (ib *InboxBadger) GetInboxWithRequests(ctx context.Context, ID uuid.UUID) (model.Inbox, error) {
+// listKeysUpTo lists the keys with prefix until the one with a sequence bigger than maxSeq.
+func (ib *InboxBadger) listKeysUpTo(txn *badger.Txn, prefix []byte, maxSeq uint64, sequence func([]byte) uint64) [][]byte {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = prefix
+	it := txn.NewIterator(opts)
+	defer it.Close()
+	keys := [][]byte{}
+	for it.Rewind(); it.Valid(); it.Next() {
+		key := it.Item().KeyCopy(nil)
+		if sequence(key) > maxSeq {
+			break
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (ib *InboxBadger) PruneInboxRequests(ctx context.Context, ID uuid.UUID, policy model.RetentionPolicy) (int, error) {
+	cutoff := retention.Cutoff(policy, time.Now())
+	toDelete := [][]byte{}
+	prunedRequests := 0
+	err := ib.db.View(func(txn *badger.Txn) error {
+		if _, err := ib.getInbox(txn, ID); err != nil {
+			return err
+		}
+		// Newest first, so everything after MaxRequests keys is removed
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = true
+		opts.Prefix = ib.getRequestPrefix(ID)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		kept := 0
+		prunedSeqs := map[uint64]bool{}
+		for it.Seek(ib.getRequestKey(ID, math.MaxUint64)); it.Valid(); it.Next() {
+			item := it.Item()
+			if policy.MaxRequests <= 0 || kept < policy.MaxRequests {
+				req, err := ib.decodeRequest(item)
+				if err != nil {
+					return err
+				}
+				if req.Timestamp >= cutoff {
+					kept++
+					continue
+				}
+			}
+			toDelete = append(toDelete, item.KeyCopy(nil))
+			prunedSeqs[ib.requestKeySequence(item.Key())] = true
+		}
+		prunedRequests = len(toDelete)
+		if len(prunedSeqs) == 0 {
+			return nil
+		}
+
+		fileOpts := badger.DefaultIteratorOptions
+		fileOpts.PrefetchValues = false
+		fileOpts.Prefix = ib.getFilePrefix(ID)
+		fileIt := txn.NewIterator(fileOpts)
+		defer fileIt.Close()
+		for fileIt.Rewind(); fileIt.Valid(); fileIt.Next() {
+			if prunedSeqs[ib.fileKeySequence(fileIt.Item().Key())] {
+				toDelete = append(toDelete, fileIt.Item().KeyCopy(nil))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error pruning requests of inbox %v: %w", ID, err)
+	}
+	err = ib.deleteKeys(toDelete)
+	if err != nil {
+		return 0, fmt.Errorf("error pruning requests of inbox %v: %w", ID, err)
+	}
+	return prunedRequests, nil
+}
+
+func (ib *InboxBadger) getInbox(txn *badger.Txn, ID uuid.UUID) (model.Inbox, error) {
+	item, err := txn.Get(ib.getInboxKey(ID))
+	if err != nil {
+		return model.Inbox{}, err
+	}
+	valCopy, err := item.ValueCopy(nil)
+	if err != nil {
+		return model.Inbox{}, err
+	}
+	inbox, err := decode[model.Inbox](valCopy)
+	if err != nil {
+		return model.Inbox{}, err
+	}
+	inbox.Requests = []model.Request{}
+	inbox.Scenario, err = ib.getScenario(txn, ID)
+	if err != nil {
+		return model.Inbox{}, err
+	}
+	return inbox, nil
+}
+
+// getScenario returns the scenario of the inbox, an empty one when it has not been updated yet.
+func (ib *InboxBadger) getScenario(txn *badger.Txn, ID uuid.UUID) (model.Scenario, error) {
+	item, err := txn.Get(ib.getScenarioKey(ID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return model.Scenario{}, nil
+	}
+	if err != nil {
+		return model.Scenario{}, err
+	}
+	valCopy, err := item.ValueCopy(nil)
+	if err != nil {
+		return model.Scenario{}, err
+	}
+	return decode[model.Scenario](valCopy)
+}
+
+func (ib *InboxBadger) UpdateInboxScenario(ctx context.Context, ID uuid.UUID, update func(model.Scenario) model.Scenario) (model.Scenario, error) {
+	var scenario model.Scenario
+	err := ib.update(ctx, func(txn *badger.Txn) error {
+		_, err := txn.Get(ib.getInboxKey(ID))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return dberrors.ErrItemNotFound
+		}
+		if err != nil {
+			return err
+		}
+		current, err := ib.getScenario(txn, ID)
+		if err != nil {
+			return err
+		}
+		scenario = update(current)
+		data, err := encode(scenario)
+		if err != nil {
+			return err
+		}
+		return txn.Set(ib.getScenarioKey(ID), data)
+	})
+	if err != nil {
+		return model.Scenario{}, err
+	}
+	return scenario, nil
+}
+
+func (ib *InboxBadger) PutDeadLetter(ctx context.Context, ID uuid.UUID, deadLetter model.DeadLetter) (model.DeadLetter, error) {
+	if deadLetter.ID == uuid.Nil {
+		deadLetter.ID = uuid.Must(uuid.NewV7())
+	}
+	if deadLetter.Timestamp == 0 {
+		deadLetter.Timestamp = time.Now().UnixMilli()
+	}
+	data, err := encode(deadLetter)
+	if err != nil {
+		return model.DeadLetter{}, err
+	}
+	err = ib.update(ctx, func(txn *badger.Txn) error {
+		_, err := txn.Get(ib.getInboxKey(ID))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return dberrors.ErrItemNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(ib.getDeadLetterKey(ID, deadLetter.ID), data); err != nil {
+			return err
+		}
+		return ib.pruneDeadLetters(txn, ID, config.GetInt(config.CallbackMaxDeadLetters))
+	})
+	if err != nil {
+		return model.DeadLetter{}, fmt.Errorf("error storing dead letter of inbox %v: %w", ID, err)
+	}
+	return deadLetter, nil
+}
+
+// pruneDeadLetters deletes the oldest dead letters of the inbox beyond limit, 0 means unlimited.
+func (ib *InboxBadger) pruneDeadLetters(txn *badger.Txn, ID uuid.UUID, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = ib.getDeadLetterPrefix(ID)
+	opts.PrefetchValues = false
+	opts.Reverse = true
+	it := txn.NewIterator(opts)
+	defer it.Close()
+	kept := 0
+	stale := [][]byte{}
+	for it.Seek(ib.getDeadLetterKey(ID, uuid.Max)); it.Valid(); it.Next() {
+		kept++
+		if kept > limit {
+			stale = append(stale, it.Item().KeyCopy(nil))
+		}
+	}
+	for _, key := range stale {
+		if err := txn.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ib *InboxBadger) ListDeadLetters(ctx context.Context, ID uuid.UUID) ([]model.DeadLetter, error) {
+	deadLetters := []model.DeadLetter{}
+	err := ib.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = ib.getDeadLetterPrefix(ID)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			valCopy, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			deadLetter, err := decode[model.DeadLetter](valCopy)
+			if err != nil {
+				return err
+			}
+			deadLetters = append(deadLetters, deadLetter)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+func (ib *InboxBadger) GetDeadLetter(ctx context.Context, ID uuid.UUID, deadLetterID uuid.UUID) (model.DeadLetter, error) {
 	var valCopy []byte
 	err := ib.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(ib.getInboxKey(ID))
+		item, err := txn.Get(ib.getDeadLetterKey(ID, deadLetterID))
 		if err != nil {
 			return err
 		}
-		err = item.Value(func(val []byte) error {
-			valCopy = append([]byte{}, val...)
-			return nil
-		})
+		valCopy, err = item.ValueCopy(nil)
 		return err
 	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return model.DeadLetter{}, dberrors.ErrItemNotFound
+	}
+	if err != nil {
+		return model.DeadLetter{}, err
+	}
+	return decode[model.DeadLetter](valCopy)
+}
+
+func (ib *InboxBadger) DeleteDeadLetter(ctx context.Context, ID uuid.UUID, deadLetterID uuid.UUID) error {
+	return ib.update(ctx, func(txn *badger.Txn) error {
+		key := ib.getDeadLetterKey(ID, deadLetterID)
+		_, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return dberrors.ErrItemNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return txn.Delete(key)
+	})
+}
+
+func (ib *InboxBadger) decodeRequest(item *badger.Item) (model.Request, error) {
+	valCopy, err := item.ValueCopy(nil)
+	if err != nil {
+		return model.Request{}, err
+	}
+	return decode[model.Request](valCopy)
+}
+
+func (ib *InboxBadger) GetInboxWithRequests(ctx context.Context, ID uuid.UUID) (model.Inbox, error) {
+	var inbox model.Inbox
+	err := ib.db.View(func(txn *badger.Txn) error {
+		var err error
+		inbox, err = ib.getInbox(txn, ID)
+		if err != nil {
+			return err
+		}
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = ib.getRequestPrefix(ID)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			req, err := ib.decodeRequest(it.Item())
+			if err != nil {
+				return err
+			}
+			inbox.Requests = append(inbox.Requests, req)
+		}
+		return nil
+	})
 	if err != nil {
 		return model.Inbox{}, err
 	}
-	return decode[model.Inbox](valCopy)
+	return inbox, nil
+}
+
+func (ib *InboxBadger) ListInboxRequests(ctx context.Context, ID uuid.UUID, options ...option.ListRequestsOption) (model.Page[model.Request], error) {
+	opts := option.NewListRequestsOptions(options...)
+	start := ib.getRequestKey(ID, 0)
+	if opts.IsDescending() {
+		start = ib.getRequestKey(ID, math.MaxUint64)
+	}
+	if opts.Cursor != "" {
+		position, err := option.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return model.Page[model.Request]{}, err
+		}
+		after, err := strconv.ParseUint(position, 10, 64)
+		if err != nil {
+			return model.Page[model.Request]{}, fmt.Errorf("%w: %w", dberrors.ErrInvalidCursor, err)
+		}
+		if opts.IsDescending() {
+			if after == 0 {
+				return model.NewPage([]model.Request{}, ""), nil
+			}
+			start = ib.getRequestKey(ID, after-1)
+		} else {
+			start = ib.getRequestKey(ID, after+1)
+		}
+	}
+
+	results := []model.Request{}
+	nextCursor := ""
+	err := ib.db.View(func(txn *badger.Txn) error {
+		if _, err := ib.getInbox(txn, ID); err != nil {
+			return err
+		}
+		itOpts := badger.DefaultIteratorOptions
+		itOpts.Reverse = opts.IsDescending()
+		itOpts.Prefix = ib.getRequestPrefix(ID)
+		it := txn.NewIterator(itOpts)
+		defer it.Close()
+		last := uint64(0)
+		for it.Seek(start); it.Valid(); it.Next() {
+			item := it.Item()
+			req, err := ib.decodeRequest(item)
+			if err != nil {
+				return err
+			}
+			if !opts.Match(req) {
+				continue
+			}
+			if len(results) == opts.Limit {
+				nextCursor = option.EncodeCursor(strconv.FormatUint(last, 10))
+				break
+			}
+			results = append(results, req)
+			last = ib.requestKeySequence(item.Key())
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Page[model.Request]{}, err
+	}
+	return model.NewPage(results, nextCursor), nil
+}
+
+func (ib *InboxBadger) GetInboxRequest(ctx context.Context, ID uuid.UUID, requestID int) (model.Request, error) {
+	if requestID < 0 {
+		return model.Request{}, dberrors.ErrItemNotFound
+	}
+	var req model.Request
+	err := ib.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(ib.getRequestKey(ID, uint64(requestID)))
+		if err != nil {
+			return err
+		}
+		req, err = ib.decodeRequest(item)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return model.Request{}, dberrors.ErrItemNotFound
+	}
+	return req, err
+}
+
+func (ib *InboxBadger) UpdateInboxRequest(ctx context.Context, ID uuid.UUID, req model.Request) error {
+	if req.ID < 0 {
+		return dberrors.ErrItemNotFound
+	}
+	data, err := encode(req)
+	if err != nil {
+		return err
+	}
+	return ib.update(ctx, func(txn *badger.Txn) error {
+		key := ib.getRequestKey(ID, uint64(req.ID))
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return dberrors.ErrItemNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return ib.setEntry(txn, key, data, int64(item.ExpiresAt()))
+	})
+}
+
+func (ib *InboxBadger) GetRequestFile(ctx context.Context, ID uuid.UUID, requestID int, index int) (model.RequestFile, error) {
+	if requestID < 0 || index < 0 {
+		return model.RequestFile{}, dberrors.ErrItemNotFound
+	}
+	var valCopy []byte
+	err := ib.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(ib.getFileKey(ID, uint64(requestID), index))
+		if err != nil {
+			return err
+		}
+		valCopy, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return model.RequestFile{}, dberrors.ErrItemNotFound
+	}
+	if err != nil {
+		return model.RequestFile{}, err
+	}
+	return decode[model.RequestFile](valCopy)
 }
 
 func (ib *InboxBadger) GetInbox(ctx context.Context, ID uuid.UUID) (model.Inbox, error) {
-	return ib.GetInboxWithRequests(ctx, ID)
+	var inbox model.Inbox
+	err := ib.db.View(func(txn *badger.Txn) error {
+		var err error
+		inbox, err = ib.getInbox(txn, ID)
+		return err
+	})
+	return inbox, err
 }
 
 func (ib *InboxBadger) DeleteInbox(ctx context.Context, ID uuid.UUID) error {
-	err := ib.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete(ib.getInboxKey(ID))
-	})
+	if err := ib.releaseSequence(ID); err != nil {
+		return fmt.Errorf("error deleting %v: %w", ID, err)
+	}
+	keys, err := ib.listKeys(ib.getInboxKey(ID))
+	if err != nil {
+		return fmt.Errorf("error deleting %v: %w", ID, err)
+	}
+	err = ib.deleteKeys(keys)
 	if err != nil {
 		return fmt.Errorf("error deleting %v: %w", ID, err)
 	}
@@ -126,18 +810,52 @@ func (ib *InboxBadger) DeleteInbox(ctx context.Context, ID uuid.UUID) error {
 }
 
 func (ib *InboxBadger) DeleteInboxRequests(ctx context.Context, ID uuid.UUID) error {
-	inbox, err := ib.GetInboxWithRequests(ctx, ID)
-	if err != nil {
+	if _, err := ib.GetInbox(ctx, ID); err != nil {
 		return err
 	}
-	inbox.Requests = []model.Request{}
-	_, err = ib.UpdateInbox(ctx, inbox)
+	keys, err := ib.listKeys(ib.getRequestPrefix(ID))
+	if err != nil {
+		return fmt.Errorf("error deleting request of inbox %v: %w", ID, err)
+	}
+	fileKeys, err := ib.listKeys(ib.getFilePrefix(ID))
+	if err != nil {
+		return fmt.Errorf("error deleting request of inbox %v: %w", ID, err)
+	}
+	err = ib.deleteKeys(append(keys, fileKeys...))
 	if err != nil {
 		return fmt.Errorf("error deleting request of inbox %v: %w", ID, err)
 	}
 	return nil
 }
 
+func (ib *InboxBadger) listKeys(prefix []byte) ([][]byte, error) {
+	keys := [][]byte{}
+	err := ib.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// deleteKeys uses a write batch because an inbox can have more requests than fit in a transaction.
+func (ib *InboxBadger) deleteKeys(keys [][]byte) error {
+	wb := ib.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, key := range keys {
+		if err := wb.Delete(key); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
 func (ib *InboxBadger) ListInbox(ctx context.Context) ([]model.Inbox, error) {
 	return ib.listInbox(ctx, func(i model.Inbox) bool {
 		return true
@@ -153,31 +871,55 @@ func (ib *InboxBadger) ListInboxByUser(ctx context.Context, userID uuid.UUID) ([
 func (ib *InboxBadger) listInbox(ctx context.Context, filter func(model.Inbox) bool) ([]model.Inbox, error) {
 	inboxList := []model.Inbox{}
 	err := ib.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
-		prefix := []byte(inboxPrefix)
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-			err := item.Value(func(val []byte) error {
-				valCopy := append([]byte{}, val...)
-				inbox, err := decode[model.Inbox](valCopy)
-				if err != nil {
-					return err
-				}
-				if filter(inbox) {
-					inboxList = append(inboxList, inbox)
-				}
-				return nil
-			})
+		return ib.forEachInbox(txn, func(item *badger.Item) error {
+			valCopy, err := item.ValueCopy(nil)
 			if err != nil {
 				return err
 			}
-		}
-		return nil
+			inbox, err := decode[model.Inbox](valCopy)
+			if err != nil {
+				return err
+			}
+			inbox.Requests = []model.Request{}
+			inbox.Scenario, err = ib.getScenario(txn, inbox.ID)
+			if err != nil {
+				return err
+			}
+			if filter(inbox) {
+				inboxList = append(inboxList, inbox)
+			}
+			return nil
+		})
 	})
 	return inboxList, err
 }
 
+// forEachInbox calls fn with the item of every inbox. The keys stored under each inbox are skipped with a seek
+// instead of being iterated.
+func (ib *InboxBadger) forEachInbox(txn *badger.Txn, fn func(*badger.Item) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = []byte(inboxPrefix)
+	it := txn.NewIterator(opts)
+	defer it.Close()
+	inboxKeyLen := len(inboxPrefix) + len(uuid.UUID{})
+	for it.Rewind(); it.Valid(); {
+		key := it.Item().KeyCopy(nil)
+		if ib.isInboxKey(key) {
+			if err := fn(it.Item()); err != nil {
+				return err
+			}
+		}
+		// Sub-keys of the inbox continue its key with a '#', the next inbox key sorts after all of them
+		if len(key) < inboxKeyLen {
+			it.Next()
+			continue
+		}
+		it.Seek(append(key[:inboxKeyLen:inboxKeyLen], '#'+1))
+	}
+	return nil
+}
+
 func (ib *InboxBadger) UpsertUser(ctx context.Context, user model.User) (bool, error) {
 	data, err := encode(user)
 	if err != nil {
@@ -2,7 +2,13 @@ package embedded
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
 
 func TestNewInboxDB(t *testing.T) {
@@ -41,3 +47,128 @@ func TestNewInboxDB(t *testing.T) {
 		})
 	}
 }
+
+func TestNewInboxDBMigratesInboxRequests(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	// Before requests had their own keys they were stored in the inbox
+	inbox := model.GenerateInbox()
+	inbox.Requests = []model.Request{model.GenerateRequest(0), model.GenerateRequest(1)}
+	db, err := badger.Open(badger.DefaultOptions(path).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("Expected no error opening badger but got %v", err)
+	}
+	data, err := encode(inbox)
+	if err != nil {
+		t.Fatalf("Expected no error encoding inbox but got %v", err)
+	}
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Set(append([]byte(inboxPrefix), inbox.ID[:]...), data)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error storing inbox but got %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Expected no error closing badger but got %v", err)
+	}
+
+	inboxDB, err := NewInboxDB(path, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer inboxDB.Close(ctx)
+	got, err := inboxDB.GetInboxWithRequests(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("GetInboxWithRequests() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(inbox.Requests, got.Requests); diff != "" {
+		t.Errorf("GetInboxWithRequests() got unexpected requests. Diff: %s", diff)
+	}
+	id, err := inboxDB.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(0))
+	if err != nil {
+		t.Fatalf("AddRequestToInbox() unexpected error: %v", err)
+	}
+	if id != 2 {
+		t.Errorf("AddRequestToInbox() = %d, want the ID after the migrated requests, 2", id)
+	}
+	stored, err := inboxDB.GetInbox(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("GetInbox() unexpected error: %v", err)
+	}
+	if len(stored.Requests) != 0 {
+		t.Errorf("GetInbox() got %d requests, want the inbox stored without them", len(stored.Requests))
+	}
+}
+
+func TestUpdateStopsRetryingConflicts(t *testing.T) {
+	inboxDB, err := NewInboxDB("", true)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer inboxDB.Close(context.Background())
+	attempts := 0
+	err = inboxDB.update(context.Background(), func(txn *badger.Txn) error {
+		attempts++
+		return badger.ErrConflict
+	})
+	if !errors.Is(err, badger.ErrConflict) {
+		t.Errorf("update() error = %v, want %v", err, badger.ErrConflict)
+	}
+	if attempts != maxUpdateAttempts {
+		t.Errorf("update() ran %d attempts, want %d", attempts, maxUpdateAttempts)
+	}
+}
+
+func TestAddRequestToInboxConcurrently(t *testing.T) {
+	ctx := context.Background()
+	inboxDB, err := NewInboxDB("", true)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer inboxDB.Close(ctx)
+	inbox, err := inboxDB.CreateInbox(ctx, model.GenerateInbox())
+	if err != nil {
+		t.Fatalf("CreateInbox() unexpected error: %v", err)
+	}
+
+	const posts = 100
+	var wg sync.WaitGroup
+	ids := make(chan int, posts)
+	errs := make(chan error, posts)
+	for i := 0; i < posts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := inboxDB.AddRequestToInbox(ctx, inbox.ID, model.GenerateRequest(i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			ids <- id
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("AddRequestToInbox() unexpected error: %v", err)
+	}
+	seen := map[int]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("AddRequestToInbox() returned the ID %d twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != posts {
+		t.Errorf("AddRequestToInbox() returned %d IDs, want %d", len(seen), posts)
+	}
+	got, err := inboxDB.GetInboxWithRequests(ctx, inbox.ID)
+	if err != nil {
+		t.Fatalf("GetInboxWithRequests() unexpected error: %v", err)
+	}
+	if len(got.Requests) != posts {
+		t.Errorf("GetInboxWithRequests() got %d requests, want %d", len(got.Requests), posts)
+	}
+}
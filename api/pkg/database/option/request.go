@@ -0,0 +1,151 @@
+package option
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/textproto"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+type Order string
+
+const (
+	OrderAsc  Order = "asc"
+	OrderDesc Order = "desc"
+
+	DefaultRequestsLimit = 50
+	MaxRequestsLimit     = 500
+)
+
+type ListRequestsOptions struct {
+	Limit  int
+	Cursor string
+	Order  Order
+	// Filters, empty values are ignored
+	Method       string
+	PathPrefix   string
+	From         int64 // unix milliseconds, inclusive
+	To           int64 // unix milliseconds, inclusive
+	Headers      []string
+	BodyContains string
+}
+
+type ListRequestsOption func(*ListRequestsOptions)
+
+func NewListRequestsOptions(options ...ListRequestsOption) ListRequestsOptions {
+	opts := ListRequestsOptions{
+		Limit: DefaultRequestsLimit,
+		Order: OrderAsc,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+func WithLimit(limit int) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.Limit = limit
+	}
+}
+
+func WithCursor(cursor string) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.Cursor = cursor
+	}
+}
+
+func WithOrder(order Order) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.Order = order
+	}
+}
+
+func WithMethod(method string) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.Method = method
+	}
+}
+
+func WithPathPrefix(prefix string) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.PathPrefix = prefix
+	}
+}
+
+func WithTimeRange(from, to int64) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.From = from
+		opts.To = to
+	}
+}
+
+func WithHeader(name string) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.Headers = append(opts.Headers, name)
+	}
+}
+
+func WithBodyContains(substr string) ListRequestsOption {
+	return func(opts *ListRequestsOptions) {
+		opts.BodyContains = substr
+	}
+}
+
+func (opts ListRequestsOptions) IsDescending() bool {
+	return opts.Order == OrderDesc
+}
+
+// Match reports whether the request passes every filter.
+// The time range is included so backends that can not use it as a key condition get the same results.
+func (opts ListRequestsOptions) Match(req model.Request) bool {
+	if opts.Method != "" && !strings.EqualFold(opts.Method, req.Method) {
+		return false
+	}
+	if opts.PathPrefix != "" && !strings.HasPrefix(req.InboxPath(), opts.PathPrefix) {
+		return false
+	}
+	if opts.From != 0 && req.Timestamp < opts.From {
+		return false
+	}
+	if opts.To != 0 && req.Timestamp > opts.To {
+		return false
+	}
+	for _, h := range opts.Headers {
+		if len(req.Headers[textproto.CanonicalMIMEHeaderKey(h)]) == 0 {
+			return false
+		}
+	}
+	if opts.BodyContains != "" && !bodyContains(req, opts.BodyContains) {
+		return false
+	}
+	return true
+}
+
+// bodyContains looks for substr in the received and the decompressed body bytes, as stored bodies can be base64.
+func bodyContains(req model.Request, substr string) bool {
+	for _, body := range []func() ([]byte, error){req.RawBody, req.DecodedBodyBytes} {
+		b, err := body()
+		if err == nil && bytes.Contains(b, []byte(substr)) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeCursor hides the backend position so clients treat cursors as opaque values.
+func EncodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func DecodeCursor(cursor string) (string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", dberrors.ErrInvalidCursor, err)
+	}
+	return string(position), nil
+}
@@ -0,0 +1,53 @@
+package option_test
+
+import (
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+func TestMatchBodyContains(t *testing.T) {
+	binaryBody, binaryEncoding := model.EncodeBody([]byte("\xff\xfe{\"status\":\"paid\"}"))
+	decodedBody, decodedEncoding := model.EncodeBody([]byte("\xff{\"status\":\"paid\"}"))
+	testCases := []struct {
+		desc   string
+		req    model.Request
+		substr string
+		want   bool
+	}{
+		{
+			desc:   "Text body",
+			req:    model.Request{Body: `{"status":"paid"}`, BodyEncoding: model.BodyEncodingUTF8},
+			substr: `"paid"`,
+			want:   true,
+		},
+		{
+			desc:   "Binary body",
+			req:    model.Request{Body: binaryBody, BodyEncoding: binaryEncoding},
+			substr: `"paid"`,
+			want:   true,
+		},
+		{
+			desc: "Binary decompressed body",
+			req: model.Request{Body: "H4sIAAAAAAAA", BodyEncoding: model.BodyEncodingBase64,
+				DecodedBody: decodedBody, DecodedBodyEncoding: decodedEncoding},
+			substr: `"paid"`,
+			want:   true,
+		},
+		{
+			desc:   "Base64 text is not matched",
+			req:    model.Request{Body: binaryBody, BodyEncoding: binaryEncoding},
+			substr: binaryBody[4:12],
+			want:   false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := option.NewListRequestsOptions(option.WithBodyContains(tc.substr))
+			if got := opts.Match(tc.req); got != tc.want {
+				t.Errorf("Match(%+v) with body %q = %v, want %v", tc.req, tc.substr, got, tc.want)
+			}
+		})
+	}
+}
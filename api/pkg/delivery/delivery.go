@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/callback"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+var ErrQueueFull = errors.New("callback delivery queue is full")
+var ErrQueueClosed = errors.New("callback delivery queue is closed")
+
+type Store interface {
+	UpdateInboxRequest(ctx context.Context, ID uuid.UUID, req model.Request) error
+	PutDeadLetter(ctx context.Context, ID uuid.UUID, deadLetter model.DeadLetter) (model.DeadLetter, error)
+}
+
+// Publisher announces the requests updated with their callback responses to the live streams.
+type Publisher interface {
+	Publish(inboxID uuid.UUID, req model.Request)
+}
+
+// Job is a stored request whose callbacks have not been sent yet.
+type Job struct {
+	Inbox   model.Inbox
+	Request model.Request
+}
+
+// Queue delivers the callbacks of the enqueued jobs after the request has been answered.
+type Queue interface {
+	Enqueue(context.Context, Job) error
+	// Close stops accepting jobs and waits for the pending ones until the context is done
+	Close(context.Context) error
+}
+
+// NewQueue returns the queue for the configured delivery mode, nil when callbacks are delivered synchronously.
+// A lambda is frozen once it answers, so it delivers each job before Enqueue returns.
+func NewQueue(store Store, publisher Publisher) Queue {
+	if config.GetString(config.CallbackDeliveryMode) != config.CallbackDeliveryAsync {
+		return nil
+	}
+	if config.GetString(config.APIMode) == config.APIModeLambda {
+		return NewInlineQueue(store, publisher)
+	}
+	return NewWorkerPool(store, publisher, config.GetInt(config.CallbackWorkers), config.GetInt(config.CallbackQueueSize))
+}
+
+// Deliver sends the callbacks of the job and writes their responses back to the stored request.
+func Deliver(ctx context.Context, store Store, publisher Publisher, job Job) {
+	deliver(ctx, ctx, store, publisher, job)
+}
+
+// DeliverInline is Deliver for the jobs delivered while the request waits, their retries are bounded by callback.InlineContext.
+func DeliverInline(ctx context.Context, store Store, publisher Publisher, job Job) {
+	callbackCtx, cancel := callback.InlineContext(ctx)
+	defer cancel()
+	deliver(ctx, callbackCtx, store, publisher, job)
+}
+
+func deliver(ctx context.Context, callbackCtx context.Context, store Store, publisher Publisher, job Job) {
+	request := job.Request
+	var deadLetters []model.DeadLetter
+	request.CallbackResponses, deadLetters = callback.Deliver(callbackCtx, job.Inbox, request)
+	request.CallbacksPending = false
+	err := store.UpdateInboxRequest(ctx, job.Inbox.ID, request)
+	if err != nil {
+		slog.Error("error storing callback responses", "error", err, "inbox_id", job.Inbox.ID, "request_id", request.ID)
+	} else if publisher != nil {
+		publisher.Publish(job.Inbox.ID, request)
+	}
+	StoreDeadLetters(ctx, store, job.Inbox.ID, request.ID, deadLetters)
+}
+
+// StoreDeadLetters keeps the callbacks of the request that failed every attempt, errors are only logged.
+func StoreDeadLetters(ctx context.Context, store Store, ID uuid.UUID, requestID int, deadLetters []model.DeadLetter) {
+	for _, dl := range deadLetters {
+		dl.RequestID = requestID
+		if _, err := store.PutDeadLetter(ctx, ID, dl); err != nil {
+			slog.Error("error storing callback dead letter", "error", err, "inbox_id", ID, "callback_index", dl.CallbackIndex)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func init() {
+	config.LoadConfig(config.Test)
+	config.Set(config.EnableCallbackURLValidation, false)
+}
+
+type memoryStore struct {
+	mu          sync.Mutex
+	requests    map[int]model.Request
+	deadLetters []model.DeadLetter
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{requests: map[int]model.Request{}}
+}
+
+func (s *memoryStore) UpdateInboxRequest(ctx context.Context, ID uuid.UUID, req model.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[req.ID] = req
+	return nil
+}
+
+func (s *memoryStore) PutDeadLetter(ctx context.Context, ID uuid.UUID, dl model.DeadLetter) (model.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, dl)
+	return dl, nil
+}
+
+func (s *memoryStore) request(id int) (model.Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	return req, ok
+}
+
+func newJob(url string, requestID int) Job {
+	inbox := model.NewInbox()
+	inbox.Callbacks = []model.Callback{{IsEnabled: true, ToURL: url, Method: http.MethodPost}}
+	return Job{
+		Inbox:   inbox,
+		Request: model.Request{ID: requestID, Method: http.MethodPost, CallbacksPending: true},
+	}
+}
+
+func newTarget(t *testing.T, code int) *httptest.Server {
+	t.Helper()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(target.Close)
+	return target
+}
+
+func TestDeliver(t *testing.T) {
+	store := newMemoryStore()
+	target := newTarget(t, http.StatusBadGateway)
+
+	Deliver(context.Background(), store, nil, newJob(target.URL, 7))
+
+	req, ok := store.request(7)
+	if !ok {
+		t.Fatal("Expected the request to be updated")
+	}
+	t_util.AssertEquals(t, req.CallbacksPending, false)
+	t_util.AssertLen(t, req.CallbackResponses, 1)
+	t_util.AssertEquals(t, req.CallbackResponses[0].Code, http.StatusBadGateway)
+	t_util.AssertLen(t, store.deadLetters, 1)
+	t_util.AssertEquals(t, store.deadLetters[0].RequestID, 7)
+}
+
+func TestWorkerPool(t *testing.T) {
+	store := newMemoryStore()
+	target := newTarget(t, http.StatusOK)
+	pool := NewWorkerPool(store, nil, 2, 10)
+
+	for i := 0; i < 5; i++ {
+		if err := pool.Enqueue(context.Background(), newJob(target.URL, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		req, ok := store.request(i)
+		if !ok {
+			t.Fatalf("Expected request %d to be delivered", i)
+		}
+		t_util.AssertEquals(t, req.CallbackResponses[0].Code, http.StatusOK)
+	}
+	err := pool.Enqueue(context.Background(), newJob(target.URL, 5))
+	if !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expected %v, got %v", ErrQueueClosed, err)
+	}
+}
+
+func TestWorkerPoolFull(t *testing.T) {
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer target.Close()
+	pool := NewWorkerPool(newMemoryStore(), nil, 1, 1)
+	defer func() {
+		close(release)
+		if err := pool.Close(context.Background()); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = pool.Enqueue(context.Background(), newJob(target.URL, i))
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected %v, got %v", ErrQueueFull, err)
+	}
+}
+
+func TestInlineQueue(t *testing.T) {
+	store := newMemoryStore()
+	target := newTarget(t, http.StatusOK)
+
+	err := NewInlineQueue(store, nil).Enqueue(context.Background(), newJob(target.URL, 1))
+
+	t_util.AssertEquals(t, err, nil)
+	req, ok := store.request(1)
+	if !ok {
+		t.Fatal("Expected the request to be delivered before Enqueue returns")
+	}
+	t_util.AssertEquals(t, req.CallbacksPending, false)
+}
+
+func TestNewQueue(t *testing.T) {
+	defer config.Set(config.CallbackDeliveryMode, config.CallbackDeliverySync)
+	defer config.Set(config.APIMode, config.APIModeServer)
+
+	if NewQueue(newMemoryStore(), nil) != nil {
+		t.Error("Expected no queue in sync mode")
+	}
+	config.Set(config.CallbackDeliveryMode, config.CallbackDeliveryAsync)
+	pool, ok := NewQueue(newMemoryStore(), nil).(*WorkerPool)
+	if !ok {
+		t.Error("Expected a worker pool in server mode")
+	} else if err := pool.Close(context.Background()); err != nil {
+		t.Error(err)
+	}
+	config.Set(config.APIMode, config.APIModeLambda)
+	if _, ok := NewQueue(newMemoryStore(), nil).(*InlineQueue); !ok {
+		t.Error("Expected an inline queue in lambda mode")
+	}
+}
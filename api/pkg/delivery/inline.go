@@ -0,0 +1,23 @@
+package delivery
+
+import "context"
+
+// InlineQueue delivers each job before Enqueue returns. It stands in for a managed queue where
+// background work does not survive the request, as in a lambda.
+type InlineQueue struct {
+	store     Store
+	publisher Publisher
+}
+
+func NewInlineQueue(store Store, publisher Publisher) *InlineQueue {
+	return &InlineQueue{store: store, publisher: publisher}
+}
+
+func (q *InlineQueue) Enqueue(ctx context.Context, job Job) error {
+	DeliverInline(context.WithoutCancel(ctx), q.store, q.publisher, job)
+	return nil
+}
+
+func (q *InlineQueue) Close(context.Context) error {
+	return nil
+}
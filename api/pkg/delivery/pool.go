@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkerPool delivers jobs in the background with a fixed number of workers.
+// Jobs are rejected with ErrQueueFull instead of blocking the request when the buffer is full.
+type WorkerPool struct {
+	store     Store
+	publisher Publisher
+	jobs      chan Job
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.RWMutex
+	closed    bool
+}
+
+func NewWorkerPool(store Store, publisher Publisher, workers int, size int) *WorkerPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &WorkerPool{
+		store:     store,
+		publisher: publisher,
+		jobs:      make(chan Job, max(0, size)),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for i := 0; i < max(1, workers); i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		Deliver(p.ctx, p.store, p.publisher, job)
+	}
+}
+
+func (p *WorkerPool) Enqueue(ctx context.Context, job Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (p *WorkerPool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		// Pending callbacks are abandoned, their requests keep CallbacksPending
+		p.cancel()
+		return ctx.Err()
+	}
+}
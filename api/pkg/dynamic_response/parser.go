@@ -104,14 +104,38 @@ func ParseCallback(c context.Context, index int, inbox model.Inbox, req model.Re
 		return model.Callback{}, fmt.Errorf("callback %d %w", index, err)
 	}
 
-	return model.Callback{
-		IsEnabled: cb.IsEnabled,
-		IsDynamic: cb.IsDynamic,
-		ToURL:     parsedURL,
-		Method:    parsedMethod,
-		Headers:   parsedHeaders,
-		Body:      parsedBody,
-	}, nil
+	// Settings that are not templates, as retries and signing, are kept.
+	// Dynamic callbacks do not forward the request headers, their templates can use them.
+	cb.ToURL = parsedURL
+	cb.Method = parsedMethod
+	cb.Headers = parsedHeaders
+	cb.Body = parsedBody
+	cb.IsForwardingHeaders = false
+	return cb, nil
+}
+
+// ParseNotification renders a notification message template. Suppressed is the number of requests
+// that were not notified because of the throttling since the previous message.
+func ParseNotification(content string, inbox model.Inbox, req model.Request, suppressed int) (string, error) {
+	values := map[string]any{
+		"Request":    req,
+		"Inbox":      &inbox,
+		"Suppressed": suppressed,
+	}
+	message, err := parse(content, values)
+	if err != nil {
+		return "", fmt.Errorf("notification template error: %w", err)
+	}
+	return message, nil
+}
+
+// ParseEmail renders an email template with the values of the alert or the digest being sent.
+func ParseEmail(content string, values map[string]any) (string, error) {
+	message, err := parse(content, values)
+	if err != nil {
+		return "", fmt.Errorf("email template error: %w", err)
+	}
+	return message, nil
 }
 
 func parseHeaders(headers map[string]string, values map[string]any) (map[string]string, error) {
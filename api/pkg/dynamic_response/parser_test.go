@@ -322,6 +322,35 @@ func TestParseCallback(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			desc: "Dynamic callback keeps its retry policy",
+			inbox: func() model.Inbox {
+				in := model.CopyInbox(orgInbox)
+				in.Callbacks = []model.Callback{
+					{
+						IsEnabled:           true,
+						IsDynamic:           true,
+						ToURL:               "https://example.com/webhook-{{.Index}}",
+						Method:              "POST",
+						Headers:             map[string]string{},
+						IsForwardingHeaders: true,
+						Retry:               model.RetryPolicy{MaxAttempts: 3, InitialBackoffMillis: 100, RetryOnStatus: []int{503}},
+					},
+				}
+				return in
+			}(),
+			index: 0,
+			req:   model.CopyRequest(orgReq),
+			expect: model.Callback{
+				IsEnabled: true,
+				IsDynamic: true,
+				ToURL:     "https://example.com/webhook-0",
+				Method:    "POST",
+				Headers:   map[string]string{},
+				Retry:     model.RetryPolicy{MaxAttempts: 3, InitialBackoffMillis: 100, RetryOnStatus: []int{503}},
+			},
+			expectErr: false,
+		},
 		{
 			desc: "Template error in URL",
 			inbox: func() model.Inbox {
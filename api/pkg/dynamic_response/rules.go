@@ -0,0 +1,90 @@
+package dynamic_response
+
+import (
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/tidwall/gjson"
+)
+
+// SelectResponse returns the response of the first rule matching the request and the inbox scenario,
+// or the inbox response when none match. The scenario is not advanced, see NextResponse.
+func SelectResponse(inbox model.Inbox, req model.Request) model.Response {
+	resp, _ := NextResponse(inbox, inbox.Scenario, req)
+	return resp
+}
+
+// NextResponse selects the response for the request in the given scenario and returns the scenario after answering it.
+func NextResponse(inbox model.Inbox, scenario model.Scenario, req model.Request) (model.Response, model.Scenario) {
+	next := model.CopyScenario(scenario)
+	index := -1
+	for i, rule := range inbox.ResponseRules {
+		if (rule.State == "" || rule.State == scenario.CurrentState()) && MatchRule(rule, req) {
+			index = i
+			break
+		}
+	}
+	resp := inbox.Response
+	if index >= 0 {
+		rule := inbox.ResponseRules[index]
+		resp = rule.Response
+		if rule.NextState != "" {
+			next.State = rule.NextState
+		}
+	}
+	if len(resp.Sequence) > 0 {
+		key := model.ResponseKey(index)
+		resp = resp.SequenceResponse(scenario.Calls[key])
+		next.Calls[key]++
+	}
+	return resp, next
+}
+
+func MatchRule(rule model.ResponseRule, req model.Request) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, req.Method) {
+		return false
+	}
+	if rule.Path != "" {
+		p := req.InboxPath()
+		if p == "" {
+			p = "/"
+		}
+		if ok, _ := path.Match(rule.Path, p); !ok {
+			return false
+		}
+	}
+	if len(rule.Query) > 0 {
+		query, err := url.ParseQuery(strings.TrimPrefix(extractQueryParams(req.URI), "?"))
+		if err != nil || !matchValues(rule.Query, query, func(k string) string { return k }) {
+			return false
+		}
+	}
+	if !matchValues(rule.Headers, req.Headers, textproto.CanonicalMIMEHeaderKey) {
+		return false
+	}
+	if len(rule.Body) > 0 {
+		body := req.BodyText()
+		for _, m := range rule.Body {
+			result := gjson.Get(body, m.Path)
+			if !result.Exists() || (m.Value != "" && result.String() != m.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchValues checks that every expected key is present in values, with the expected value when it is not empty.
+func matchValues(expected map[string]string, values map[string][]string, canonicalKey func(string) string) bool {
+	for k, v := range expected {
+		found, ok := values[canonicalKey(k)]
+		if !ok || (v != "" && !collection.SliceContains(found, v)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package dynamic_response
+
+import (
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const rulesTestURI = "/api/v1/inboxes/6e47f781-64cd-4e71-89d8-9a3754aa788c/in"
+
+func TestMatchRule(t *testing.T) {
+	req := model.Request{
+		Method: "POST",
+		URI:    rulesTestURI + "/orders/42?status=paid&debug",
+		Headers: map[string][]string{
+			"Content-Type":  {"application/json"},
+			"X-Tenant":      {"acme"},
+			"Authorization": {"Bearer token"},
+		},
+		Body:         `{"order":{"id":42,"paid":true,"items":[{"sku":"A1"}]}}`,
+		BodyEncoding: model.BodyEncodingUTF8,
+	}
+
+	tests := []struct {
+		name     string
+		rule     model.ResponseRule
+		expected bool
+	}{
+		{"empty rule matches everything", model.ResponseRule{}, true},
+		{"method matches ignoring case", model.ResponseRule{Method: "post"}, true},
+		{"method does not match", model.ResponseRule{Method: "GET"}, false},
+		{"path glob matches", model.ResponseRule{Path: "/orders/*"}, true},
+		{"path glob does not match", model.ResponseRule{Path: "/users/*"}, false},
+		{"query value matches", model.ResponseRule{Query: map[string]string{"status": "paid"}}, true},
+		{"query presence matches", model.ResponseRule{Query: map[string]string{"debug": ""}}, true},
+		{"query value does not match", model.ResponseRule{Query: map[string]string{"status": "pending"}}, false},
+		{"missing query does not match", model.ResponseRule{Query: map[string]string{"page": ""}}, false},
+		{"header value matches ignoring name case", model.ResponseRule{Headers: map[string]string{"x-tenant": "acme"}}, true},
+		{"header presence matches", model.ResponseRule{Headers: map[string]string{"Authorization": ""}}, true},
+		{"header value does not match", model.ResponseRule{Headers: map[string]string{"X-Tenant": "other"}}, false},
+		{"body value matches", model.ResponseRule{Body: []model.BodyMatcher{{Path: "order.paid", Value: "true"}}}, true},
+		{"body array path matches", model.ResponseRule{Body: []model.BodyMatcher{{Path: "order.items.0.sku", Value: "A1"}}}, true},
+		{"body presence matches", model.ResponseRule{Body: []model.BodyMatcher{{Path: "order.id"}}}, true},
+		{"body value does not match", model.ResponseRule{Body: []model.BodyMatcher{{Path: "order.id", Value: "7"}}}, false},
+		{"missing body path does not match", model.ResponseRule{Body: []model.BodyMatcher{{Path: "order.customer"}}}, false},
+		{
+			"every condition must match",
+			model.ResponseRule{Method: "POST", Path: "/orders/*", Headers: map[string]string{"X-Tenant": "other"}},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchRule(tt.rule, req); got != tt.expected {
+				t.Errorf("MatchRule() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatchRuleRootPath(t *testing.T) {
+	req := model.Request{Method: "GET", URI: rulesTestURI}
+	if !MatchRule(model.ResponseRule{Path: "/"}, req) {
+		t.Error("MatchRule() should match the root path")
+	}
+}
+
+func TestMatchRuleDecodedBody(t *testing.T) {
+	req := model.Request{
+		URI:                 rulesTestURI,
+		Body:                "H4sIAAAAAAAA",
+		BodyEncoding:        model.BodyEncodingBase64,
+		DecodedBody:         `{"compressed":true}`,
+		DecodedBodyEncoding: model.BodyEncodingUTF8,
+	}
+	rule := model.ResponseRule{Body: []model.BodyMatcher{{Path: "compressed", Value: "true"}}}
+	if !MatchRule(rule, req) {
+		t.Error("MatchRule() should match the decoded body")
+	}
+}
+
+func TestSelectResponse(t *testing.T) {
+	inbox := model.Inbox{
+		Response: model.Response{Code: 200, Body: "default"},
+		ResponseRules: []model.ResponseRule{
+			{Name: "created", Method: "POST", Response: model.Response{Code: 201, Body: "created"}},
+			{Name: "any post", Method: "POST", Response: model.Response{Code: 202, Body: "second"}},
+			{Name: "deleted", Method: "DELETE", Response: model.Response{Code: 204}},
+		},
+	}
+	tests := []struct {
+		method       string
+		expectedCode int
+	}{
+		{"POST", 201},
+		{"DELETE", 204},
+		{"GET", 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			resp := SelectResponse(inbox, model.Request{Method: tt.method, URI: rulesTestURI})
+			if resp.Code != tt.expectedCode {
+				t.Errorf("SelectResponse() code = %d, want %d", resp.Code, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestNextResponseSequence(t *testing.T) {
+	sequence := []model.Response{{Code: 503}, {Code: 503}, {Code: 200}}
+	tests := []struct {
+		mode          string
+		expectedCodes []int
+	}{
+		{"", []int{503, 503, 200, 200, 200}},
+		{model.SequenceModeSticky, []int{503, 503, 200, 200, 200}},
+		{model.SequenceModeLoop, []int{503, 503, 200, 503, 503}},
+	}
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			inbox := model.Inbox{
+				Response: model.Response{Code: 200, Sequence: sequence, SequenceMode: tt.mode},
+			}
+			scenario := model.Scenario{}
+			for i, expected := range tt.expectedCodes {
+				var resp model.Response
+				resp, scenario = NextResponse(inbox, scenario, model.Request{Method: "GET", URI: rulesTestURI})
+				if resp.Code != expected {
+					t.Errorf("call %d: NextResponse() code = %d, want %d", i, resp.Code, expected)
+				}
+			}
+			if calls := scenario.Calls[model.ResponseKey(-1)]; calls != len(tt.expectedCodes) {
+				t.Errorf("calls = %d, want %d", calls, len(tt.expectedCodes))
+			}
+		})
+	}
+}
+
+func TestNextResponseScenarioState(t *testing.T) {
+	inbox := model.Inbox{
+		Response: model.Response{Code: 404},
+		ResponseRules: []model.ResponseRule{
+			{Name: "create", Method: "POST", State: model.ScenarioStarted, NextState: "created", Response: model.Response{Code: 201}},
+			{Name: "read", Method: "GET", State: "created", Response: model.Response{Code: 200}},
+			{Name: "delete", Method: "DELETE", State: "created", NextState: model.ScenarioStarted, Response: model.Response{Code: 204}},
+		},
+	}
+	steps := []struct {
+		method        string
+		expectedCode  int
+		expectedState string
+	}{
+		{"GET", 404, model.ScenarioStarted},
+		{"POST", 201, "created"},
+		{"POST", 404, "created"},
+		{"GET", 200, "created"},
+		{"DELETE", 204, model.ScenarioStarted},
+		{"GET", 404, model.ScenarioStarted},
+	}
+	scenario := model.Scenario{}
+	for _, step := range steps {
+		var resp model.Response
+		resp, scenario = NextResponse(inbox, scenario, model.Request{Method: step.method, URI: rulesTestURI})
+		if resp.Code != step.expectedCode {
+			t.Errorf("%s: NextResponse() code = %d, want %d", step.method, resp.Code, step.expectedCode)
+		}
+		if scenario.CurrentState() != step.expectedState {
+			t.Errorf("%s: state = %q, want %q", step.method, scenario.CurrentState(), step.expectedState)
+		}
+	}
+}
+
+func TestNextResponseDoesNotModifyScenario(t *testing.T) {
+	inbox := model.Inbox{Response: model.Response{Code: 200, Sequence: []model.Response{{Code: 503}, {Code: 200}}}}
+	scenario := model.Scenario{Calls: map[string]int{}}
+	_, next := NextResponse(inbox, scenario, model.Request{Method: "GET", URI: rulesTestURI})
+	if len(scenario.Calls) != 0 {
+		t.Errorf("NextResponse() modified the given scenario: %v", scenario)
+	}
+	if next.Calls[model.ResponseKey(-1)] != 1 {
+		t.Errorf("next calls = %v, want 1", next.Calls)
+	}
+}
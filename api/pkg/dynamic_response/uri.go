@@ -2,6 +2,8 @@ package dynamic_response
 
 import (
 	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
 
 func extractURI(uri string) string {
@@ -21,15 +23,7 @@ func extractURI(uri string) string {
 }
 
 func extractPath(uri string) string {
-	extraURI := extractURI(uri)
-	if extraURI == "" {
-		return ""
-	}
-
-	if idx := strings.Index(extraURI, "?"); idx != -1 {
-		return extraURI[:idx]
-	}
-	return extraURI
+	return model.Request{URI: uri}.InboxPath()
 }
 
 func extractQueryParams(uri string) string {
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// MockServerExpectation is an expectation of a MockServer initialization file, see https://www.mock-server.com/
+type MockServerExpectation struct {
+	HTTPRequest  MockServerRequest  `json:"httpRequest"`
+	HTTPResponse MockServerResponse `json:"httpResponse"`
+}
+
+type MockServerRequest struct {
+	Method                string              `json:"method"`
+	Path                  string              `json:"path"`
+	QueryStringParameters map[string][]string `json:"queryStringParameters,omitempty"`
+	Headers               map[string][]string `json:"headers,omitempty"`
+	Body                  *MockServerBody     `json:"body,omitempty"`
+}
+
+type MockServerResponse struct {
+	StatusCode int                 `json:"statusCode"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       *MockServerBody     `json:"body,omitempty"`
+}
+
+type MockServerBody struct {
+	Type        string          `json:"type"`
+	String      string          `json:"string,omitempty"`
+	JSON        json.RawMessage `json:"json,omitempty"`
+	Base64Bytes string          `json:"base64Bytes,omitempty"`
+}
+
+func toMockServer(stubs []Stub) []MockServerExpectation {
+	expectations := make([]MockServerExpectation, len(stubs))
+	for i, s := range stubs {
+		req := MockServerRequest{
+			Method:  s.Method,
+			Path:    s.Path,
+			Headers: s.Headers,
+			Body:    mockServerBody(s.Body),
+		}
+		if len(s.Query) > 0 {
+			req.QueryStringParameters = s.Query
+		}
+		if len(req.Headers) == 0 {
+			req.Headers = nil
+		}
+		resp := MockServerResponse{
+			StatusCode: s.Response.Code,
+			Body:       mockServerBody([]byte(s.Response.Body)),
+		}
+		if len(s.Response.Headers) > 0 {
+			resp.Headers = make(map[string][]string, len(s.Response.Headers))
+			for k, v := range s.Response.Headers {
+				resp.Headers[k] = []string{v}
+			}
+		}
+		expectations[i] = MockServerExpectation{HTTPRequest: req, HTTPResponse: resp}
+	}
+	return expectations
+}
+
+func mockServerBody(body []byte) *MockServerBody {
+	switch {
+	case len(body) == 0:
+		return nil
+	case json.Valid(body):
+		return &MockServerBody{Type: "JSON", JSON: body}
+	case utf8.Valid(body):
+		return &MockServerBody{Type: "STRING", String: string(body)}
+	default:
+		return &MockServerBody{Type: "BINARY", Base64Bytes: base64.StdEncoding.EncodeToString(body)}
+	}
+}
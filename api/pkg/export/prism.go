@@ -0,0 +1,105 @@
+package export
+
+import (
+	"encoding/json"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const defaultExampleContentType = "text/plain"
+
+// OpenAPIDocument is the minimal OpenAPI 3 document Prism needs to mock the captured responses, see https://stoplight.io/open-source/prism
+type OpenAPIDocument struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    OpenAPIInfo                            `json:"info"`
+	Paths   map[string]map[string]OpenAPIOperation `json:"paths"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIOperation struct {
+	Parameters []OpenAPIParameter         `json:"parameters,omitempty"`
+	Responses  map[string]OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name    string `json:"name"`
+	In      string `json:"in"`
+	Example string `json:"example,omitempty"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Example any `json:"example,omitempty"`
+}
+
+// toPrism groups the stubs by path and method, the first response of each status code is its example.
+func toPrism(inbox model.Inbox, stubs []Stub) OpenAPIDocument {
+	doc := OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: inbox.Name, Version: "1.0.0"},
+		Paths:   map[string]map[string]OpenAPIOperation{},
+	}
+	for _, s := range stubs {
+		if doc.Paths[s.Path] == nil {
+			doc.Paths[s.Path] = map[string]OpenAPIOperation{}
+		}
+		method := strings.ToLower(s.Method)
+		op, ok := doc.Paths[s.Path][method]
+		if !ok {
+			op = OpenAPIOperation{Parameters: queryParameters(s.Query), Responses: map[string]OpenAPIResponse{}}
+		}
+		code := strconv.Itoa(s.Response.Code)
+		if _, ok := op.Responses[code]; !ok {
+			op.Responses[code] = prismResponse(s.Response)
+		}
+		doc.Paths[s.Path][method] = op
+	}
+	return doc
+}
+
+func queryParameters(query map[string][]string) []OpenAPIParameter {
+	params := make([]OpenAPIParameter, 0, len(query))
+	for name, values := range query {
+		p := OpenAPIParameter{Name: name, In: "query"}
+		if len(values) > 0 {
+			p.Example = values[0]
+		}
+		params = append(params, p)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
+}
+
+func prismResponse(resp StubResponse) OpenAPIResponse {
+	r := OpenAPIResponse{Description: "Captured response"}
+	if resp.Body == "" {
+		return r
+	}
+	contentType := defaultExampleContentType
+	for k, v := range resp.Headers {
+		if strings.EqualFold(k, model.ContentTypeHeader) {
+			if mediaType, _, err := mime.ParseMediaType(v); err == nil {
+				contentType = mediaType
+			}
+		}
+	}
+	var example any = resp.Body
+	var decoded any
+	if json.Unmarshal([]byte(resp.Body), &decoded) == nil {
+		example = decoded
+	}
+	r.Content = map[string]OpenAPIMediaType{contentType: {Example: example}}
+	return r
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
+	"github.com/jesusnoseq/request-inbox/pkg/dynamic_response"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	FormatWireMock   = "wiremock"
+	FormatMockServer = "mockserver"
+	FormatPrism      = "prism"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported stub format")
+
+// ignoredHeaders are set by clients and proxies on every request, matching them would make the stubs useless
+var ignoredHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Connection",
+	"Content-Length",
+	"Cookie",
+	"Host",
+	"User-Agent",
+	"Via",
+}
+
+var ignoredHeaderPrefixes = []string{"X-Forwarded-", "X-Amzn-", "Cf-"}
+
+// Stub is a request captured by the inbox with the response it was answered with.
+type Stub struct {
+	Method  string
+	Path    string
+	Query   map[string][]string
+	Headers map[string][]string
+	// Body is nil when the body can not be matched because it was truncated
+	Body     []byte
+	Response StubResponse
+}
+
+type StubResponse struct {
+	Code    int
+	Headers map[string]string
+	Body    string
+}
+
+// Stubs converts the requests of the inbox into stubs, keeping the first request of each method, path and body.
+// Proxied requests are answered with the captured upstream response, the rest with the inbox response they matched.
+func Stubs(ctx context.Context, inbox model.Inbox) []Stub {
+	stubs := []Stub{}
+	seen := map[string]bool{}
+	for _, req := range inbox.Requests {
+		stub, ok := toStub(ctx, inbox, req)
+		if !ok {
+			continue
+		}
+		key := stubKey(stub)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		stubs = append(stubs, stub)
+	}
+	return stubs
+}
+
+// Export returns the stubs of the inbox in the given format, ready to be encoded as JSON.
+func Export(ctx context.Context, inbox model.Inbox, format string) (any, error) {
+	stubs := Stubs(ctx, inbox)
+	switch format {
+	case FormatWireMock:
+		return toWireMock(stubs), nil
+	case FormatMockServer:
+		return toMockServer(stubs), nil
+	case FormatPrism:
+		return toPrism(inbox, stubs), nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func toStub(ctx context.Context, inbox model.Inbox, req model.Request) (Stub, bool) {
+	uri, err := url.Parse(req.URI)
+	if err != nil {
+		return Stub{}, false
+	}
+	path := req.InboxPath()
+	if path == "" {
+		path = "/"
+	}
+	stub := Stub{
+		Method:   req.Method,
+		Path:     path,
+		Query:    uri.Query(),
+		Headers:  map[string][]string{},
+		Response: stubResponse(ctx, inbox, req),
+	}
+	for k, v := range req.Headers {
+		if !isIgnoredHeader(k) {
+			stub.Headers[k] = v
+		}
+	}
+	if !req.BodyTruncated {
+		body, err := req.RawBody()
+		if err != nil {
+			return Stub{}, false
+		}
+		stub.Body = body
+	}
+	return stub, true
+}
+
+func stubResponse(ctx context.Context, inbox model.Inbox, req model.Request) StubResponse {
+	if req.ProxyResponse != nil && req.ProxyResponse.Error == "" {
+		if body, err := req.ProxyResponse.RawBody(); err == nil {
+			headers := make(map[string]string, len(req.ProxyResponse.Headers))
+			for k, values := range req.ProxyResponse.Headers {
+				if len(values) > 0 {
+					headers[k] = values[0]
+				}
+			}
+			return StubResponse{Code: req.ProxyResponse.Code, Headers: headers, Body: string(body)}
+		}
+	}
+	inbox.Response = dynamic_response.SelectResponse(inbox, req)
+	if inbox.Response.IsDynamic {
+		// The template is exported as it is when it can not be rendered
+		if parsed, err := dynamic_response.ParseInboxResponse(ctx, inbox, req); err == nil {
+			inbox = parsed
+		}
+	}
+	code := inbox.Response.Code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	return StubResponse{
+		Code:    code,
+		Headers: inbox.Response.Headers,
+		Body:    inbox.Response.Body,
+	}
+}
+
+func stubKey(s Stub) string {
+	sum := sha256.Sum256(s.Body)
+	return s.Method + " " + s.Path + " " + string(sum[:])
+}
+
+func isIgnoredHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	if collection.SliceContains(ignoredHeaders, key) {
+		return true
+	}
+	for _, prefix := range ignoredHeaderPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+const stubsTestURI = "/api/v1/inboxes/6e47f781-64cd-4e71-89d8-9a3754aa788c/in"
+
+func stubsTestInbox() model.Inbox {
+	inbox := model.NewInbox()
+	inbox.Response = model.Response{Code: 200, Body: `{"ok":true}`, Headers: map[string]string{"Content-Type": "application/json"}}
+	inbox.ResponseRules = []model.ResponseRule{
+		{Method: "DELETE", Response: model.Response{Code: 204}},
+	}
+	inbox.Requests = []model.Request{
+		{Method: "POST", URI: stubsTestURI + "/orders?page=1", Body: `{"id":1}`, BodyEncoding: model.BodyEncodingUTF8,
+			Headers: map[string][]string{"Content-Type": {"application/json"}, "User-Agent": {"curl"}, "X-Forwarded-For": {"1.1.1.1"}}},
+		// Same method, path and body than the first one, only the first is exported
+		{Method: "POST", URI: stubsTestURI + "/orders?page=2", Body: `{"id":1}`, BodyEncoding: model.BodyEncodingUTF8},
+		{Method: "POST", URI: stubsTestURI + "/orders", Body: `{"id":2}`, BodyEncoding: model.BodyEncodingUTF8},
+		{Method: "DELETE", URI: stubsTestURI + "/orders/1"},
+		{Method: "GET", URI: stubsTestURI, ProxyResponse: &model.ProxyResponse{Code: 418, Body: "teapot", BodyEncoding: model.BodyEncodingUTF8, Headers: map[string][]string{"Content-Type": {"text/plain"}}}},
+	}
+	return inbox
+}
+
+func TestStubs(t *testing.T) {
+	stubs := Stubs(context.Background(), stubsTestInbox())
+
+	t_util.AssertLen(t, stubs, 4)
+	first := stubs[0]
+	t_util.AssertStringEquals(t, first.Path, "/orders")
+	t_util.AssertStringEquals(t, first.Query["page"][0], "1")
+	t_util.AssertStringEquals(t, string(first.Body), `{"id":1}`)
+	if len(first.Headers) != 1 || first.Headers["Content-Type"] == nil {
+		t.Errorf("Expected only the Content-Type header, got %v", first.Headers)
+	}
+	t_util.AssertEquals(t, first.Response.Code, 200)
+	t_util.AssertStringEquals(t, string(stubs[1].Body), `{"id":2}`)
+	t_util.AssertEquals(t, stubs[2].Response.Code, 204)
+	t_util.AssertStringEquals(t, stubs[3].Path, "/")
+	t_util.AssertEquals(t, stubs[3].Response.Code, 418)
+	t_util.AssertStringEquals(t, stubs[3].Response.Body, "teapot")
+}
+
+func TestStubsDynamicResponse(t *testing.T) {
+	inbox := model.NewInbox()
+	inbox.Response = model.Response{Code: 200, Body: "{{ .Request.Method }} received", IsDynamic: true}
+	inbox.Requests = []model.Request{{Method: "PATCH", URI: stubsTestURI}}
+
+	stubs := Stubs(context.Background(), inbox)
+
+	t_util.AssertLen(t, stubs, 1)
+	t_util.AssertStringEquals(t, stubs[0].Response.Body, "PATCH received")
+}
+
+func mustExportJSON(t *testing.T, format string) string {
+	t.Helper()
+	exported, err := Export(context.Background(), stubsTestInbox(), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExportWireMock(t *testing.T) {
+	got := mustExportJSON(t, FormatWireMock)
+
+	for _, expected := range []string{
+		`"method":"POST","urlPath":"/orders","queryParameters":{"page":{"equalTo":"1"}}`,
+		`"headers":{"Content-Type":{"equalTo":"application/json"}}`,
+		`"bodyPatterns":[{"equalToJson":{"id":1}}]`,
+		`"response":{"status":418,"headers":{"Content-Type":"text/plain"},"body":"teapot"}`,
+	} {
+		t_util.AssertStringContains(t, got, expected)
+	}
+}
+
+func TestExportMockServer(t *testing.T) {
+	got := mustExportJSON(t, FormatMockServer)
+
+	for _, expected := range []string{
+		`"httpRequest":{"method":"POST","path":"/orders","queryStringParameters":{"page":["1"]}`,
+		`"body":{"type":"JSON","json":{"id":1}}`,
+		`"httpResponse":{"statusCode":418,"headers":{"Content-Type":["text/plain"]},"body":{"type":"STRING","string":"teapot"}}`,
+	} {
+		t_util.AssertStringContains(t, got, expected)
+	}
+}
+
+func TestExportPrism(t *testing.T) {
+	exported, err := Export(context.Background(), stubsTestInbox(), FormatPrism)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := exported.(OpenAPIDocument)
+
+	post := doc.Paths["/orders"]["post"]
+	t_util.AssertLen(t, post.Parameters, 1)
+	t_util.AssertStringEquals(t, post.Parameters[0].Name, "page")
+	example := post.Responses["200"].Content["application/json"].Example
+	t_util.AssertStringEquals(t, jsonString(t, example), `{"ok":true}`)
+	t_util.AssertEquals(t, doc.Paths["/"]["get"].Responses["418"].Content["text/plain"].Example, any("teapot"))
+	if _, ok := doc.Paths["/orders/1"]["delete"].Responses["204"]; !ok {
+		t.Errorf("Expected a 204 response for DELETE /orders/1, got %v", doc.Paths["/orders/1"])
+	}
+}
+
+func jsonString(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	_, err := Export(context.Background(), stubsTestInbox(), "postman")
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Export() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
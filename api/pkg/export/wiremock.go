@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// WireMockMappings is the content of a WireMock mappings file, see https://wiremock.org/docs/stubbing/
+type WireMockMappings struct {
+	Mappings []WireMockMapping `json:"mappings"`
+}
+
+type WireMockMapping struct {
+	Request  WireMockRequest  `json:"request"`
+	Response WireMockResponse `json:"response"`
+}
+
+type WireMockRequest struct {
+	Method          string                     `json:"method"`
+	URLPath         string                     `json:"urlPath"`
+	QueryParameters map[string]WireMockMatcher `json:"queryParameters,omitempty"`
+	Headers         map[string]WireMockMatcher `json:"headers,omitempty"`
+	BodyPatterns    []WireMockMatcher          `json:"bodyPatterns,omitempty"`
+}
+
+type WireMockMatcher struct {
+	EqualTo       *string         `json:"equalTo,omitempty"`
+	EqualToJSON   json.RawMessage `json:"equalToJson,omitempty"`
+	BinaryEqualTo string          `json:"binaryEqualTo,omitempty"`
+}
+
+type WireMockResponse struct {
+	Status     int               `json:"status"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Base64Body string            `json:"base64Body,omitempty"`
+}
+
+func toWireMock(stubs []Stub) WireMockMappings {
+	mappings := WireMockMappings{Mappings: make([]WireMockMapping, len(stubs))}
+	for i, s := range stubs {
+		req := WireMockRequest{
+			Method:          s.Method,
+			URLPath:         s.Path,
+			QueryParameters: firstValueMatchers(s.Query),
+			Headers:         firstValueMatchers(s.Headers),
+		}
+		if len(s.Body) > 0 {
+			req.BodyPatterns = []WireMockMatcher{wireMockBodyMatcher(s.Body)}
+		}
+		resp := WireMockResponse{Status: s.Response.Code, Headers: s.Response.Headers}
+		if utf8.ValidString(s.Response.Body) {
+			resp.Body = s.Response.Body
+		} else {
+			resp.Base64Body = base64.StdEncoding.EncodeToString([]byte(s.Response.Body))
+		}
+		mappings.Mappings[i] = WireMockMapping{Request: req, Response: resp}
+	}
+	return mappings
+}
+
+func firstValueMatchers(values map[string][]string) map[string]WireMockMatcher {
+	if len(values) == 0 {
+		return nil
+	}
+	matchers := make(map[string]WireMockMatcher, len(values))
+	for k, v := range values {
+		value := ""
+		if len(v) > 0 {
+			value = v[0]
+		}
+		matchers[k] = WireMockMatcher{EqualTo: &value}
+	}
+	return matchers
+}
+
+func wireMockBodyMatcher(body []byte) WireMockMatcher {
+	if json.Valid(body) {
+		return WireMockMatcher{EqualToJSON: body}
+	}
+	if utf8.Valid(body) {
+		value := string(body)
+		return WireMockMatcher{EqualTo: &value}
+	}
+	return WireMockMatcher{BinaryEqualTo: base64.StdEncoding.EncodeToString(body)}
+}
@@ -0,0 +1,127 @@
+package fault
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	DefaultChunkBytes     = 16
+	DefaultIntervalMillis = 100
+)
+
+// DelayDuration returns the time to wait before the response, capped to the server maximum.
+func DelayDuration(d model.Delay) time.Duration {
+	ms := d.MinMillis
+	if d.MaxMillis > d.MinMillis {
+		ms += rand.IntN(d.MaxMillis - d.MinMillis + 1)
+	}
+	ms = min(ms, config.GetInt(config.MaxResponseDelayMillisKey))
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Sleep waits d, it returns early with an error when ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WriteDuration returns the longest time writing the response can take with the fault.
+func WriteDuration(f model.Fault) time.Duration {
+	if f.Type != model.FaultSlowBody {
+		return 0
+	}
+	return time.Duration(config.GetInt(config.MaxSlowBodyMillisKey)) * time.Millisecond
+}
+
+// Write writes the response breaking it as the fault says, the headers must be already set in w.
+// Faults that close the connection only write the status code when the connection can not be hijacked, e.g. in Lambda.
+func Write(ctx context.Context, w http.ResponseWriter, code int, body []byte, f model.Fault) {
+	switch f.Type {
+	case model.FaultConnectionReset:
+		if closeConnection(w, true) {
+			return
+		}
+		w.WriteHeader(code)
+	case model.FaultEmptyResponse:
+		if closeConnection(w, false) {
+			return
+		}
+		w.WriteHeader(code)
+	case model.FaultTruncatedBody:
+		// The server closes the connection when the handler writes less than the Content-Length
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(code)
+		_, _ = w.Write(body[:len(body)/2])
+	case model.FaultSlowBody:
+		writeSlowly(ctx, w, code, body, f)
+	default:
+		w.WriteHeader(code)
+		_, _ = w.Write(body)
+	}
+}
+
+// closeConnection closes the connection without writing anything, with a TCP RST instead of a FIN when reset is set.
+func closeConnection(w http.ResponseWriter, reset bool) bool {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return false
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return false
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok && reset {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+	return true
+}
+
+// writeSlowly writes the body in chunks, the rest of the body is written at once when the server maximum is reached.
+func writeSlowly(ctx context.Context, w http.ResponseWriter, code int, body []byte, f model.Fault) {
+	chunk := f.ChunkBytes
+	if chunk <= 0 {
+		chunk = DefaultChunkBytes
+	}
+	interval := time.Duration(f.IntervalMillis) * time.Millisecond
+	if interval <= 0 {
+		interval = DefaultIntervalMillis * time.Millisecond
+	}
+	deadline := time.Now().Add(time.Duration(config.GetInt(config.MaxSlowBodyMillisKey)) * time.Millisecond)
+	flusher, _ := w.(http.Flusher)
+
+	w.WriteHeader(code)
+	for len(body) > 0 {
+		n := min(chunk, len(body))
+		if time.Until(deadline) < interval {
+			n = len(body)
+		}
+		if _, err := w.Write(body[:n]); err != nil {
+			return
+		}
+		body = body[n:]
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if len(body) == 0 || Sleep(ctx, interval) != nil {
+			return
+		}
+	}
+}
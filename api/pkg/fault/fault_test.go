@@ -0,0 +1,155 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const testBody = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func TestDelayDuration(t *testing.T) {
+	config.LoadConfig(config.Test)
+	maxDelay := time.Duration(config.MaxResponseDelayMillisDefault) * time.Millisecond
+	tests := []struct {
+		name     string
+		delay    model.Delay
+		min, max time.Duration
+	}{
+		{"no delay", model.Delay{}, 0, 0},
+		{"fixed delay", model.Delay{MinMillis: 50}, 50 * time.Millisecond, 50 * time.Millisecond},
+		{"fixed delay with the same maximum", model.Delay{MinMillis: 50, MaxMillis: 50}, 50 * time.Millisecond, 50 * time.Millisecond},
+		{"random delay", model.Delay{MinMillis: 10, MaxMillis: 20}, 10 * time.Millisecond, 20 * time.Millisecond},
+		{"random delay from zero", model.Delay{MaxMillis: 20}, 0, 20 * time.Millisecond},
+		{"capped delay", model.Delay{MinMillis: config.MaxResponseDelayMillisDefault * 2}, maxDelay, maxDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got := DelayDuration(tt.delay)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("DelayDuration() = %v, want between %v and %v", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestSleepCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+
+	err := Sleep(ctx, 5*time.Second)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() error = %v, want %v", err, context.Canceled)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Sleep() did not return when the context was canceled")
+	}
+}
+
+func faultServer(t *testing.T, f model.Fault) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(r.Context(), w, http.StatusTeapot, []byte(testBody), f)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWriteClosingFaults(t *testing.T) {
+	config.LoadConfig(config.Test)
+	for _, faultType := range []string{model.FaultConnectionReset, model.FaultEmptyResponse} {
+		t.Run(faultType, func(t *testing.T) {
+			srv := faultServer(t, model.Fault{Type: faultType})
+
+			resp, err := http.Get(srv.URL)
+
+			if err == nil {
+				_ = resp.Body.Close()
+				t.Fatalf("Expected an error, but got status %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestWriteClosingFaultsWithoutHijack(t *testing.T) {
+	config.LoadConfig(config.Test)
+	w := httptest.NewRecorder()
+
+	Write(context.Background(), w, http.StatusBadGateway, []byte(testBody), model.Fault{Type: model.FaultConnectionReset})
+
+	if w.Code != http.StatusBadGateway || w.Body.Len() != 0 {
+		t.Errorf("Write() = %d %q, want %d without body", w.Code, w.Body.String(), http.StatusBadGateway)
+	}
+}
+
+func TestWriteTruncatedBody(t *testing.T) {
+	config.LoadConfig(config.Test)
+	srv := faultServer(t, model.Fault{Type: model.FaultTruncatedBody})
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected %v reading the body, but got %v", io.ErrUnexpectedEOF, err)
+	}
+	if string(b) != testBody[:len(testBody)/2] {
+		t.Errorf("Body = %q, want %q", b, testBody[:len(testBody)/2])
+	}
+	if resp.ContentLength != int64(len(testBody)) {
+		t.Errorf("ContentLength = %d, want %d", resp.ContentLength, len(testBody))
+	}
+}
+
+func TestWriteSlowBody(t *testing.T) {
+	config.LoadConfig(config.Test)
+	tests := []struct {
+		name        string
+		maxMillis   int
+		minDuration time.Duration
+		maxDuration time.Duration
+	}{
+		{"drips the body", config.MaxSlowBodyMillisDefault, 80 * time.Millisecond, 2 * time.Second},
+		{"is capped by the server maximum", 30, 0, 80 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Set(config.MaxSlowBodyMillisKey, tt.maxMillis)
+			defer config.Set(config.MaxSlowBodyMillisKey, config.MaxSlowBodyMillisDefault)
+			// 36 bytes in chunks of 8 are 5 chunks and 4 pauses
+			srv := faultServer(t, model.Fault{Type: model.FaultSlowBody, ChunkBytes: 8, IntervalMillis: 20})
+			start := time.Now()
+
+			resp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			elapsed := time.Since(start)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusTeapot || string(b) != testBody {
+				t.Errorf("Write() = %d %q, want %d %q", resp.StatusCode, b, http.StatusTeapot, testBody)
+			}
+			if elapsed < tt.minDuration || elapsed > tt.maxDuration {
+				t.Errorf("The body took %v, want between %v and %v", elapsed, tt.minDuration, tt.maxDuration)
+			}
+		})
+	}
+}
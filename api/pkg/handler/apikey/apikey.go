@@ -9,6 +9,7 @@ import (
 	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
 	"github.com/jesusnoseq/request-inbox/pkg/login"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
 )
 
 type apiKeyHandler struct {
@@ -45,6 +46,14 @@ func (h *apiKeyHandler) CreateAPIKey(c *gin.Context) {
 	}
 	apiKey.Name = aPIKeyParams.Name
 	apiKey.ExpiryDate = aPIKeyParams.ExpiryDate
+	apiKey.Permissions = model.NewFullAccessPermissions()
+	if aPIKeyParams.Permissions != nil {
+		if valid, err := validation.IsValidAPIKeyPermissions(aPIKeyParams.Permissions); !valid {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+			return
+		}
+		apiKey.Permissions = aPIKeyParams.Permissions
+	}
 
 	if err := h.dao.CreateAPIKey(c.Request.Context(), apiKey); err != nil {
 		c.AbortWithStatusJSON(model.ErrorResponseWithError("Failed to save API key", err, http.StatusInternalServerError))
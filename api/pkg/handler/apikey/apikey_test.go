@@ -56,8 +56,9 @@ func ApiKeyEquals(a, b model.APIKey) bool {
 }
 
 type APIKeyParams struct {
-	Name       string
-	ExpiryDate time.Time
+	Name        string
+	ExpiryDate  time.Time
+	Permissions model.APIKeyPermissions
 }
 
 func mustCreateAndSetLoggedUser(t *testing.T, ctx *gin.Context, dao database.Repository, email string) model.User {
@@ -126,6 +127,66 @@ func TestCreateAPIKey(t *testing.T) {
 	t_util.AssertSameID(t, createdAPIKey.OwnerID, user.ID)
 	t_util.AssertTimeDiffFromNow(t, createdAPIKey.CreationDate, 0, time.Duration(1)*time.Second)
 	t_util.AssertTimeDiffFromNow(t, createdAPIKey.ExpiryDate, 30*time.Duration(24)*time.Hour, 3*time.Duration(24)*time.Hour)
+	if diff := cmp.Diff(model.NewFullAccessPermissions(), createdAPIKey.Permissions); diff != "" {
+		t.Errorf("Expected full access permissions by default. Diff: %s", diff)
+	}
+}
+
+func TestCreateAPIKeyWithPermissions(t *testing.T) {
+	config.LoadConfig(config.Test)
+	handler, dao, closer := mustGetAPIKeyHandler()
+	defer closer()
+
+	testCases := []struct {
+		desc           string
+		permissions    model.APIKeyPermissions
+		expectedStatus int
+	}{
+		{
+			desc:           "Read only key",
+			permissions:    model.APIKeyPermissions{model.Inboxes: {model.Read: true}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			desc:           "Key without any granted permission",
+			permissions:    model.APIKeyPermissions{model.Inboxes: {model.Read: false}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "Key with unknown action",
+			permissions:    model.APIKeyPermissions{model.Inboxes: {"Share": true}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(w)
+			_ = mustCreateAndSetLoggedUser(t, ginCtx, dao, "test@mail.dev")
+			body := t_util.MustJson(t, APIKeyParams{
+				Name:        "scoped",
+				ExpiryDate:  time.Now().AddDate(0, 1, 0),
+				Permissions: tc.permissions,
+			})
+			req, err := http.NewRequest("POST", "", bytes.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			ginCtx.Request = req
+
+			handler.CreateAPIKey(ginCtx)
+
+			t_util.AssertStatusCode(t, w.Code, tc.expectedStatus)
+			if tc.expectedStatus != http.StatusCreated {
+				return
+			}
+			createdAPIKey := mustParseAPIKey(w.Body.Bytes())
+			if diff := cmp.Diff(tc.permissions, createdAPIKey.Permissions); diff != "" {
+				t.Errorf("Unexpected permissions. Diff: %s", diff)
+			}
+		})
+	}
 }
 
 func TestCreateAPIKeyUnauthorized(t *testing.T) {
@@ -135,7 +196,7 @@ func TestCreateAPIKeyUnauthorized(t *testing.T) {
 	handler, dao, closer := mustGetAPIKeyHandler()
 	defer closer()
 	user := model.NewUser("test@mail.com")
-	apikey := mustCreateAPIKey(t, dao, user, APIKeyParams{"testapikey", time.Now().Add(time.Hour)})
+	apikey := mustCreateAPIKey(t, dao, user, APIKeyParams{Name: "testapikey", ExpiryDate: time.Now().Add(time.Hour)})
 
 	body := t_util.MustJson(t, apikey)
 	req, err := http.NewRequest(
@@ -165,7 +226,7 @@ func TestGetAPIKey(t *testing.T) {
 	handler, dao, closer := mustGetAPIKeyHandler()
 	defer closer()
 	user := mustCreateAndSetLoggedUser(t, ginCtx, dao, "test@mail.dev")
-	apiKey := mustCreateAPIKey(t, dao, user, APIKeyParams{"testapikey", time.Now().Add(time.Hour)})
+	apiKey := mustCreateAPIKey(t, dao, user, APIKeyParams{Name: "testapikey", ExpiryDate: time.Now().Add(time.Hour)})
 
 	req, err := http.NewRequest(
 		"GET",
@@ -198,11 +259,11 @@ func TestListAPIKeysByUser(t *testing.T) {
 	handler, dao, closer := mustGetAPIKeyHandler()
 	defer closer()
 	user := mustCreateAndSetLoggedUser(t, ginCtx, dao, "test@mail.dev")
-	apiKey1 := mustCreateAPIKey(t, dao, user, APIKeyParams{"testapikey1", time.Now().Add(time.Hour)})
-	apiKey2 := mustCreateAPIKey(t, dao, user, APIKeyParams{"testapikey2", time.Now().Add(time.Hour)})
+	apiKey1 := mustCreateAPIKey(t, dao, user, APIKeyParams{Name: "testapikey1", ExpiryDate: time.Now().Add(time.Hour)})
+	apiKey2 := mustCreateAPIKey(t, dao, user, APIKeyParams{Name: "testapikey2", ExpiryDate: time.Now().Add(time.Hour)})
 
 	otherUser := model.NewUser("other@mail.dev")
-	otherApiKey := mustCreateAPIKey(t, dao, otherUser, APIKeyParams{"testapikey3", time.Now().Add(time.Hour)})
+	otherApiKey := mustCreateAPIKey(t, dao, otherUser, APIKeyParams{Name: "testapikey3", ExpiryDate: time.Now().Add(time.Hour)})
 
 	req, err := http.NewRequest(
 		"GET",
@@ -241,9 +302,9 @@ func TestDeleteAPIKey(t *testing.T) {
 	handler, dao, closer := mustGetAPIKeyHandler()
 	defer closer()
 	user := mustCreateAndSetLoggedUser(t, ginCtx, dao, "test@mail.dev")
-	apiKey := mustCreateAPIKey(t, dao, user, APIKeyParams{"testapikey", time.Now().Add(time.Hour)})
+	apiKey := mustCreateAPIKey(t, dao, user, APIKeyParams{Name: "testapikey", ExpiryDate: time.Now().Add(time.Hour)})
 	otherUser := model.NewUser("other@mail.dev")
-	otherApiKey := mustCreateAPIKey(t, dao, otherUser, APIKeyParams{"other testapikey", time.Now().Add(time.Hour)})
+	otherApiKey := mustCreateAPIKey(t, dao, otherUser, APIKeyParams{Name: "other testapikey", ExpiryDate: time.Now().Add(time.Hour)})
 
 	req, err := http.NewRequest("DELETE", "", nil)
 	if err != nil {
@@ -272,7 +333,7 @@ func TestDeleteOtherUserAPIKey(t *testing.T) {
 	defer closer()
 	_ = mustCreateAndSetLoggedUser(t, ginCtx, dao, "test@mail.dev")
 	otherUser := model.NewUser("other@mail.dev")
-	otherApiKey := mustCreateAPIKey(t, dao, otherUser, APIKeyParams{"other testapikey", time.Now().Add(time.Hour)})
+	otherApiKey := mustCreateAPIKey(t, dao, otherUser, APIKeyParams{Name: "other testapikey", ExpiryDate: time.Now().Add(time.Hour)})
 
 	req, err := http.NewRequest("DELETE", "", nil)
 	if err != nil {
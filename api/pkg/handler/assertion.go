@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/assertion"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+// assertionPollInterval is how often a wait reads the stored requests, the hub only sees the requests
+// received by this instance of the service.
+const assertionPollInterval = time.Second
+
+// assertionPollLookback is how long before the newest collected request a poll starts reading. Requests are
+// stored after their proxy and callbacks, so one can be stored after newer ones.
+const assertionPollLookback = 30 * time.Second
+
+// WaitForRequests blocks until the inbox has the requests expected by the assertion or the timeout expires,
+// answering 408 with the result when it does.
+func (ih *inboxHandler) WaitForRequests(c *gin.Context) {
+	id, a, ok := ih.bindAssertion(c)
+	if !ok {
+		return
+	}
+	timeout := time.Duration(a.TimeoutSeconds) * time.Second
+	if a.TimeoutSeconds == 0 {
+		timeout = time.Duration(config.GetInt(config.AssertionTimeoutSeconds)) * time.Second
+	}
+	extendWriteDeadline(c, timeout)
+
+	// Subscribe before reading the stored requests so nothing is lost between both steps
+	sub := ih.hub.Subscribe(id)
+	defer func() { sub.Close() }()
+	received := map[int]model.Request{}
+	newest, err := ih.collectRequestsSince(c, id, a.Since, received)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	result := a.Evaluate(sortedRequests(received))
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(assertionPollInterval)
+	defer poll.Stop()
+	for !result.Passed {
+		select {
+		case req, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind, the poll reads what was missed
+				sub = ih.hub.Subscribe(id)
+				continue
+			}
+			received[req.ID] = req
+			newest = max(newest, req.Timestamp)
+		case <-poll.C:
+			// Only the recent requests are read again, the older ones were already collected
+			since := max(a.Since, newest-assertionPollLookback.Milliseconds())
+			polled, err := ih.collectRequestsSince(c, id, since, received)
+			if err != nil {
+				slog.Error("error polling requests of assertion", "error", err, "inbox_id", id)
+				continue
+			}
+			newest = max(newest, polled)
+		case <-deadline.C:
+			c.JSON(http.StatusRequestTimeout, result)
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+		result = a.Evaluate(sortedRequests(received))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyRequests checks the assertion against the stored requests without waiting.
+func (ih *inboxHandler) VerifyRequests(c *gin.Context) {
+	id, a, ok := ih.bindAssertion(c)
+	if !ok {
+		return
+	}
+	received := map[int]model.Request{}
+	if _, err := ih.collectRequestsSince(c, id, a.Since, received); err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	c.JSON(http.StatusOK, a.Evaluate(sortedRequests(received)))
+}
+
+// bindAssertion reads the assertion of the request, compiled so it can be evaluated repeatedly.
+func (ih *inboxHandler) bindAssertion(c *gin.Context) (uuid.UUID, assertion.Assertion, bool) {
+	a := model.Assertion{}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return id, assertion.Assertion{}, false
+	}
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid assertion", err, http.StatusBadRequest))
+		return id, assertion.Assertion{}, false
+	}
+	if valid, err := validation.IsValidAssertion(a); !valid {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+		return id, assertion.Assertion{}, false
+	}
+	compiled, err := assertion.Compile(a)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid assertion", err, http.StatusBadRequest))
+		return id, assertion.Assertion{}, false
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return id, assertion.Assertion{}, false
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return id, assertion.Assertion{}, false
+	}
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error asserting requests of inbox", "error", err)
+		return id, assertion.Assertion{}, false
+	}
+	return id, compiled, true
+}
+
+// collectRequestsSince adds the stored requests received from since (unix milliseconds) to received, by ID.
+// It returns the timestamp of the newest one, since when there are none.
+func (ih *inboxHandler) collectRequestsSince(c *gin.Context, id uuid.UUID, since int64, received map[int]model.Request) (int64, error) {
+	newest := since
+	cursor := ""
+	for {
+		page, err := ih.dao.ListInboxRequests(c, id,
+			option.WithTimeRange(since, 0),
+			option.WithLimit(option.MaxRequestsLimit),
+			option.WithCursor(cursor))
+		if err != nil {
+			return newest, fmt.Errorf("error listing requests since %d: %w", since, err)
+		}
+		for _, req := range page.Results {
+			received[req.ID] = req
+			newest = max(newest, req.Timestamp)
+		}
+		if page.NextCursor == "" {
+			return newest, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func sortedRequests(received map[int]model.Request) []model.Request {
+	requests := make([]model.Request, 0, len(received))
+	for _, req := range received {
+		requests = append(requests, req)
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID < requests[j].ID })
+	return requests
+}
@@ -0,0 +1,272 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func mustGetAssertionServer(t *testing.T) (database.Repository, *stream.Hub, *httptest.Server) {
+	config.LoadConfig(config.Test)
+	dao, hub, r := mustGetRouter(t, func(r *gin.Engine, ih handler.InboxService) {
+		r.POST("/:id/assertions/wait", ih.WaitForRequests)
+		r.POST("/:id/assertions/verify", ih.VerifyRequests)
+		r.Any("/:id/in", ih.RegisterInboxRequest)
+		r.Any("/:id/in/*path", ih.RegisterInboxRequest)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return dao, hub, srv
+}
+
+func postAssertion(t *testing.T, url string, a any) (int, model.AssertionResult) {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", bytes.NewReader(t_util.MustJson(t, a)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	result := model.AssertionResult{}
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusRequestTimeout {
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, result
+}
+
+func sendToInbox(t *testing.T, srv *httptest.Server, id uuid.UUID, method, path, body string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+"/"+id.String()+"/in"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestWaitForRequests(t *testing.T) {
+	dao, hub, srv := mustGetAssertionServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	a := model.Assertion{
+		Matcher: model.RequestMatcher{
+			Method: http.MethodPost,
+			Path:   "/orders",
+			Body:   []model.BodyMatcher{{Path: "status", Value: "paid"}},
+		},
+		Count:          2,
+		TimeoutSeconds: 5,
+	}
+	type response struct {
+		code   int
+		result model.AssertionResult
+	}
+	done := make(chan response, 1)
+	go func() {
+		code, result := postAssertion(t, srv.URL+"/"+inbox.ID.String()+"/assertions/wait", a)
+		done <- response{code, result}
+	}()
+	waitForSubscriber(t, hub, inbox.ID)
+
+	sendToInbox(t, srv, inbox.ID, http.MethodPost, "/orders", `{"status":"paid"}`)
+	sendToInbox(t, srv, inbox.ID, http.MethodPost, "/orders", `{"status":"pending"}`)
+	sendToInbox(t, srv, inbox.ID, http.MethodPost, "/orders", `{"status":"paid"}`)
+
+	select {
+	case got := <-done:
+		t_util.AssertStatusCode(t, got.code, http.StatusOK)
+		t_util.AssertTrue(t, got.result.Passed, "assertion passed")
+		t_util.AssertEquals(t, got.result.Matched, 2)
+		t_util.AssertLen(t, got.result.Requests, 2)
+		t_util.AssertEquals(t, got.result.Requests[1].ID, 2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return")
+	}
+}
+
+func TestWaitForRequestsPollsStoredRequests(t *testing.T) {
+	dao, hub, srv := mustGetAssertionServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	sendToInbox(t, srv, inbox.ID, http.MethodPost, "/orders", `{}`)
+	done := make(chan model.AssertionResult, 1)
+	go func() {
+		_, result := postAssertion(t, srv.URL+"/"+inbox.ID.String()+"/assertions/wait", model.Assertion{
+			Matcher:        model.RequestMatcher{Method: http.MethodPost, Path: "/orders"},
+			Count:          2,
+			TimeoutSeconds: 5,
+		})
+		done <- result
+	}()
+	waitForSubscriber(t, hub, inbox.ID)
+
+	// Stored by another instance of the service, so only the poll sees it
+	req := model.GenerateRequest(0)
+	req.Method = http.MethodPost
+	req.URI = "/api/v1/inboxes/" + inbox.ID.String() + "/in/orders"
+	if _, err := dao.AddRequestToInbox(context.Background(), inbox.ID, req); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-done:
+		t_util.AssertTrue(t, result.Passed, "assertion passed")
+		t_util.AssertEquals(t, result.Matched, 2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return")
+	}
+}
+
+func TestWaitForRequestsAlreadyReceived(t *testing.T) {
+	dao, _, srv := mustGetAssertionServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	sendToInbox(t, srv, inbox.ID, http.MethodPut, "/users/1", `{}`)
+
+	code, result := postAssertion(t, srv.URL+"/"+inbox.ID.String()+"/assertions/wait", model.Assertion{
+		Matcher: model.RequestMatcher{Method: http.MethodPut, Path: "/users/*"},
+	})
+
+	t_util.AssertStatusCode(t, code, http.StatusOK)
+	t_util.AssertTrue(t, result.Passed, "assertion passed")
+	t_util.AssertEquals(t, result.Matched, 1)
+}
+
+func TestWaitForRequestsTimeout(t *testing.T) {
+	dao, _, srv := mustGetAssertionServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	sendToInbox(t, srv, inbox.ID, http.MethodPost, "/orders", `{"status":"pending"}`)
+
+	start := time.Now()
+	code, result := postAssertion(t, srv.URL+"/"+inbox.ID.String()+"/assertions/wait", model.Assertion{
+		Matcher:        model.RequestMatcher{Method: http.MethodPost, Body: []model.BodyMatcher{{Path: "status", Value: "paid"}}},
+		TimeoutSeconds: 1,
+	})
+
+	t_util.AssertStatusCode(t, code, http.StatusRequestTimeout)
+	t_util.AssertTrue(t, time.Since(start) >= time.Second, "waited for the timeout")
+	t_util.AssertFalse(t, result.Passed, "assertion failed")
+	t_util.AssertNotNil(t, result.Closest)
+	t_util.AssertEquals(t, result.Closest.Diff[0], model.Mismatch{Field: "body status", Expected: "paid", Actual: "pending"})
+}
+
+func TestWaitForRequestsLongerThanWriteTimeout(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, _, r := mustGetRouter(t, func(r *gin.Engine, ih handler.InboxService) {
+		r.POST("/:id/assertions/wait", ih.WaitForRequests)
+	})
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+
+	code, result := postAssertion(t, srv.URL+"/"+inbox.ID.String()+"/assertions/wait", model.Assertion{TimeoutSeconds: 1})
+
+	t_util.AssertStatusCode(t, code, http.StatusRequestTimeout)
+	t_util.AssertFalse(t, result.Passed, "assertion failed")
+}
+
+func TestVerifyRequests(t *testing.T) {
+	dao, _, srv := mustGetAssertionServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	sendToInbox(t, srv, inbox.ID, http.MethodPost, "/orders", `{"id":1}`)
+	sendToInbox(t, srv, inbox.ID, http.MethodGet, "/orders/1", ``)
+
+	testCases := []struct {
+		desc      string
+		assertion model.Assertion
+		passed    bool
+		matched   int
+		diff      []model.Mismatch
+	}{
+		{
+			desc:      "Matching request",
+			assertion: model.Assertion{Matcher: model.RequestMatcher{Method: http.MethodPost, Body: []model.BodyMatcher{{Path: "id", Value: "1"}}}},
+			passed:    true,
+			matched:   1,
+		},
+		{
+			desc: "Header regex",
+			assertion: model.Assertion{Matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{
+				{Name: "content-type", Regex: "^application/(json|xml)$"},
+			}}, Count: 2},
+			passed:  true,
+			matched: 2,
+		},
+		{
+			desc:      "Not enough requests",
+			assertion: model.Assertion{Matcher: model.RequestMatcher{Path: "/orders/*"}, Count: 2},
+			passed:    false,
+			matched:   1,
+			diff:      []model.Mismatch{{Field: "path", Expected: "/orders/*", Actual: "/orders"}},
+		},
+		{
+			desc:      "No matching requests expected",
+			assertion: model.Assertion{Matcher: model.RequestMatcher{Method: http.MethodDelete}, Exactly: true},
+			passed:    true,
+			matched:   0,
+		},
+		{
+			desc:      "Closest request",
+			assertion: model.Assertion{Matcher: model.RequestMatcher{Method: http.MethodGet, Path: "/orders"}},
+			passed:    false,
+			matched:   0,
+			diff:      []model.Mismatch{{Field: "path", Expected: "/orders", Actual: "/orders/1"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			code, result := postAssertion(t, srv.URL+"/"+inbox.ID.String()+"/assertions/verify", tc.assertion)
+
+			t_util.AssertStatusCode(t, code, http.StatusOK)
+			t_util.AssertEquals(t, result.Passed, tc.passed)
+			t_util.AssertEquals(t, result.Matched, tc.matched)
+			if tc.diff == nil {
+				t_util.AssertTrue(t, result.Closest == nil, "no closest request")
+				return
+			}
+			t_util.AssertEqualsAsJson(t, result.Closest.Diff, tc.diff)
+		})
+	}
+}
+
+func TestAssertionErrors(t *testing.T) {
+	dao, _, srv := mustGetAssertionServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+
+	testCases := []struct {
+		desc      string
+		id        string
+		assertion any
+		code      int
+	}{
+		{"Invalid inbox ID", "abc", model.Assertion{}, http.StatusBadRequest},
+		{"Invalid body", inbox.ID.String(), "assertion", http.StatusBadRequest},
+		{"Invalid regex", inbox.ID.String(), model.Assertion{Matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "X", Regex: "("}}}}, http.StatusBadRequest},
+		{"Timeout too long", inbox.ID.String(), model.Assertion{TimeoutSeconds: 3600}, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			code, _ := postAssertion(t, srv.URL+"/"+tc.id+"/assertions/verify", tc.assertion)
+
+			t_util.AssertStatusCode(t, code, tc.code)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/callback"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// ListDeadLetters lists the callbacks of the inbox that failed every attempt, oldest first.
+func (ih *inboxHandler) ListDeadLetters(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error listing dead letters", "error", err)
+		return
+	}
+
+	deadLetters, err := ih.dao.ListDeadLetters(c, id)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	for i := range deadLetters {
+		deadLetters[i] = deadLetters[i].WithoutSecrets()
+	}
+	c.JSON(http.StatusOK, deadLetters)
+}
+
+// RetryDeadLetter sends a dead letter callback again. It is deleted when the callback succeeds,
+// otherwise it is kept with the new response.
+func (ih *inboxHandler) RetryDeadLetter(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+	deadLetterID, err := uuid.Parse(c.Param("deadLetterId"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid dead letter ID", err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkWriteInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error retrying dead letter", "error", err)
+		return
+	}
+
+	deadLetter, err := ih.dao.GetDeadLetter(c, id, deadLetterID)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	callbackCtx, cancel := callback.InlineContext(c)
+	resp := callback.RetryDeadLetter(callbackCtx, inbox, deadLetter)
+	cancel()
+	if callback.IsRetryable(deadLetter.Callback.Retry, resp) {
+		deadLetter.Response = resp
+		_, err = ih.dao.PutDeadLetter(c, id, deadLetter)
+	} else {
+		err = ih.dao.DeleteDeadLetter(c, id, deadLetterID)
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (ih *inboxHandler) DeleteDeadLetter(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+	deadLetterID, err := uuid.Parse(c.Param("deadLetterId"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid dead letter ID", err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkWriteInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error deleting dead letter", "error", err)
+		return
+	}
+
+	err = ih.dao.DeleteDeadLetter(c, id, deadLetterID)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
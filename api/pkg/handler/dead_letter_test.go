@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestDeadLetters(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	config.Set(config.EnableCallbackURLValidation, false)
+	defer config.Set(config.EnableCallbackURLValidation, config.EnableCallbackURLValidationDefault)
+	isDown := atomic.Bool{}
+	isDown.Store(true)
+	calls := atomic.Int32{}
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		if isDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: http.StatusOK}, nil)
+	inbox.Callbacks = []model.Callback{{
+		IsEnabled: true,
+		ToURL:     target.URL,
+		Method:    http.MethodPost,
+		Retry:     model.RetryPolicy{MaxAttempts: 2, InitialBackoffMillis: 1},
+		Signing:   model.Signing{IsEnabled: true, Secret: "callback secret"},
+	}}
+	if _, err := dao.UpdateInbox(context.Background(), inbox); err != nil {
+		t.Fatal(err)
+	}
+	base := "/" + inbox.ID.String() + "/dead-letters"
+
+	serve(r, httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", strings.NewReader("event")))
+	t_util.AssertEquals(t, int(calls.Load()), 2)
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, base, nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	deadLetters := []model.DeadLetter{}
+	if err := json.Unmarshal(w.Body.Bytes(), &deadLetters); err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertLen(t, deadLetters, 1)
+	t_util.AssertEquals(t, deadLetters[0].RequestID, 0)
+	t_util.AssertLen(t, deadLetters[0].Response.Attempts, 2)
+	t_util.AssertStringEquals(t, deadLetters[0].Callback.Signing.Secret, "")
+	retryURL := base + "/" + deadLetters[0].ID.String() + "/retry"
+
+	t.Run("failed retry keeps the dead letter", func(t *testing.T) {
+		w := serve(r, httptest.NewRequest(http.MethodPost, retryURL, nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+		list, err := dao.ListDeadLetters(context.Background(), inbox.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertLen(t, list, 1)
+	})
+	t.Run("successful retry removes the dead letter", func(t *testing.T) {
+		isDown.Store(false)
+		w := serve(r, httptest.NewRequest(http.MethodPost, retryURL, nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+		resp := model.CallbackResponse{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		t_util.AssertEquals(t, resp.Code, http.StatusOK)
+		w = serve(r, httptest.NewRequest(http.MethodPost, retryURL, nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusNotFound)
+	})
+	t.Run("delete dead letter", func(t *testing.T) {
+		dl, err := dao.PutDeadLetter(context.Background(), inbox.ID, model.DeadLetter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := serve(r, httptest.NewRequest(http.MethodDelete, base+"/"+dl.ID.String(), nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusNoContent)
+		w = serve(r, httptest.NewRequest(http.MethodDelete, base+"/"+dl.ID.String(), nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusNotFound)
+	})
+	t.Run("invalid dead letter ID", func(t *testing.T) {
+		w := serve(r, httptest.NewRequest(http.MethodPost, base+"/first/retry", nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusBadRequest)
+	})
+	t.Run("dead letter that does not exist", func(t *testing.T) {
+		w := serve(r, httptest.NewRequest(http.MethodPost, base+"/"+uuid.NewString()+"/retry", nil))
+		t_util.AssertStatusCode(t, w.Code, http.StatusNotFound)
+	})
+}
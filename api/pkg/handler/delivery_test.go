@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/delivery"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestRegisterInboxRequestAsyncCallbacks(t *testing.T) {
+	config.LoadConfig(config.Test)
+	config.Set(config.EnableCallbackURLValidation, false)
+	defer config.Set(config.EnableCallbackURLValidation, config.EnableCallbackURLValidationDefault)
+	ctx := context.Background()
+	dao, err := database.NewRepository(ctx, database.Badger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := dao.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	et, err := instrumentation.NewEventTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := stream.NewHub()
+	queue := delivery.NewWorkerPool(dao, hub, 1, 10)
+	ih := handler.NewInboxHandler(dao, et, hub, queue, nil)
+	r := gin.New()
+	r.Any("/:id/in", ih.RegisterInboxRequest)
+
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer target.Close()
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: http.StatusOK}, nil)
+	inbox.Callbacks = []model.Callback{{IsEnabled: true, ToURL: target.URL, Method: http.MethodPost}}
+	if _, err := dao.UpdateInbox(ctx, inbox); err != nil {
+		t.Fatal(err)
+	}
+	sub := hub.Subscribe(inbox.ID)
+	defer sub.Close()
+
+	// The callback is blocked, so the request is answered before it is delivered
+	w := serve(r, httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", strings.NewReader("event")))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	pending, err := dao.GetInboxRequest(ctx, inbox.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, pending.CallbacksPending, true)
+
+	close(release)
+	closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := queue.Close(closeCtx); err != nil {
+		t.Fatal(err)
+	}
+	delivered, err := dao.GetInboxRequest(ctx, inbox.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, delivered.CallbacksPending, false)
+	t_util.AssertLen(t, delivered.CallbackResponses, 1)
+	t_util.AssertEquals(t, delivered.CallbackResponses[0].Code, http.StatusAccepted)
+	// Streams receive the request when it arrives and again with its callback responses
+	published := <-sub.Events()
+	t_util.AssertEquals(t, published.CallbacksPending, true)
+	published = <-sub.Events()
+	t_util.AssertEquals(t, published.CallbacksPending, false)
+	t_util.AssertLen(t, published.CallbackResponses, 1)
+}
@@ -0,0 +1,184 @@
+package email
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
+	"github.com/jesusnoseq/request-inbox/pkg/login"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+type emailHandler struct {
+	dao database.Repository
+}
+
+func NewEmailHandler(dao database.Repository) EmailHandler {
+	return &emailHandler{
+		dao: dao,
+	}
+}
+
+// storedUser returns the logged user as stored, the user of the session does not carry the email preferences.
+func (h *emailHandler) storedUser(c *gin.Context) (model.User, bool) {
+	if !login.IsUserLoggedIn(c) {
+		c.AbortWithStatusJSON(model.NewUnauthorizedError())
+		return model.User{}, false
+	}
+	user, err := login.GetUser(c)
+	if err != nil {
+		instrumentation.LogError(c, err, "error getting user")
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("Could not retrieve user", err, http.StatusInternalServerError))
+		return model.User{}, false
+	}
+	stored, err := h.dao.GetUser(c.Request.Context(), user.ID)
+	if err != nil {
+		instrumentation.LogError(c, err, "error getting stored user")
+		c.AbortWithStatusJSON(model.NewNotFoundError(model.UserEntityName))
+		return model.User{}, false
+	}
+	return stored, true
+}
+
+func (h *emailHandler) GetEmailPreferences(c *gin.Context) {
+	user, ok := h.storedUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, user.EmailPreferences)
+}
+
+// UpdateEmailPreferences replaces the preferences of the logged user, saving them subscribes the user again.
+func (h *emailHandler) UpdateEmailPreferences(c *gin.Context) {
+	user, ok := h.storedUser(c)
+	if !ok {
+		return
+	}
+	prefs := model.EmailPreferences{}
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid email preferences", err, http.StatusBadRequest))
+		return
+	}
+	if valid, err := validation.IsValidEmailPreferences(prefs); !valid {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+		return
+	}
+	for _, a := range prefs.Alerts {
+		inbox, err := h.dao.GetInbox(c.Request.Context(), a.InboxID)
+		if err != nil || inbox.OwnerID != user.ID {
+			c.AbortWithStatusJSON(model.ErrorResponseMsg("email alerts can only watch your inboxes", http.StatusBadRequest))
+			return
+		}
+	}
+
+	prefs.UnsubscribeToken = user.EmailPreferences.UnsubscribeToken
+	if prefs.UnsubscribeToken == "" {
+		token, err := model.NewUnsubscribeToken()
+		if err != nil {
+			c.AbortWithStatusJSON(model.ErrorResponseWithError("Failed to generate unsubscribe token", err, http.StatusInternalServerError))
+			return
+		}
+		prefs.UnsubscribeToken = token
+	}
+	// The first digest covers the activity since the digest was enabled
+	prefs.LastDigest = user.EmailPreferences.LastDigest
+	if prefs.Digest != "" && prefs.LastDigest == 0 {
+		prefs.LastDigest = time.Now().UnixMilli()
+	}
+	user.EmailPreferences = prefs
+	if _, err := h.dao.UpsertUser(c.Request.Context(), user); err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("Failed to save email preferences", err, http.StatusInternalServerError))
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// unsubscribePage is shown by the unsubscribe link. Mail scanners and link previews open links, so the link
+// only asks for confirmation and the form posts to the same URL.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Request Inbox emails</title></head>
+<body>
+{{if .Unsubscribed}}<p>You will not receive more emails from Request Inbox.</p>
+{{else}}<form method="post" action="{{.Action}}">
+<p>Do you want to stop receiving emails from Request Inbox?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Action       string
+	Unsubscribed bool
+}
+
+// linkUser returns the user of the unsubscribe link, it does not need a session so it works from any mail client.
+func (h *emailHandler) linkUser(c *gin.Context) (model.User, bool) {
+	id, err := uuid.Parse(c.Query("user"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid user ID", err, http.StatusBadRequest))
+		return model.User{}, false
+	}
+	user, err := h.dao.GetUser(c.Request.Context(), id)
+	if err != nil {
+		c.AbortWithStatusJSON(model.NewNotFoundError(model.UserEntityName))
+		return model.User{}, false
+	}
+	token := user.EmailPreferences.UnsubscribeToken
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.Query("token"))) != 1 {
+		c.AbortWithStatusJSON(model.ErrorResponseMsg("invalid unsubscribe token", http.StatusForbidden))
+		return model.User{}, false
+	}
+	return user, true
+}
+
+// UnsubscribePage answers the unsubscribe link with a confirmation form, opening the link changes nothing.
+func (h *emailHandler) UnsubscribePage(c *gin.Context) {
+	user, ok := h.linkUser(c)
+	if !ok {
+		return
+	}
+	h.renderUnsubscribePage(c, unsubscribePageData{
+		Action:       c.Request.URL.RequestURI(),
+		Unsubscribed: user.EmailPreferences.Unsubscribed,
+	})
+}
+
+// Unsubscribe stops the emails of the user of the link. It answers the confirmation form and the one-click
+// unsubscribe of mail clients (RFC 8058).
+func (h *emailHandler) Unsubscribe(c *gin.Context) {
+	user, ok := h.linkUser(c)
+	if !ok {
+		return
+	}
+	if !user.EmailPreferences.Unsubscribed {
+		user.EmailPreferences.Unsubscribed = true
+		if _, err := h.dao.UpsertUser(c.Request.Context(), user); err != nil {
+			c.AbortWithStatusJSON(model.ErrorResponseWithError("Failed to unsubscribe", err, http.StatusInternalServerError))
+			return
+		}
+	}
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		h.renderUnsubscribePage(c, unsubscribePageData{Unsubscribed: true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You will not receive more emails from Request Inbox"})
+}
+
+func (h *emailHandler) renderUnsubscribePage(c *gin.Context, data unsubscribePageData) {
+	page := bytes.Buffer{}
+	if err := unsubscribePage.Execute(&page, data); err != nil {
+		instrumentation.LogError(c, err, "error rendering unsubscribe page")
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("Failed to render page", err, http.StatusInternalServerError))
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
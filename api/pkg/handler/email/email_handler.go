@@ -0,0 +1,12 @@
+package email
+
+import "github.com/gin-gonic/gin"
+
+//go:generate mockgen -destination=email_mock/email_mock.go -package=email_mock github.com/jesusnoseq/request-inbox/pkg/handler/email EmailHandler
+
+type EmailHandler interface {
+	GetEmailPreferences(c *gin.Context)
+	UpdateEmailPreferences(c *gin.Context)
+	UnsubscribePage(c *gin.Context)
+	Unsubscribe(c *gin.Context)
+}
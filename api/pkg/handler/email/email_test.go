@@ -0,0 +1,215 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/login"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func mustGetEmailHandler(t *testing.T) (EmailHandler, database.Repository) {
+	t.Helper()
+	ctx := context.Background()
+	dao, err := database.NewRepository(ctx, database.Badger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := dao.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	return NewEmailHandler(dao), dao
+}
+
+func mustCreateUser(t *testing.T, dao database.Repository, email string) model.User {
+	t.Helper()
+	user := model.NewUser(email)
+	if _, err := dao.UpsertUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func newContext(t *testing.T, method, target string, body any, user *model.User) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(w)
+	var payload []byte
+	if body != nil {
+		payload = t_util.MustJson(t, body)
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ginCtx.Request = req
+	if user != nil {
+		ginCtx.Set(login.USER_CONTEXT_KEY, *user)
+		ginCtx.Set(login.IS_LOGGED_IN_CONTEXT_KEY, true)
+		ginCtx.Set(login.IS_LOGGED_WITH_COOKIE_CONTEXT_KEY, true)
+	}
+	return ginCtx, w
+}
+
+func mustCreateOwnedInbox(t *testing.T, dao database.Repository, owner model.User) model.Inbox {
+	t.Helper()
+	inbox := model.GenerateInbox()
+	inbox.OwnerID = owner.ID
+	inbox, err := dao.CreateInbox(context.Background(), inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inbox
+}
+
+func TestUpdateEmailPreferences(t *testing.T) {
+	config.LoadConfig(config.Test)
+	handler, dao := mustGetEmailHandler(t)
+	user := mustCreateUser(t, dao, "owner@mail.dev")
+	inbox := mustCreateOwnedInbox(t, dao, user)
+	prefs := model.EmailPreferences{
+		Digest: model.EmailDigestDaily,
+		Alerts: []model.EmailAlert{{InboxID: inbox.ID, Method: http.MethodPost}},
+	}
+	ginCtx, w := newContext(t, http.MethodPut, "", prefs, &user)
+
+	handler.UpdateEmailPreferences(ginCtx)
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	stored, err := dao.GetUser(context.Background(), user.ID)
+	t_util.AssertNoError(t, err)
+	t_util.AssertStringEquals(t, stored.EmailPreferences.Digest, model.EmailDigestDaily)
+	t_util.AssertLen(t, stored.EmailPreferences.Alerts, 1)
+	t_util.AssertEquals(t, len(stored.EmailPreferences.UnsubscribeToken), 32)
+	t_util.AssertTrue(t, stored.EmailPreferences.LastDigest > 0, "digest period started")
+
+	ginCtx, w = newContext(t, http.MethodGet, "", nil, &user)
+	handler.GetEmailPreferences(ginCtx)
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	t_util.AssertFalse(t, bytes.Contains(w.Body.Bytes(), []byte(stored.EmailPreferences.UnsubscribeToken)), "token is not exposed")
+}
+
+func TestUpdateEmailPreferencesKeepsUnsubscribeToken(t *testing.T) {
+	config.LoadConfig(config.Test)
+	handler, dao := mustGetEmailHandler(t)
+	user := mustCreateUser(t, dao, "owner@mail.dev")
+	user.EmailPreferences = model.EmailPreferences{UnsubscribeToken: "token", Unsubscribed: true}
+	if _, err := dao.UpsertUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	ginCtx, w := newContext(t, http.MethodPut, "", model.EmailPreferences{UnsubscribeToken: "other"}, &user)
+
+	handler.UpdateEmailPreferences(ginCtx)
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	stored, err := dao.GetUser(context.Background(), user.ID)
+	t_util.AssertNoError(t, err)
+	t_util.AssertStringEquals(t, stored.EmailPreferences.UnsubscribeToken, "token")
+	t_util.AssertFalse(t, stored.EmailPreferences.Unsubscribed, "saving subscribes again")
+}
+
+func TestUpdateEmailPreferencesErrors(t *testing.T) {
+	config.LoadConfig(config.Test)
+	handler, dao := mustGetEmailHandler(t)
+	user := mustCreateUser(t, dao, "owner@mail.dev")
+	other := mustCreateUser(t, dao, "other@mail.dev")
+	othersInbox := mustCreateOwnedInbox(t, dao, other)
+
+	testCases := []struct {
+		desc           string
+		body           any
+		user           *model.User
+		expectedStatus int
+	}{
+		{"not logged", model.EmailPreferences{}, nil, http.StatusUnauthorized},
+		{"invalid digest", model.EmailPreferences{Digest: "weekly"}, &user, http.StatusBadRequest},
+		{"inbox of another user", model.EmailPreferences{Alerts: []model.EmailAlert{{InboxID: othersInbox.ID}}}, &user, http.StatusBadRequest},
+		{"invalid body", "preferences", &user, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ginCtx, w := newContext(t, http.MethodPut, "", tc.body, tc.user)
+
+			handler.UpdateEmailPreferences(ginCtx)
+
+			t_util.AssertStatusCode(t, w.Code, tc.expectedStatus)
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	config.LoadConfig(config.Test)
+	handler, dao := mustGetEmailHandler(t)
+	user := mustCreateUser(t, dao, "owner@mail.dev")
+	user.EmailPreferences = model.EmailPreferences{UnsubscribeToken: "secret-token", Digest: model.EmailDigestHourly}
+	if _, err := dao.UpsertUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc           string
+		userID         string
+		token          string
+		expectedStatus int
+	}{
+		{"invalid user ID", "abc", "secret-token", http.StatusBadRequest},
+		{"unknown user", model.NewUser("nobody@mail.dev").ID.String(), "secret-token", http.StatusNotFound},
+		{"wrong token", user.ID.String(), "guess", http.StatusForbidden},
+		{"valid link", user.ID.String(), "secret-token", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			query := url.Values{"user": {tc.userID}, "token": {tc.token}}
+			ginCtx, w := newContext(t, http.MethodPost, "/?"+query.Encode(), nil, nil)
+
+			handler.Unsubscribe(ginCtx)
+
+			t_util.AssertStatusCode(t, w.Code, tc.expectedStatus)
+		})
+	}
+
+	stored, err := dao.GetUser(context.Background(), user.ID)
+	t_util.AssertNoError(t, err)
+	t_util.AssertTrue(t, stored.EmailPreferences.Unsubscribed, "user unsubscribed")
+	t_util.AssertStringEquals(t, stored.EmailPreferences.Digest, model.EmailDigestHourly)
+}
+
+func TestUnsubscribePageDoesNotUnsubscribe(t *testing.T) {
+	config.LoadConfig(config.Test)
+	handler, dao := mustGetEmailHandler(t)
+	user := mustCreateUser(t, dao, "owner@mail.dev")
+	user.EmailPreferences = model.EmailPreferences{UnsubscribeToken: "secret-token"}
+	if _, err := dao.UpsertUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	query := url.Values{"user": {user.ID.String()}, "token": {"secret-token"}}
+	ginCtx, w := newContext(t, http.MethodGet, "/api/v1/email/unsubscribe?"+query.Encode(), nil, nil)
+
+	handler.UnsubscribePage(ginCtx)
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	t_util.AssertStringContains(t, w.Body.String(), `method="post"`)
+	t_util.AssertStringContains(t, w.Body.String(), `action="/api/v1/email/unsubscribe?token=secret-token&amp;user=`+user.ID.String()+`"`)
+	stored, err := dao.GetUser(context.Background(), user.ID)
+	t_util.AssertNoError(t, err)
+	t_util.AssertFalse(t, stored.EmailPreferences.Unsubscribed, "opening the link keeps the user subscribed")
+}
+
+func TestEmailPreferencesAreNotInUserJSON(t *testing.T) {
+	user := model.NewUser("owner@mail.dev")
+	user.EmailPreferences.UnsubscribeToken = "secret-token"
+	payload, err := json.Marshal(user)
+	t_util.AssertNoError(t, err)
+	t_util.AssertFalse(t, bytes.Contains(payload, []byte("secret-token")), "preferences are kept out of the JWT")
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/export"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// ExportInboxStubs downloads the captured requests of the inbox as stubs of a mock server.
+// The format query parameter chooses the mock server, wiremock by default.
+func (ih *inboxHandler) ExportInboxStubs(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+	format := c.DefaultQuery("format", export.FormatWireMock)
+
+	inbox, err := ih.dao.GetInboxWithRequests(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error exporting inbox stubs", "error", err)
+		return
+	}
+
+	stubs, err := export.Export(c, inbox, format)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid stub format "+format, err, http.StatusBadRequest))
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\"inbox-"+id.String()+"-"+format+".json\"")
+	c.JSON(http.StatusOK, stubs)
+}
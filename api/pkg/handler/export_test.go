@@ -0,0 +1,62 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestExportInboxStubs(t *testing.T) {
+	config.LoadConfig(config.Test)
+	ctx := context.Background()
+	dao, err := database.NewRepository(ctx, database.Badger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dao.Close(ctx) }()
+	et, err := instrumentation.NewEventTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ih := handler.NewInboxHandler(dao, et, stream.NewHub(), nil, nil)
+	r := gin.New()
+	r.Any("/:id/in/*path", ih.RegisterInboxRequest)
+	r.GET("/:id/export/stubs", ih.ExportInboxStubs)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	serve(r, httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in/orders", strings.NewReader(`{"id":1}`)))
+
+	testCases := []struct {
+		desc     string
+		format   string
+		code     int
+		contains string
+	}{
+		{"default format", "", http.StatusOK, `"urlPath":"/orders"`},
+		{"wiremock", "wiremock", http.StatusOK, `"bodyPatterns":[{"equalToJson":{"id":1}}]`},
+		{"mockserver", "mockserver", http.StatusOK, `"path":"/orders"`},
+		{"prism", "prism", http.StatusOK, `"openapi":"3.0.3"`},
+		{"unsupported format", "postman", http.StatusBadRequest, "invalid stub format"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			url := "/" + inbox.ID.String() + "/export/stubs"
+			if tc.format != "" {
+				url += "?format=" + tc.format
+			}
+			w := serve(r, httptest.NewRequest(http.MethodGet, url, nil))
+
+			t_util.AssertStatusCode(t, w.Code, tc.code)
+			t_util.AssertStringContains(t, w.Body.String(), tc.contains)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	defaultFileContentType = "application/octet-stream"
+	// BodyTruncatedHeader tells that the downloaded body is only the beginning of the received one
+	BodyTruncatedHeader = "X-Body-Truncated"
+)
+
+func parseRequestID(c *gin.Context) (int, error) {
+	return strconv.Atoi(c.Param("requestId"))
+}
+
+// GetInboxRequestBody downloads the body of a request as it was received, with its Content-Type.
+// The decoded query parameter downloads the body decompressed following its Content-Encoding.
+func (ih *inboxHandler) GetInboxRequestBody(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+	requestID, err := parseRequestID(c)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid request ID", err, http.StatusBadRequest))
+		return
+	}
+	decoded := false
+	if value := c.Query("decoded"); value != "" {
+		decoded, err = strconv.ParseBool(value)
+		if err != nil {
+			c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid decoded parameter", err, http.StatusBadRequest))
+			return
+		}
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error getting request body", "error", err)
+		return
+	}
+
+	req, err := ih.dao.GetInboxRequest(c, id, requestID)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	b, err := req.RawBody()
+	truncated := req.BodyTruncated
+	if decoded {
+		b, err = req.DecodedBodyBytes()
+		truncated = truncated || req.DecodedBodyTruncated
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	contentType := http.Header(req.Headers).Get(model.ContentTypeHeader)
+	if contentType == "" {
+		contentType = defaultFileContentType
+	}
+	c.Header(BodyTruncatedHeader, strconv.FormatBool(truncated))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": bodyFilename(req.ID, contentType)}))
+	c.Data(http.StatusOK, contentType, b)
+}
+
+// bodyFilename names the body download after the request with the extension of its content type.
+func bodyFilename(requestID int, contentType string) string {
+	ext := ".bin"
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return "request-" + strconv.Itoa(requestID) + "-body" + ext
+}
+
+// GetInboxRequestFile downloads the content of a file received in a multipart request.
+// The index is the position of the file in the request form files.
+func (ih *inboxHandler) GetInboxRequestFile(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+	requestID, err := parseRequestID(c)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid request ID", err, http.StatusBadRequest))
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid file index", err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error getting request file", "error", err)
+		return
+	}
+
+	file, err := ih.dao.GetRequestFile(c, id, requestID, index)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = defaultFileContentType
+	}
+	// Files are sent by anybody, browsers must not render them as API content
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	c.Data(http.StatusOK, contentType, file.Content)
+}
@@ -0,0 +1,185 @@
+package handler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func mustGetFilesRouter(t *testing.T) (database.Repository, *gin.Engine) {
+	config.LoadConfig(config.Test)
+	dao, _, r := mustGetRouter(t, func(r *gin.Engine, ih handler.InboxService) {
+		r.Any("/:id/in", ih.RegisterInboxRequest)
+		r.GET("/:id/requests/:requestId/body", ih.GetInboxRequestBody)
+		r.GET("/:id/requests/:requestId/files/:index", ih.GetInboxRequestFile)
+	})
+	return dao, r
+}
+
+func serve(r *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterInboxRequestForm(t *testing.T) {
+	dao, r := mustGetFilesRouter(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+
+	req := httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", strings.NewReader("name=inbox&tag=a&tag=b"))
+	req.Header.Set(model.ContentTypeHeader, "application/x-www-form-urlencoded")
+	w := serve(r, req)
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+
+	got, err := dao.GetInboxWithRequests(context.Background(), inbox.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Requests) != 1 || got.Requests[0].Form == nil {
+		t.Fatalf("Expected a request with form, got %+v", got.Requests)
+	}
+	form := got.Requests[0].Form
+	t_util.AssertStringEquals(t, strings.Join(form.Fields["tag"], ","), "a,b")
+	t_util.AssertLen(t, form.Files, 0)
+}
+
+func TestGetInboxRequestFile(t *testing.T) {
+	dao, r := mustGetFilesRouter(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+
+	content := []byte{0x89, 0x50, 0x4e, 0x47, 0x00}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("description", "a picture"); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := mw.CreateFormFile("picture", "picture.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", &buf)
+	req.Header.Set(model.ContentTypeHeader, mw.FormDataContentType())
+	w := serve(r, req)
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+
+	got, err := dao.GetInboxWithRequests(context.Background(), inbox.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Requests) != 1 || got.Requests[0].Form == nil {
+		t.Fatalf("Expected a request with form, got %+v", got.Requests)
+	}
+	form := got.Requests[0].Form
+	t_util.AssertStringEquals(t, form.Fields["description"][0], "a picture")
+	t_util.AssertLen(t, form.Files, 1)
+	t_util.AssertStringEquals(t, form.Files[0].Filename, "picture.png")
+	t_util.AssertEquals(t, form.Files[0].Size, int64(len(content)))
+
+	testCases := []struct {
+		desc         string
+		path         string
+		expectedCode int
+	}{
+		{"download file", "/requests/0/files/0", http.StatusOK},
+		{"file index out of range", "/requests/0/files/1", http.StatusNotFound},
+		{"request without files", "/requests/1/files/0", http.StatusNotFound},
+		{"invalid request ID", "/requests/abc/files/0", http.StatusBadRequest},
+		{"invalid file index", "/requests/0/files/abc", http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+tc.path, nil))
+			t_util.AssertStatusCode(t, w.Code, tc.expectedCode)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			if !bytes.Equal(w.Body.Bytes(), content) {
+				t.Errorf("Expected file content %v, got %v", content, w.Body.Bytes())
+			}
+			t_util.AssertStringEquals(t, w.Header().Get("Content-Type"), "application/octet-stream")
+			t_util.AssertStringEquals(t, w.Header().Get("Content-Disposition"), `attachment; filename=picture.png`)
+		})
+	}
+}
+
+func TestGetInboxRequestFileDeletedWithRequests(t *testing.T) {
+	dao, r := mustGetFilesRouter(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	file := model.RequestFile{FormFile: model.FormFile{Filename: "a.txt"}, Content: []byte("a")}
+	_, err := dao.AddRequestToInbox(context.Background(), inbox.ID, model.GenerateRequest(0), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/requests/0/files/0", nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+
+	if err := dao.DeleteInboxRequests(context.Background(), inbox.ID); err != nil {
+		t.Fatal(err)
+	}
+	w = serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/requests/0/files/0", nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusNotFound)
+}
+
+func TestGetInboxRequestBody(t *testing.T) {
+	dao, r := mustGetFilesRouter(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	if _, err := gw.Write([]byte(`{"compressed":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", bytes.NewReader(gzipped.Bytes()))
+	req.Header.Set(model.ContentTypeHeader, "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	t_util.AssertStatusCode(t, serve(r, req).Code, http.StatusOK)
+
+	testCases := []struct {
+		desc         string
+		path         string
+		expectedCode int
+		expectedBody []byte
+	}{
+		{"download raw body", "/requests/0/body", http.StatusOK, gzipped.Bytes()},
+		{"download decoded body", "/requests/0/body?decoded=true", http.StatusOK, []byte(`{"compressed":true}`)},
+		{"invalid decoded parameter", "/requests/0/body?decoded=maybe", http.StatusBadRequest, nil},
+		{"request not found", "/requests/1/body", http.StatusNotFound, nil},
+		{"invalid request ID", "/requests/abc/body", http.StatusBadRequest, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+tc.path, nil))
+			t_util.AssertStatusCode(t, w.Code, tc.expectedCode)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			if !bytes.Equal(w.Body.Bytes(), tc.expectedBody) {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, w.Body.Bytes())
+			}
+			t_util.AssertStringEquals(t, w.Header().Get("Content-Type"), "application/json")
+			t_util.AssertStringEquals(t, w.Header().Get("Content-Disposition"), "attachment; filename=request-0-body.json")
+			t_util.AssertStringEquals(t, w.Header().Get(handler.BodyTruncatedHeader), "false")
+		})
+	}
+}
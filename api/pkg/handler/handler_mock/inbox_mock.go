@@ -46,6 +46,18 @@ func (mr *MockInboxServiceMockRecorder) CreateInbox(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInbox", reflect.TypeOf((*MockInboxService)(nil).CreateInbox), arg0)
 }
 
+// DeleteDeadLetter mocks base method.
+func (m *MockInboxService) DeleteDeadLetter(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteDeadLetter", arg0)
+}
+
+// DeleteDeadLetter indicates an expected call of DeleteDeadLetter.
+func (mr *MockInboxServiceMockRecorder) DeleteDeadLetter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDeadLetter", reflect.TypeOf((*MockInboxService)(nil).DeleteDeadLetter), arg0)
+}
+
 // DeleteInbox mocks base method.
 func (m *MockInboxService) DeleteInbox(arg0 *gin.Context) {
 	m.ctrl.T.Helper()
@@ -70,6 +82,18 @@ func (mr *MockInboxServiceMockRecorder) DeleteInboxRequests(arg0 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteInboxRequests", reflect.TypeOf((*MockInboxService)(nil).DeleteInboxRequests), arg0)
 }
 
+// ExportInboxStubs mocks base method.
+func (m *MockInboxService) ExportInboxStubs(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExportInboxStubs", arg0)
+}
+
+// ExportInboxStubs indicates an expected call of ExportInboxStubs.
+func (mr *MockInboxServiceMockRecorder) ExportInboxStubs(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportInboxStubs", reflect.TypeOf((*MockInboxService)(nil).ExportInboxStubs), arg0)
+}
+
 // GetInbox mocks base method.
 func (m *MockInboxService) GetInbox(arg0 *gin.Context) {
 	m.ctrl.T.Helper()
@@ -82,6 +106,42 @@ func (mr *MockInboxServiceMockRecorder) GetInbox(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInbox", reflect.TypeOf((*MockInboxService)(nil).GetInbox), arg0)
 }
 
+// GetInboxRequestBody mocks base method.
+func (m *MockInboxService) GetInboxRequestBody(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetInboxRequestBody", arg0)
+}
+
+// GetInboxRequestBody indicates an expected call of GetInboxRequestBody.
+func (mr *MockInboxServiceMockRecorder) GetInboxRequestBody(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInboxRequestBody", reflect.TypeOf((*MockInboxService)(nil).GetInboxRequestBody), arg0)
+}
+
+// GetInboxRequestFile mocks base method.
+func (m *MockInboxService) GetInboxRequestFile(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetInboxRequestFile", arg0)
+}
+
+// GetInboxRequestFile indicates an expected call of GetInboxRequestFile.
+func (mr *MockInboxServiceMockRecorder) GetInboxRequestFile(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInboxRequestFile", reflect.TypeOf((*MockInboxService)(nil).GetInboxRequestFile), arg0)
+}
+
+// ListDeadLetters mocks base method.
+func (m *MockInboxService) ListDeadLetters(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListDeadLetters", arg0)
+}
+
+// ListDeadLetters indicates an expected call of ListDeadLetters.
+func (mr *MockInboxServiceMockRecorder) ListDeadLetters(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadLetters", reflect.TypeOf((*MockInboxService)(nil).ListDeadLetters), arg0)
+}
+
 // ListInbox mocks base method.
 func (m *MockInboxService) ListInbox(arg0 *gin.Context) {
 	m.ctrl.T.Helper()
@@ -94,6 +154,18 @@ func (mr *MockInboxServiceMockRecorder) ListInbox(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInbox", reflect.TypeOf((*MockInboxService)(nil).ListInbox), arg0)
 }
 
+// ListInboxRequests mocks base method.
+func (m *MockInboxService) ListInboxRequests(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListInboxRequests", arg0)
+}
+
+// ListInboxRequests indicates an expected call of ListInboxRequests.
+func (mr *MockInboxServiceMockRecorder) ListInboxRequests(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInboxRequests", reflect.TypeOf((*MockInboxService)(nil).ListInboxRequests), arg0)
+}
+
 // RegisterInboxRequest mocks base method.
 func (m *MockInboxService) RegisterInboxRequest(arg0 *gin.Context) {
 	m.ctrl.T.Helper()
@@ -106,6 +178,54 @@ func (mr *MockInboxServiceMockRecorder) RegisterInboxRequest(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterInboxRequest", reflect.TypeOf((*MockInboxService)(nil).RegisterInboxRequest), arg0)
 }
 
+// ReplayInboxRequest mocks base method.
+func (m *MockInboxService) ReplayInboxRequest(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReplayInboxRequest", arg0)
+}
+
+// ReplayInboxRequest indicates an expected call of ReplayInboxRequest.
+func (mr *MockInboxServiceMockRecorder) ReplayInboxRequest(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayInboxRequest", reflect.TypeOf((*MockInboxService)(nil).ReplayInboxRequest), arg0)
+}
+
+// ResetInboxScenario mocks base method.
+func (m *MockInboxService) ResetInboxScenario(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetInboxScenario", arg0)
+}
+
+// ResetInboxScenario indicates an expected call of ResetInboxScenario.
+func (mr *MockInboxServiceMockRecorder) ResetInboxScenario(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetInboxScenario", reflect.TypeOf((*MockInboxService)(nil).ResetInboxScenario), arg0)
+}
+
+// RetryDeadLetter mocks base method.
+func (m *MockInboxService) RetryDeadLetter(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RetryDeadLetter", arg0)
+}
+
+// RetryDeadLetter indicates an expected call of RetryDeadLetter.
+func (mr *MockInboxServiceMockRecorder) RetryDeadLetter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryDeadLetter", reflect.TypeOf((*MockInboxService)(nil).RetryDeadLetter), arg0)
+}
+
+// StreamInboxRequests mocks base method.
+func (m *MockInboxService) StreamInboxRequests(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StreamInboxRequests", arg0)
+}
+
+// StreamInboxRequests indicates an expected call of StreamInboxRequests.
+func (mr *MockInboxServiceMockRecorder) StreamInboxRequests(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamInboxRequests", reflect.TypeOf((*MockInboxService)(nil).StreamInboxRequests), arg0)
+}
+
 // UpdateInbox mocks base method.
 func (m *MockInboxService) UpdateInbox(arg0 *gin.Context) {
 	m.ctrl.T.Helper()
@@ -118,6 +238,42 @@ func (mr *MockInboxServiceMockRecorder) UpdateInbox(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInbox", reflect.TypeOf((*MockInboxService)(nil).UpdateInbox), arg0)
 }
 
+// VerifyRequests mocks base method.
+func (m *MockInboxService) VerifyRequests(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "VerifyRequests", arg0)
+}
+
+// VerifyRequests indicates an expected call of VerifyRequests.
+func (mr *MockInboxServiceMockRecorder) VerifyRequests(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyRequests", reflect.TypeOf((*MockInboxService)(nil).VerifyRequests), arg0)
+}
+
+// WaitForRequests mocks base method.
+func (m *MockInboxService) WaitForRequests(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "WaitForRequests", arg0)
+}
+
+// WaitForRequests indicates an expected call of WaitForRequests.
+func (mr *MockInboxServiceMockRecorder) WaitForRequests(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForRequests", reflect.TypeOf((*MockInboxService)(nil).WaitForRequests), arg0)
+}
+
+// WatchInboxRequests mocks base method.
+func (m *MockInboxService) WatchInboxRequests(arg0 *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "WatchInboxRequests", arg0)
+}
+
+// WatchInboxRequests indicates an expected call of WatchInboxRequests.
+func (mr *MockInboxServiceMockRecorder) WatchInboxRequests(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchInboxRequests", reflect.TypeOf((*MockInboxService)(nil).WatchInboxRequests), arg0)
+}
+
 // MockHealthHandler is a mock of HealthHandler interface.
 type MockHealthHandler struct {
 	ctrl     *gomock.Controller
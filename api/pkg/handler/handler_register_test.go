@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
 	"github.com/jesusnoseq/request-inbox/pkg/login"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
 	"github.com/jesusnoseq/request-inbox/pkg/t_util"
 )
 
@@ -94,6 +96,30 @@ func TestRegisterInboxRequest(t *testing.T) {
 			wantStatus:   http.StatusOK,
 			wantResponse: true,
 		},
+		{
+			name:          "register request matching a response rule",
+			inbox:         model.GenerateInbox(),
+			requestBody:   `{"rule": {"enabled": true}}`,
+			requestMethod: "PUT",
+			requestHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			requestURI:   "/api/v1/inboxes/id/in/rules/1?rule",
+			wantStatus:   http.StatusCreated,
+			wantResponse: true,
+		},
+		{
+			name:          "register request not matching any response rule",
+			inbox:         model.GenerateInbox(),
+			requestBody:   `{"rule": {"enabled": false}}`,
+			requestMethod: "PUT",
+			requestHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			requestURI:   "/api/v1/inboxes/id/in/rules/1?rule",
+			wantStatus:   http.StatusOK,
+			wantResponse: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +212,9 @@ func TestRegisterInboxRequest(t *testing.T) {
 
 				// Verify ID is incremented
 				expectedID := len(createdInbox.Requests)
+				if expectedID > 0 {
+					expectedID = createdInbox.Requests[expectedID-1].ID + 1
+				}
 				if lastRequest.ID != expectedID {
 					t.Errorf("Expected ID %d, got %d", expectedID, lastRequest.ID)
 				}
@@ -302,6 +331,77 @@ func TestRegisterInboxRequestFiltersAuthCookies(t *testing.T) {
 
 // Helper functions
 
+func TestRegisterInboxRequestBody(t *testing.T) {
+	config.LoadConfig(config.Test)
+	config.Set(config.RequestBodyMaxBytes, 64)
+	defer config.Set(config.RequestBodyMaxBytes, config.RequestBodyMaxBytesDefault)
+
+	text := `{"message":"hello"}`
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	if _, err := gw.Write([]byte(text)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		body            []byte
+		contentEncoding string
+		wantEncoding    string
+		wantTruncated   bool
+		wantDecodedBody string
+	}{
+		{"text body", []byte(text), "", model.BodyEncodingUTF8, false, ""},
+		{"binary body", []byte{0x00, 0xff, 0xfe}, "", model.BodyEncodingBase64, false, ""},
+		{"gzip body", gzipped.Bytes(), "gzip", model.BodyEncodingBase64, false, text},
+		{"body over the limit", bytes.Repeat([]byte("a"), 100), "", model.BodyEncodingUTF8, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ih, closer := mustGetInboxHandler()
+			defer closer()
+			inbox := model.GenerateInbox()
+			inbox.Callbacks = []model.Callback{}
+			createdInbox := shouldExistInbox(t, ih, inbox)
+
+			w := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(w)
+			ginCtx.AddParam("id", createdInbox.ID.String())
+			req, err := http.NewRequest(http.MethodPost, "/test", bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			req.RequestURI = "/test"
+			ginCtx.Request = req
+
+			ih.RegisterInboxRequest(ginCtx)
+
+			updatedInbox := getInbox(t, ih, createdInbox.ID)
+			if len(updatedInbox.Requests) != 1 {
+				t.Fatalf("Expected 1 request, got %d", len(updatedInbox.Requests))
+			}
+			got := updatedInbox.Requests[0]
+			t_util.AssertStringEquals(t, got.BodyEncoding, tt.wantEncoding)
+			t_util.AssertEquals(t, got.BodySize, int64(len(tt.body)))
+			t_util.AssertEquals(t, got.BodyTruncated, tt.wantTruncated)
+			t_util.AssertStringEquals(t, got.DecodedBody, tt.wantDecodedBody)
+			raw, err := got.RawBody()
+			if err != nil {
+				t.Fatalf("Expected a valid raw body, got %v", err)
+			}
+			if !bytes.HasPrefix(tt.body, raw) {
+				t.Errorf("Expected raw body %v to be a prefix of %v", raw, tt.body)
+			}
+		})
+	}
+}
+
 func mustCloseBody(t *testing.T, resp *http.Response) {
 	if err := resp.Body.Close(); err != nil {
 		t.Fatalf("Failed to close response body: %v", err)
@@ -318,7 +418,7 @@ func mustGetInboxHandler() (InboxService, func()) {
 	if err != nil {
 		panic(err)
 	}
-	return NewInboxHandler(dao, et), func() {
+	return NewInboxHandler(dao, et, stream.NewHub(), nil, nil), func() {
 		err := dao.Close(ctx)
 		if err != nil {
 			panic(err)
@@ -5,32 +5,50 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/body"
 	"github.com/jesusnoseq/request-inbox/pkg/callback"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
 	"github.com/jesusnoseq/request-inbox/pkg/database"
 	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
+	"github.com/jesusnoseq/request-inbox/pkg/delivery"
 	"github.com/jesusnoseq/request-inbox/pkg/dynamic_response"
+	"github.com/jesusnoseq/request-inbox/pkg/fault"
 	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
 	"github.com/jesusnoseq/request-inbox/pkg/instrumentation/event"
 	"github.com/jesusnoseq/request-inbox/pkg/login"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
 	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+	"github.com/jesusnoseq/request-inbox/pkg/notify"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
 )
 
+// ServerWriteTimeout is the write timeout of the HTTP server, responses that take longer on purpose extend it.
+const ServerWriteTimeout = 10 * time.Second
+
 type inboxHandler struct {
 	dao database.Repository
 	et  event.EventTracker
+	hub *stream.Hub
+	// queue delivers the callbacks after answering, they are sent before answering when it is nil
+	queue delivery.Queue
+	// notifier sends the chat notifications of the inboxes, they are disabled when it is nil
+	notifier *notify.Notifier
 }
 
-func NewInboxHandler(dao database.Repository, et event.EventTracker) InboxService {
+func NewInboxHandler(dao database.Repository, et event.EventTracker, hub *stream.Hub, queue delivery.Queue, notifier *notify.Notifier) InboxService {
 	return &inboxHandler{
-		dao: dao,
-		et:  et,
+		dao:      dao,
+		et:       et,
+		hub:      hub,
+		queue:    queue,
+		notifier: notifier,
 	}
 }
 
@@ -83,7 +101,7 @@ func (ih *inboxHandler) CreateInbox(c *gin.Context) {
 		instrumentation.LogError(c, err, "Failed to track create inbox event")
 	}
 
-	c.JSON(http.StatusCreated, inbox)
+	c.JSON(http.StatusCreated, inbox.WithoutSecrets())
 }
 
 func (ih *inboxHandler) DeleteInbox(c *gin.Context) {
@@ -185,7 +203,49 @@ func (ih *inboxHandler) GetInbox(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, inbox)
+	c.JSON(http.StatusOK, inbox.WithoutSecrets())
+}
+
+func (ih *inboxHandler) ListInboxRequests(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+
+	opts, err := parseListRequestsOptions(c)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error listing requests of inbox", "error", err)
+		return
+	}
+
+	page, err := ih.dao.ListInboxRequests(c, id, opts...)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrInvalidCursor) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 func (ih *inboxHandler) UpdateInbox(c *gin.Context) {
@@ -200,11 +260,6 @@ func (ih *inboxHandler) UpdateInbox(c *gin.Context) {
 		return
 	}
 
-	if valid, err := validation.IsValidInbox(updatedInbox); !valid {
-		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
-		return
-	}
-
 	if updatedInbox.IsPrivate && updatedInbox.OwnerID == uuid.Nil {
 		c.AbortWithStatusJSON(model.ErrorResponseMsg("An anonymous inbox can not be private", http.StatusBadRequest))
 		return
@@ -230,6 +285,12 @@ func (ih *inboxHandler) UpdateInbox(c *gin.Context) {
 		return
 	}
 
+	updatedInbox.KeepSecrets(inbox)
+	if valid, err := validation.IsValidInbox(updatedInbox); !valid {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+		return
+	}
+
 	updatedInbox.ID = id
 	updatedInbox.Timestamp = inbox.Timestamp
 	updatedInbox.Requests = inbox.Requests
@@ -239,7 +300,7 @@ func (ih *inboxHandler) UpdateInbox(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedInbox)
+	c.JSON(http.StatusOK, updatedInbox.WithoutSecrets())
 }
 
 func (ih *inboxHandler) ListInbox(c *gin.Context) {
@@ -254,7 +315,7 @@ func (ih *inboxHandler) ListInbox(c *gin.Context) {
 			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
 			return
 		}
-		c.JSON(http.StatusOK, model.NewItemList(inboxes))
+		c.JSON(http.StatusOK, model.NewItemList(withoutSecrets(inboxes)))
 		return
 	}
 
@@ -268,7 +329,15 @@ func (ih *inboxHandler) ListInbox(c *gin.Context) {
 		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
 		return
 	}
-	c.JSON(http.StatusOK, model.NewItemList(inboxes))
+	c.JSON(http.StatusOK, model.NewItemList(withoutSecrets(inboxes)))
+}
+
+func withoutSecrets(inboxes []model.Inbox) []model.Inbox {
+	redacted := make([]model.Inbox, len(inboxes))
+	for i, inbox := range inboxes {
+		redacted[i] = inbox.WithoutSecrets()
+	}
+	return redacted
 }
 
 func (ih *inboxHandler) RegisterInboxRequest(c *gin.Context) {
@@ -278,7 +347,7 @@ func (ih *inboxHandler) RegisterInboxRequest(c *gin.Context) {
 		return
 	}
 
-	inbox, err := ih.dao.GetInboxWithRequests(c, id)
+	inbox, err := ih.dao.GetInbox(c, id)
 	if err != nil {
 		if errors.Is(err, dberrors.ErrItemNotFound) {
 			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
@@ -292,14 +361,7 @@ func (ih *inboxHandler) RegisterInboxRequest(c *gin.Context) {
 		return
 	}
 
-	body, err := c.GetRawData()
-	if err != nil {
-		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
-		return
-	}
-
 	request := model.Request{
-		ID:            len(inbox.Requests),
 		Timestamp:     time.Now().UnixMilli(),
 		URI:           c.Request.RequestURI,
 		Headers:       c.Request.Header,
@@ -308,12 +370,60 @@ func (ih *inboxHandler) RegisterInboxRequest(c *gin.Context) {
 		RemoteAddr:    c.Request.RemoteAddr,
 		Protocol:      c.Request.Proto,
 		ContentLength: c.Request.ContentLength,
-		Body:          string(body),
 	}
+	maxBodySize := int64(config.GetInt(config.RequestBodyMaxBytes))
+	err = body.Read(&request, c.Request.Body, c.GetHeader("Content-Encoding"), maxBodySize)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	// Signatures are checked before the headers are obfuscated
+	if inbox.Verification.IsEnabled {
+		request.Verification = verifyRequest(inbox.Verification, c.Request.Header, request)
+	}
+	files := parseRequestForm(&request, c.GetHeader(model.ContentTypeHeader))
 	filterRequestData(&request)
 
-	request.CallbackResponses = callback.SendCallbacks(c, inbox, request)
-	err = ih.dao.AddRequestToInbox(c, id, request)
+	rejected := isRejected(inbox.Verification, request.Verification)
+	if inbox.Proxy.IsEnabled && !rejected {
+		proxyResponse := callback.SendProxy(inbox, request)
+		request.ProxyResponse = &proxyResponse
+	}
+	var deadLetters []model.DeadLetter
+	switch {
+	case rejected:
+		request.CallbackResponses = []model.CallbackResponse{}
+	case ih.queue != nil && len(inbox.Callbacks) > 0:
+		request.CallbacksPending = true
+		request.CallbackResponses = []model.CallbackResponse{}
+	default:
+		callbackCtx, cancel := callback.InlineContext(c)
+		request.CallbackResponses, deadLetters = callback.Deliver(callbackCtx, inbox, request)
+		cancel()
+	}
+	request.ID, err = ih.dao.AddRequestToInbox(c, id, request, files...)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	delivery.StoreDeadLetters(c, ih.dao, id, request.ID, deadLetters)
+	// The request is published before its callbacks, their delivery publishes it again with the responses
+	ih.hub.Publish(id, request)
+	if request.CallbacksPending {
+		ih.enqueueCallbacks(c, delivery.Job{Inbox: inbox, Request: request})
+	}
+	ih.notifier.Notify(c, inbox, request)
+	if rejected {
+		c.AbortWithStatusJSON(model.ErrorResponseMsg(
+			"request signature is "+request.Verification.Status+": "+request.Verification.Reason,
+			inbox.Verification.RejectStatus))
+		return
+	}
+	if request.ProxyResponse != nil {
+		writeProxyResponse(c, *request.ProxyResponse)
+		return
+	}
+	inbox.Response, err = ih.nextResponse(c, inbox, request)
 	if err != nil {
 		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
 		return
@@ -330,6 +440,15 @@ func (ih *inboxHandler) RegisterInboxRequest(c *gin.Context) {
 		}
 	}
 
+	delay := fault.DelayDuration(inbox.Response.Delay)
+	if extra := delay + fault.WriteDuration(inbox.Response.Fault); extra > 0 {
+		extendWriteDeadline(c, extra)
+	}
+	if err := fault.Sleep(c.Request.Context(), delay); err != nil {
+		slog.Debug("request canceled while delaying the response", "error", err)
+		return
+	}
+
 	err = c.ShouldBindHeader(inbox.Response.Headers)
 	if err != nil {
 		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
@@ -342,9 +461,70 @@ func (ih *inboxHandler) RegisterInboxRequest(c *gin.Context) {
 		}
 		c.Header(k, v)
 	}
+	if inbox.Response.Fault.Type != "" {
+		fault.Write(c.Request.Context(), c.Writer, inbox.Response.Code, []byte(inbox.Response.Body), inbox.Response.Fault)
+		return
+	}
 	c.Data(inbox.Response.Code, contentType, []byte(inbox.Response.Body))
 }
 
+// extendWriteDeadline gives the response extra time on top of the server write timeout, which would otherwise
+// break the connection of responses that take longer on purpose.
+func extendWriteDeadline(c *gin.Context, extra time.Duration) {
+	err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(ServerWriteTimeout + extra))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("error extending write deadline", "error", err)
+	}
+}
+
+// enqueueCallbacks hands the callbacks to the delivery queue, they are sent right away when it does not accept them.
+func (ih *inboxHandler) enqueueCallbacks(c *gin.Context, job delivery.Job) {
+	err := ih.queue.Enqueue(c, job)
+	if err != nil {
+		slog.Warn("callbacks delivered synchronously", "error", err, "inbox_id", job.Inbox.ID, "request_id", job.Request.ID)
+		delivery.DeliverInline(c, ih.dao, ih.hub, job)
+	}
+}
+
+// writeProxyResponse answers with the upstream response, or with a bad gateway error when it could not be obtained.
+func writeProxyResponse(c *gin.Context, resp model.ProxyResponse) {
+	if resp.Error != "" {
+		c.AbortWithStatusJSON(model.ErrorResponseMsg(resp.Error, http.StatusBadGateway))
+		return
+	}
+	body, err := resp.RawBody()
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+	for k, values := range resp.Headers {
+		for _, v := range values {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	c.Data(resp.Code, c.Writer.Header().Get(model.ContentTypeHeader), body)
+}
+
+// parseRequestForm sets the form of the request and returns the content of its files.
+// Bodies that are not a valid form are only kept as they are.
+func parseRequestForm(req *model.Request, contentType string) []model.RequestFile {
+	if req.BodyTruncated || req.DecodedBodyTruncated {
+		return nil
+	}
+	b, err := req.DecodedBodyBytes()
+	if err != nil {
+		slog.Debug("error reading request body", "error", err)
+		return nil
+	}
+	form, files, err := body.ParseForm(contentType, b)
+	if err != nil {
+		slog.Debug("error parsing request form", "error", err)
+		return nil
+	}
+	req.Form = form
+	return files
+}
+
 func filterRequestData(req *model.Request) {
 	cookies := req.Headers["Cookie"]
 	if len(cookies) == 0 {
@@ -361,3 +541,67 @@ func filterRequestData(req *model.Request) {
 	}
 	cookies[0] = strings.Join(fc, cookieSeparator)
 }
+
+func parseListRequestsOptions(c *gin.Context) ([]option.ListRequestsOption, error) {
+	opts := []option.ListRequestsOption{}
+	if l := c.Query("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > option.MaxRequestsLimit {
+			return nil, fmt.Errorf("limit must be a number between 1 and %d", option.MaxRequestsLimit)
+		}
+		opts = append(opts, option.WithLimit(limit))
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		opts = append(opts, option.WithCursor(cursor))
+	}
+	if o := c.Query("order"); o != "" {
+		order := option.Order(strings.ToLower(o))
+		if order != option.OrderAsc && order != option.OrderDesc {
+			return nil, fmt.Errorf("order must be %q or %q", option.OrderAsc, option.OrderDesc)
+		}
+		opts = append(opts, option.WithOrder(order))
+	}
+	if method := c.Query("method"); method != "" {
+		opts = append(opts, option.WithMethod(method))
+	}
+	if path := c.Query("path"); path != "" {
+		opts = append(opts, option.WithPathPrefix(path))
+	}
+	from, err := parseQueryTime(c, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseQueryTime(c, "to")
+	if err != nil {
+		return nil, err
+	}
+	if from != 0 && to != 0 && from > to {
+		return nil, errors.New("from must be before to")
+	}
+	if from != 0 || to != 0 {
+		opts = append(opts, option.WithTimeRange(from, to))
+	}
+	for _, h := range c.QueryArray("header") {
+		opts = append(opts, option.WithHeader(h))
+	}
+	if bodyContains := c.Query("body"); bodyContains != "" {
+		opts = append(opts, option.WithBodyContains(bodyContains))
+	}
+	return opts, nil
+}
+
+// parseQueryTime accepts unix milliseconds or RFC3339 dates and returns unix milliseconds.
+func parseQueryTime(c *gin.Context, key string) (int64, error) {
+	v := c.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+	if millis, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return millis, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be unix milliseconds or a RFC3339 date", key)
+	}
+	return t.UnixMilli(), nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/jesusnoseq/request-inbox/pkg/handler"
 	"github.com/jesusnoseq/request-inbox/pkg/instrumentation"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
 	"github.com/jesusnoseq/request-inbox/pkg/t_util"
 )
 
@@ -30,7 +31,7 @@ func mustGetInboxHandler() (handler.InboxService, func()) {
 	if err != nil {
 		panic(err)
 	}
-	return handler.NewInboxHandler(dao, et), func() {
+	return handler.NewInboxHandler(dao, et, stream.NewHub(), nil, nil), func() {
 		err := dao.Close(ctx)
 		if err != nil {
 			panic(err)
@@ -38,6 +39,29 @@ func mustGetInboxHandler() (handler.InboxService, func()) {
 	}
 }
 
+// mustGetRouter returns a router with the routes added by register, its repository is closed when the test ends.
+func mustGetRouter(t *testing.T, register func(r *gin.Engine, ih handler.InboxService)) (database.Repository, *stream.Hub, *gin.Engine) {
+	t.Helper()
+	ctx := context.Background()
+	dao, err := database.NewRepository(ctx, database.Badger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := dao.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	et, err := instrumentation.NewEventTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := stream.NewHub()
+	r := gin.New()
+	register(r, handler.NewInboxHandler(dao, et, hub, nil, nil))
+	return dao, hub, r
+}
+
 func mustParseInbox(payload []byte) model.Inbox {
 	i := model.Inbox{}
 	err := json.Unmarshal(payload, &i)
@@ -86,6 +110,25 @@ func shouldExistInbox(t *testing.T, ih handler.InboxService, i model.Inbox) mode
 	return mustParseInbox(w.Body.Bytes())
 }
 
+func shouldRegisterRequest(t *testing.T, ih handler.InboxService, id uuid.UUID, method string, headers map[string]string) {
+	w := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(w)
+	ginCtx.AddParam("id", id.String())
+	req, err := http.NewRequest(method, "/api/v1/inboxes/"+id.String()+"/in", bytes.NewReader([]byte("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RequestURI = req.URL.Path
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	ginCtx.Request = req
+	ih.RegisterInboxRequest(ginCtx)
+	if w.Code != http.StatusOK {
+		t.Fatalf("request should be registered, got %d with body %s", w.Code, w.Body.String())
+	}
+}
+
 func TestCreateInbox(t *testing.T) {
 	config.LoadConfig(config.Test)
 	inbox := model.GenerateInbox()
@@ -120,13 +163,16 @@ func TestCreateInbox(t *testing.T) {
 	inbox.ID = newInbox.ID
 	inbox.Timestamp = newInbox.Timestamp
 	inbox.Name = inbox.ID.String()
+	// Requests are only stored when they are received
+	inbox.Requests = []model.Request{}
 	if !isUUID(newInbox.ID.String()) {
 		t.Errorf("Expected valid UUID, got %v", newInbox.ID)
 	}
 	if newInbox.Timestamp <= 0 {
 		t.Errorf("Expected Timestamp to be > 0: got  %v", newInbox.Timestamp)
 	}
-	if diff := cmp.Diff(newInbox, inbox); diff != "" {
+	// Secrets are write-only
+	if diff := cmp.Diff(newInbox, inbox.WithoutSecrets()); diff != "" {
 		t.Errorf("Diff(newInbox, inbox) = %v, expected to be equals", diff)
 	}
 }
@@ -315,6 +361,118 @@ func TestGetInbox(t *testing.T) {
 	}
 }
 
+func TestGetInboxWithoutSecrets(t *testing.T) {
+	config.LoadConfig(config.Test)
+	config.Set(config.EnableListingPublicInbox, true)
+	ctx := context.Background()
+	dao, _, r := mustGetRouter(t, func(r *gin.Engine, ih handler.InboxService) {
+		r.GET("/:id", ih.GetInbox)
+		r.PUT("/:id", ih.UpdateInbox)
+		r.GET("/", ih.ListInbox)
+	})
+	inbox := model.GenerateInbox()
+	inbox.Callbacks[0].Signing = model.Signing{IsEnabled: true, Secret: "callback secret"}
+	inbox.Verification = model.Verification{IsEnabled: true, Provider: model.VerificationGitHub, Secret: "verification secret"}
+	inbox, err := dao.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String(), nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	got := mustParseInbox(w.Body.Bytes())
+	t_util.AssertStringEquals(t, got.Callbacks[0].Signing.Secret, "")
+	t_util.AssertStringEquals(t, got.Verification.Secret, "")
+
+	w = serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	list := mustParseInboxList(w.Body.Bytes())
+	t_util.AssertLen(t, list.Results, 1)
+	for _, listed := range list.Results {
+		t_util.AssertStringEquals(t, listed.Callbacks[0].Signing.Secret, "")
+		t_util.AssertStringEquals(t, listed.Verification.Secret, "")
+	}
+
+	// The inbox that was read can be sent back without losing its secrets
+	w = serve(r, httptest.NewRequest(http.MethodPut, "/"+inbox.ID.String(), bytes.NewReader(t_util.MustJson(t, got))))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	stored, err := dao.GetInbox(ctx, inbox.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, stored.Callbacks[0].Signing.Secret, "callback secret")
+	t_util.AssertStringEquals(t, stored.Verification.Secret, "verification secret")
+}
+
+func TestListInboxRequests(t *testing.T) {
+	config.LoadConfig(config.Test)
+	ih, closer := mustGetInboxHandler()
+	defer closer()
+	inbox := model.GenerateInbox()
+	inbox.Callbacks = []model.Callback{}
+	inbox = shouldExistInbox(t, ih, inbox)
+	shouldRegisterRequest(t, ih, inbox.ID, http.MethodPost, map[string]string{"Content-Type": "application/json"})
+	shouldRegisterRequest(t, ih, inbox.ID, http.MethodPut, nil)
+
+	testCases := []struct {
+		desc         string
+		id           string
+		query        string
+		expectedCode int
+		expectedIDs  []int
+	}{
+		{"list all requests", inbox.ID.String(), "", http.StatusOK, []int{0, 1}},
+		{"list requests in descending order", inbox.ID.String(), "?order=desc", http.StatusOK, []int{1, 0}},
+		{"list requests with limit", inbox.ID.String(), "?limit=1", http.StatusOK, []int{0}},
+		{"list requests filtered by method", inbox.ID.String(), "?method=put", http.StatusOK, []int{1}},
+		{"list requests filtered by header", inbox.ID.String(), "?header=content-type", http.StatusOK, []int{0}},
+		{"list requests filtered by date", inbox.ID.String(), "?from=2000-01-01T00:00:00Z&to=2000-01-02T00:00:00Z", http.StatusOK, []int{}},
+		{"invalid inbox id", "invalid", "", http.StatusBadRequest, nil},
+		{"invalid limit", inbox.ID.String(), "?limit=0", http.StatusBadRequest, nil},
+		{"limit too big", inbox.ID.String(), "?limit=501", http.StatusBadRequest, nil},
+		{"invalid order", inbox.ID.String(), "?order=random", http.StatusBadRequest, nil},
+		{"invalid date", inbox.ID.String(), "?from=yesterday", http.StatusBadRequest, nil},
+		{"from after to", inbox.ID.String(), "?from=2000&to=1000", http.StatusBadRequest, nil},
+		{"invalid cursor", inbox.ID.String(), "?cursor=%25%25", http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(w)
+			ginCtx.AddParam("id", tc.id)
+			req, err := http.NewRequest("GET", "/requests"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ginCtx.Request = req
+			ih.ListInboxRequests(ginCtx)
+			resp := w.Result()
+			err = resp.Body.Close()
+			if err != nil {
+				t.Fatalf("Failed to close response body: %v", err)
+			}
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("Expected %d, got %v with body %s", tc.expectedCode, resp.StatusCode, w.Body.String())
+			}
+			if tc.expectedIDs == nil {
+				return
+			}
+			page := model.Page[model.Request]{}
+			if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			ids := []int{}
+			for _, r := range page.Results {
+				ids = append(ids, r.ID)
+			}
+			if diff := cmp.Diff(tc.expectedIDs, ids); diff != "" {
+				t.Errorf("Unexpected requests. Diff: %s", diff)
+			}
+		})
+	}
+}
+
 func TestUpdateInbox(t *testing.T) {
 	config.LoadConfig(config.Test)
 	ih, closer := mustGetInboxHandler()
@@ -356,7 +514,7 @@ func TestUpdateInbox(t *testing.T) {
 	if updatedInbox.Timestamp <= 0 {
 		t.Errorf("Expected valid Timestamp, got %v", updatedInbox.Timestamp)
 	}
-	if diff := cmp.Diff(updatedInbox, modInbox); diff != "" {
+	if diff := cmp.Diff(updatedInbox, modInbox.WithoutSecrets()); diff != "" {
 		t.Errorf("Diff(updatedInbox, modInbox) = %v, expected to be equals", diff)
 	}
 
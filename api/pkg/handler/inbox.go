@@ -10,7 +10,20 @@ type InboxService interface {
 	GetInbox(c *gin.Context)
 	UpdateInbox(c *gin.Context)
 	ListInbox(c *gin.Context)
+	ListInboxRequests(c *gin.Context)
+	GetInboxRequestBody(c *gin.Context)
+	GetInboxRequestFile(c *gin.Context)
+	ReplayInboxRequest(c *gin.Context)
+	StreamInboxRequests(c *gin.Context)
+	WatchInboxRequests(c *gin.Context)
 	DeleteInboxRequests(c *gin.Context)
+	ResetInboxScenario(c *gin.Context)
+	ExportInboxStubs(c *gin.Context)
+	ListDeadLetters(c *gin.Context)
+	RetryDeadLetter(c *gin.Context)
+	DeleteDeadLetter(c *gin.Context)
+	WaitForRequests(c *gin.Context)
+	VerifyRequests(c *gin.Context)
 	RegisterInboxRequest(c *gin.Context)
 }
 
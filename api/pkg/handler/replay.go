@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/callback"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+// ReplayInboxRequest resends a captured request to the target URL and answers with the target response.
+func (ih *inboxHandler) ReplayInboxRequest(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+	requestID, err := parseRequestID(c)
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid request ID", err, http.StatusBadRequest))
+		return
+	}
+	var replay model.ReplayRequest
+	if err := c.ShouldBindJSON(&replay); err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("replay not valid", err, http.StatusBadRequest))
+		return
+	}
+	if valid, err := validation.IsValidCallbackURL(replay.ToURL); !valid {
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkWriteInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error replaying request", "error", err)
+		return
+	}
+
+	req, err := ih.dao.GetInboxRequest(c, id, requestID)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	resp := callback.Replay(inbox, req, replay)
+	if resp.Error != "" {
+		c.AbortWithStatusJSON(model.ErrorResponseMsg(resp.Error, http.StatusBadGateway))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestReplayInboxRequest(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		w.WriteHeader(http.StatusAccepted)
+		t_util.MustWrite(t, w, []byte(req.Method+" "+string(b)))
+	}))
+	defer target.Close()
+	serve(r, httptest.NewRequest(http.MethodPut, "/"+inbox.ID.String()+"/in", strings.NewReader("captured")))
+
+	testCases := []struct {
+		desc       string
+		requestID  string
+		body       string
+		validation bool
+		code       int
+	}{
+		{"replays the request", "0", `{"ToURL":"` + target.URL + `"}`, false, http.StatusOK},
+		{"request that does not exist", "1", `{"ToURL":"` + target.URL + `"}`, false, http.StatusNotFound},
+		{"invalid request ID", "first", `{"ToURL":"` + target.URL + `"}`, false, http.StatusBadRequest},
+		{"invalid body", "0", `{`, false, http.StatusBadRequest},
+		{"target URL not allowed", "0", `{"ToURL":"` + target.URL + `"}`, true, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config.Set(config.EnableCallbackURLValidation, tc.validation)
+			defer config.Set(config.EnableCallbackURLValidation, config.EnableCallbackURLValidationDefault)
+			url := "/" + inbox.ID.String() + "/requests/" + tc.requestID + "/replay"
+
+			w := serve(r, httptest.NewRequest(http.MethodPost, url, strings.NewReader(tc.body)))
+
+			t_util.AssertStatusCode(t, w.Code, tc.code)
+			if tc.code != http.StatusOK {
+				return
+			}
+			resp := model.CallbackResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			t_util.AssertEquals(t, resp.Code, http.StatusAccepted)
+			t_util.AssertStringEquals(t, resp.Body, "PUT captured")
+		})
+	}
+}
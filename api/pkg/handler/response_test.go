@@ -0,0 +1,204 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func mustGetResponseRouter(t *testing.T) (database.Repository, *gin.Engine) {
+	config.LoadConfig(config.Test)
+	dao, _, r := mustGetRouter(t, func(r *gin.Engine, ih handler.InboxService) {
+		r.Any("/:id/in", ih.RegisterInboxRequest)
+		r.Any("/:id/in/*path", ih.RegisterInboxRequest)
+		r.DELETE("/:id/scenario", ih.ResetInboxScenario)
+		r.POST("/:id/requests/:requestId/replay", ih.ReplayInboxRequest)
+		r.GET("/:id/dead-letters", ih.ListDeadLetters)
+		r.POST("/:id/dead-letters/:deadLetterId/retry", ih.RetryDeadLetter)
+		r.DELETE("/:id/dead-letters/:deadLetterId", ih.DeleteDeadLetter)
+	})
+	return dao, r
+}
+
+func mustCreateInboxWithResponses(t *testing.T, dao database.Repository, response model.Response, rules []model.ResponseRule) model.Inbox {
+	inbox := model.GenerateInbox()
+	inbox.Callbacks = []model.Callback{}
+	inbox.Response = response
+	inbox.ResponseRules = rules
+	inbox, err := dao.CreateInbox(context.Background(), inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inbox
+}
+
+func TestRegisterInboxRequestResponseSequence(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{
+		Code: 200,
+		Sequence: []model.Response{
+			{Code: 503, Body: "unavailable"},
+			{Code: 503, Body: "unavailable"},
+			{Code: 200, Body: "ok"},
+		},
+	}, nil)
+
+	for _, expected := range []int{503, 503, 200, 200} {
+		w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/in", nil))
+		t_util.AssertStatusCode(t, w.Code, expected)
+	}
+
+	w := serve(r, httptest.NewRequest(http.MethodDelete, "/"+inbox.ID.String()+"/scenario", nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	scenario := model.Scenario{}
+	if err := json.Unmarshal(w.Body.Bytes(), &scenario); err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, scenario.State, model.ScenarioStarted)
+
+	w = serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/in", nil))
+	t_util.AssertStatusCode(t, w.Code, http.StatusServiceUnavailable)
+	t_util.AssertStringEquals(t, w.Body.String(), "unavailable")
+}
+
+func TestRegisterInboxRequestScenarioState(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: 404}, []model.ResponseRule{
+		{Name: "create", Method: http.MethodPost, State: model.ScenarioStarted, NextState: "created", Response: model.Response{Code: 201}},
+		{Name: "read", Method: http.MethodGet, State: "created", Response: model.Response{Code: 200}},
+	})
+
+	steps := []struct {
+		method string
+		code   int
+	}{
+		{http.MethodGet, http.StatusNotFound},
+		{http.MethodPost, http.StatusCreated},
+		{http.MethodGet, http.StatusOK},
+	}
+	for _, step := range steps {
+		w := serve(r, httptest.NewRequest(step.method, "/"+inbox.ID.String()+"/in", nil))
+		t_util.AssertStatusCode(t, w.Code, step.code)
+	}
+
+	got, err := dao.GetInbox(context.Background(), inbox.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertStringEquals(t, got.Scenario.State, "created")
+}
+
+func TestRegisterInboxRequestResponseDelay(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: 200, Body: "late", Delay: model.Delay{MinMillis: 50}}, nil)
+	start := time.Now()
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/in", nil))
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	t_util.AssertStringEquals(t, w.Body.String(), "late")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the response to be delayed 50ms, but it took %v", elapsed)
+	}
+}
+
+func TestRegisterInboxRequestResponseDelayAtCap(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	config.Set(config.MaxResponseDelayMillisKey, 300)
+	defer config.Set(config.MaxResponseDelayMillisKey, config.MaxResponseDelayMillisDefault)
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: 200, Body: "late", Delay: model.Delay{MinMillis: 300}}, nil)
+	// The delay is longer than the write timeout of the server
+	server := httptest.NewUnstartedServer(r)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/" + inbox.ID.String() + "/in")
+	if err != nil {
+		t.Fatalf("Expected the delayed response, but got an error: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Expected the delayed response body, but got an error: %v", err)
+	}
+	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusOK)
+	t_util.AssertStringEquals(t, string(b), "late")
+}
+
+func TestRegisterInboxRequestResponseFault(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: 200, Body: "0123456789", Fault: model.Fault{Type: model.FaultTruncatedBody}}, nil)
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/in", nil))
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusOK)
+	t_util.AssertStringEquals(t, w.Header().Get("Content-Length"), "10")
+	t_util.AssertStringEquals(t, w.Body.String(), "01234")
+}
+
+func TestRegisterInboxRequestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(model.ContentTypeHeader, "application/json")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.WriteHeader(http.StatusCreated)
+		t_util.MustWrite(t, w, []byte(`{"path":"`+r.URL.Path+`"}`))
+	}))
+	defer upstream.Close()
+	dao, r := mustGetResponseRouter(t)
+	config.Set(config.EnableCallbackURLValidation, false)
+	defer config.Set(config.EnableCallbackURLValidation, config.EnableCallbackURLValidationDefault)
+	inbox := model.GenerateInbox()
+	inbox.Callbacks = []model.Callback{}
+	inbox.Proxy = model.Proxy{IsEnabled: true, ToURL: upstream.URL}
+	inbox, err := dao.CreateInbox(context.Background(), inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(r, httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in/orders", strings.NewReader("{}")))
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusCreated)
+	t_util.AssertStringEquals(t, w.Body.String(), `{"path":"/orders"}`)
+	t_util.AssertStringEquals(t, w.Header().Get(model.ContentTypeHeader), "application/json")
+	if diff := cmp.Diff([]string{"a=1", "b=2"}, w.Header().Values("Set-Cookie")); diff != "" {
+		t.Errorf("Expected every Set-Cookie header of the upstream. Diff: %s", diff)
+	}
+	got, err := dao.GetInboxWithRequests(context.Background(), inbox.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Requests) != 1 || got.Requests[0].ProxyResponse == nil {
+		t.Fatalf("Expected a request with the proxy response, got %+v", got.Requests)
+	}
+	t_util.AssertEquals(t, got.Requests[0].ProxyResponse.Code, http.StatusCreated)
+}
+
+func TestRegisterInboxRequestProxyError(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := model.GenerateInbox()
+	inbox.Callbacks = []model.Callback{}
+	inbox.Proxy = model.Proxy{IsEnabled: true, ToURL: "http://127.0.0.1:1"}
+	inbox, err := dao.CreateInbox(context.Background(), inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/"+inbox.ID.String()+"/in", nil))
+
+	t_util.AssertStatusCode(t, w.Code, http.StatusBadGateway)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/dynamic_response"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// nextResponse selects the response for the request, advancing the inbox scenario when the response depends on it.
+func (ih *inboxHandler) nextResponse(ctx context.Context, inbox model.Inbox, request model.Request) (model.Response, error) {
+	if !inbox.HasScenario() {
+		return dynamic_response.SelectResponse(inbox, request), nil
+	}
+	var response model.Response
+	_, err := ih.dao.UpdateInboxScenario(ctx, inbox.ID, func(current model.Scenario) model.Scenario {
+		var next model.Scenario
+		response, next = dynamic_response.NextResponse(inbox, current, request)
+		return next
+	})
+	return response, err
+}
+
+// ResetInboxScenario moves the inbox back to the started state and restarts its response sequences.
+func (ih *inboxHandler) ResetInboxScenario(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkWriteInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error resetting inbox scenario", "error", err)
+		return
+	}
+
+	scenario, err := ih.dao.UpdateInboxScenario(c, id, func(model.Scenario) model.Scenario {
+		return model.Scenario{State: model.ScenarioStarted, Calls: map[string]int{}}
+	})
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, scenario)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	LastEventIDHeader = "Last-Event-ID"
+	RequestEventName  = "request"
+)
+
+func (ih *inboxHandler) StreamInboxRequests(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+
+	lastEventID := -1
+	if v := c.GetHeader(LastEventIDHeader); v != "" {
+		lastEventID, err = strconv.Atoi(v)
+		if err != nil {
+			c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid "+LastEventIDHeader, err, http.StatusBadRequest))
+			return
+		}
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error streaming requests of inbox", "error", err)
+		return
+	}
+
+	// Subscribe before reading the stored requests so nothing is lost between both steps
+	sub := ih.hub.Subscribe(id)
+	defer sub.Close()
+
+	missed := []model.Request{}
+	if lastEventID >= 0 {
+		missed, err = ih.listRequestsAfter(c, id, lastEventID)
+		if err != nil {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+			return
+		}
+	}
+
+	// The server write timeout would end the stream
+	err = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("error clearing write deadline of stream", "error", err)
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Requests published while the missed ones were listed can be in both, they are sent once.
+	// Later events are always sent: publishes can arrive out of order and updates reuse the request ID.
+	replayed := make(map[int]bool, len(missed))
+	for _, req := range missed {
+		renderRequestEvent(c, req)
+		replayed[req.ID] = true
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(time.Duration(config.GetInt(config.StreamHeartbeatSeconds)) * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case req, ok := <-sub.Events():
+			if !ok {
+				slog.Info("stream subscriber dropped", "inbox_id", id)
+				return
+			}
+			if replayed[req.ID] {
+				delete(replayed, req.ID)
+				continue
+			}
+			renderRequestEvent(c, req)
+		case <-heartbeat.C:
+			_, err := c.Writer.WriteString(": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+		}
+		if c.IsAborted() {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+func renderRequestEvent(c *gin.Context, req model.Request) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.Itoa(req.ID),
+		Event: RequestEventName,
+		Data:  req,
+	})
+}
+
+func (ih *inboxHandler) listRequestsAfter(c *gin.Context, id uuid.UUID, lastID int) ([]model.Request, error) {
+	requests := []model.Request{}
+	cursor := ""
+	for {
+		page, err := ih.dao.ListInboxRequests(c, id, option.WithLimit(option.MaxRequestsLimit), option.WithCursor(cursor))
+		if err != nil {
+			return nil, fmt.Errorf("error listing requests after %d: %w", lastID, err)
+		}
+		for _, req := range page.Results {
+			if req.ID > lastID {
+				requests = append(requests, req)
+			}
+		}
+		if page.NextCursor == "" {
+			return requests, nil
+		}
+		cursor = page.NextCursor
+	}
+}
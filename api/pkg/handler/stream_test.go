@@ -0,0 +1,198 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/handler"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+func mustGetStreamServer(t *testing.T) (database.Repository, *stream.Hub, *httptest.Server) {
+	dao, hub, r := mustGetRouter(t, func(r *gin.Engine, ih handler.InboxService) {
+		r.GET("/:id/stream", ih.StreamInboxRequests)
+		r.GET("/:id/ws", ih.WatchInboxRequests)
+		r.Any("/:id/in", ih.RegisterInboxRequest)
+		r.Any("/:id/in/*path", ih.RegisterInboxRequest)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return dao, hub, srv
+}
+
+func mustCreateInboxWithoutCallbacks(t *testing.T, dao database.Repository) model.Inbox {
+	inbox := model.GenerateInbox()
+	inbox.Callbacks = []model.Callback{}
+	inbox, err := dao.CreateInbox(context.Background(), inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inbox
+}
+
+func openStream(t *testing.T, ctx context.Context, url string, lastEventID string) *http.Response {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set(handler.LastEventIDHeader, lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// readSSE returns the next event or comment block of the stream.
+func readSSE(t *testing.T, r *bufio.Reader) (sseEvent, string) {
+	e := sseEvent{}
+	comment := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return e, comment
+		}
+		switch {
+		case strings.HasPrefix(line, ":"):
+			comment = strings.TrimSpace(line[1:])
+		case strings.HasPrefix(line, "id:"):
+			e.ID = strings.TrimSpace(line[3:])
+		case strings.HasPrefix(line, "event:"):
+			e.Event = strings.TrimSpace(line[6:])
+		case strings.HasPrefix(line, "data:"):
+			e.Data = strings.TrimSpace(line[5:])
+		}
+	}
+}
+
+func waitForSubscriber(t *testing.T, hub *stream.Hub, id uuid.UUID) {
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.SubscriberCount(id) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not subscribe to the hub")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamInboxRequests(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, hub, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := openStream(t, ctx, srv.URL+"/"+inbox.ID.String()+"/stream", "")
+	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusOK)
+	t_util.AssertStringContains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	waitForSubscriber(t, hub, inbox.ID)
+
+	in, err := http.Post(srv.URL+"/"+inbox.ID.String()+"/in", "application/json", strings.NewReader(`{"hello":"world"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = in.Body.Close()
+
+	e, _ := readSSE(t, bufio.NewReader(resp.Body))
+	t_util.AssertStringEquals(t, e.Event, handler.RequestEventName)
+	t_util.AssertStringEquals(t, e.ID, "0")
+	req := model.Request{}
+	if err := json.Unmarshal([]byte(e.Data), &req); err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, req.ID, 0)
+	t_util.AssertStringEquals(t, req.Body, `{"hello":"world"}`)
+	t_util.AssertStringEquals(t, req.Method, http.MethodPost)
+}
+
+func TestStreamInboxRequestsResumesFromLastEventID(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, _, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	requests := []model.Request{model.GenerateRequest(0), model.GenerateRequest(1)}
+	for _, req := range requests {
+		if _, err := dao.AddRequestToInbox(context.Background(), inbox.ID, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := openStream(t, ctx, srv.URL+"/"+inbox.ID.String()+"/stream", "0")
+	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusOK)
+
+	e, _ := readSSE(t, bufio.NewReader(resp.Body))
+	t_util.AssertStringEquals(t, e.ID, "1")
+	t_util.AssertStringContains(t, e.Data, requests[1].Body)
+}
+
+func TestStreamInboxRequestsSendsOutOfOrderRequests(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, hub, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := openStream(t, ctx, srv.URL+"/"+inbox.ID.String()+"/stream", "")
+	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusOK)
+	waitForSubscriber(t, hub, inbox.ID)
+	hub.Publish(inbox.ID, model.GenerateRequest(2))
+	hub.Publish(inbox.ID, model.GenerateRequest(1))
+
+	body := bufio.NewReader(resp.Body)
+	e, _ := readSSE(t, body)
+	t_util.AssertStringEquals(t, e.ID, "2")
+	e, _ = readSSE(t, body)
+	t_util.AssertStringEquals(t, e.ID, "1")
+}
+
+func TestStreamInboxRequestsHeartbeat(t *testing.T) {
+	config.LoadConfig(config.Test)
+	config.Set(config.StreamHeartbeatSeconds, 1)
+	defer config.Set(config.StreamHeartbeatSeconds, config.StreamHeartbeatSecondsDefault)
+	dao, _, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := openStream(t, ctx, srv.URL+"/"+inbox.ID.String()+"/stream", "")
+
+	e, comment := readSSE(t, bufio.NewReader(resp.Body))
+	t_util.AssertStringEquals(t, comment, "heartbeat")
+	t_util.AssertStringEquals(t, e.ID, "")
+}
+
+func TestStreamInboxRequestsInvalidLastEventID(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, _, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+
+	resp := openStream(t, context.Background(), srv.URL+"/"+inbox.ID.String()+"/stream", "abc")
+
+	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusBadRequest)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/signature"
+)
+
+// verifyRequest checks the signature of the received request, a truncated body can not be verified.
+func verifyRequest(v model.Verification, headers http.Header, request model.Request) *model.VerificationResult {
+	result := model.VerificationResult{Provider: v.Provider, Status: model.VerificationInvalid}
+	if request.BodyTruncated {
+		result.Reason = "body is truncated"
+		return &result
+	}
+	raw, err := request.RawBody()
+	if err != nil {
+		result.Reason = err.Error()
+		return &result
+	}
+	result = signature.Verify(v, headers, raw, time.Now())
+	return &result
+}
+
+// isRejected reports whether the request must be answered with the reject status of the verification.
+func isRejected(v model.Verification, result *model.VerificationResult) bool {
+	return v.RejectStatus != 0 && result != nil && result.Status != model.VerificationValid
+}
@@ -0,0 +1,79 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestRegisterInboxRequestVerification(t *testing.T) {
+	const body = "Hello, World!"
+	const validSignature = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	testCases := []struct {
+		desc           string
+		rejectStatus   int
+		signature      string
+		expectedCode   int
+		expectedStatus string
+	}{
+		{"valid signature", 0, validSignature, http.StatusOK, model.VerificationValid},
+		{"invalid signature is accepted", 0, "sha256=00", http.StatusOK, model.VerificationInvalid},
+		{"missing signature is accepted", 0, "", http.StatusOK, model.VerificationMissing},
+		{"valid signature is not rejected", http.StatusUnauthorized, validSignature, http.StatusOK, model.VerificationValid},
+		{"invalid signature is rejected", http.StatusUnauthorized, "sha256=00", http.StatusUnauthorized, model.VerificationInvalid},
+		{"missing signature is rejected", http.StatusForbidden, "", http.StatusForbidden, model.VerificationMissing},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dao, r := mustGetResponseRouter(t)
+			inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: http.StatusOK}, nil)
+			inbox.Verification = model.Verification{
+				IsEnabled:    true,
+				Provider:     model.VerificationGitHub,
+				Secret:       "It's a Secret to Everybody",
+				RejectStatus: tc.rejectStatus,
+			}
+			inbox.ObfuscateHeaderFields = []string{"X-Hub-Signature-256"}
+			if _, err := dao.UpdateInbox(context.Background(), inbox); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", strings.NewReader(body))
+			if tc.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tc.signature)
+			}
+
+			w := serve(r, req)
+
+			t_util.AssertStatusCode(t, w.Code, tc.expectedCode)
+			stored, err := dao.GetInboxRequest(context.Background(), inbox.ID, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Verification == nil {
+				t.Fatal("Expected the verification result to be stored")
+			}
+			t_util.AssertStringEquals(t, stored.Verification.Status, tc.expectedStatus)
+			t_util.AssertStringEquals(t, stored.Verification.Provider, model.VerificationGitHub)
+		})
+	}
+}
+
+func TestRegisterInboxRequestWithoutVerification(t *testing.T) {
+	dao, r := mustGetResponseRouter(t)
+	inbox := mustCreateInboxWithResponses(t, dao, model.Response{Code: http.StatusOK}, nil)
+
+	serve(r, httptest.NewRequest(http.MethodPost, "/"+inbox.ID.String()+"/in", strings.NewReader("body")))
+
+	stored, err := dao.GetInboxRequest(context.Background(), inbox.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Verification != nil {
+		t.Errorf("Expected no verification result, got %+v", stored.Verification)
+	}
+}
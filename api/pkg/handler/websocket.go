@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database/dberrors"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"golang.org/x/net/websocket"
+)
+
+func (ih *inboxHandler) WatchInboxRequests(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(model.ErrorResponseWithError("invalid inbox ID", err, http.StatusBadRequest))
+		return
+	}
+
+	inbox, err := ih.dao.GetInbox(c, id)
+	if err != nil {
+		if errors.Is(err, dberrors.ErrItemNotFound) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusNotFound))
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	err = checkReadInboxPermissions(c, inbox)
+	if err != nil {
+		slog.Error("error watching requests of inbox", "error", err)
+		return
+	}
+
+	server := websocket.Server{
+		Handshake: checkWebSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			ih.serveWatch(ws, id)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkWebSocketOrigin rejects browsers connecting from sites not allowed by CORS,
+// otherwise any site could open a socket with the user cookies.
+// Clients without Origin header are not browsers and are accepted.
+func checkWebSocketOrigin(cfg *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if !collection.SliceContains(config.GetStringSlice(config.CORSAllowOrigins), origin) {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	var err error
+	cfg.Origin, err = websocket.Origin(cfg, req)
+	return err
+}
+
+func (ih *inboxHandler) serveWatch(ws *websocket.Conn, id uuid.UUID) {
+	defer ws.Close()
+	// The server read and write timeouts would end the connection
+	err := ws.SetDeadline(time.Time{})
+	if err != nil {
+		slog.Warn("error clearing deadline of websocket", "error", err)
+	}
+
+	sub := ih.hub.Subscribe(id)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	incoming := make(chan []byte)
+	go readWebSocketMessages(ws, incoming, done)
+
+	heartbeat := time.NewTicker(time.Duration(config.GetInt(config.StreamHeartbeatSeconds)) * time.Second)
+	defer heartbeat.Stop()
+
+	// Nothing is delivered until the client subscribes
+	var filter *stream.Filter
+	for {
+		var msg *stream.Message
+		select {
+		case data, ok := <-incoming:
+			if !ok {
+				return
+			}
+			var next *stream.Filter
+			msg, next = handleWebSocketMessage(data, filter)
+			filter = next
+		case req, ok := <-sub.Events():
+			if !ok {
+				slog.Info("websocket subscriber dropped", "inbox_id", id)
+				return
+			}
+			if filter == nil || !filter.Match(req) {
+				continue
+			}
+			msg = &stream.Message{Type: stream.RequestMessage, Request: &req}
+		case <-heartbeat.C:
+			msg = &stream.Message{Type: stream.HeartbeatMessage}
+		}
+		if err := websocket.JSON.Send(ws, msg); err != nil {
+			slog.Debug("error sending websocket message", "inbox_id", id, "error", err)
+			return
+		}
+	}
+}
+
+func readWebSocketMessages(ws *websocket.Conn, incoming chan<- []byte, done <-chan struct{}) {
+	defer close(incoming)
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			return
+		}
+		select {
+		case incoming <- data:
+		case <-done:
+			return
+		}
+	}
+}
+
+// handleWebSocketMessage returns the reply to the client message and the filter to apply from now on.
+func handleWebSocketMessage(data []byte, current *stream.Filter) (*stream.Message, *stream.Filter) {
+	var msg stream.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return &stream.Message{Type: stream.ErrorMessage, Error: "invalid message: " + err.Error()}, current
+	}
+	switch msg.Type {
+	case stream.SubscribeMessage:
+		filter := stream.Filter{}
+		if msg.Filter != nil {
+			filter = *msg.Filter
+		}
+		if err := filter.Validate(); err != nil {
+			return &stream.Message{Type: stream.ErrorMessage, Error: err.Error()}, current
+		}
+		return &stream.Message{Type: stream.SubscribedMessage, Filter: &filter}, &filter
+	case stream.UnsubscribeMessage:
+		return &stream.Message{Type: stream.UnsubscribedMessage}, nil
+	default:
+		return &stream.Message{Type: stream.ErrorMessage, Error: fmt.Sprintf("unknown message type %q", msg.Type)}, current
+	}
+}
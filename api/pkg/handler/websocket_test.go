@@ -0,0 +1,142 @@
+package handler_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/stream"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+	"golang.org/x/net/websocket"
+)
+
+const allowedOrigin = "https://request-inbox.com"
+
+func mustDialWebSocket(t *testing.T, serverURL string, id uuid.UUID) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(serverURL, "http") + "/" + id.String() + "/ws"
+	ws, err := websocket.Dial(url, "", allowedOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ws.Close() })
+	if err := ws.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func mustReceiveMessage(t *testing.T, ws *websocket.Conn) stream.Message {
+	t.Helper()
+	msg := stream.Message{}
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func mustSendToInbox(t *testing.T, serverURL string, id uuid.UUID, method, path string, headers map[string]string) {
+	req, err := http.NewRequest(method, serverURL+"/"+id.String()+"/in"+path, strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusOK)
+}
+
+func TestWatchInboxRequestsWithFilter(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, hub, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	ws := mustDialWebSocket(t, srv.URL, inbox.ID)
+	waitForSubscriber(t, hub, inbox.ID)
+
+	err := websocket.JSON.Send(ws, stream.Message{
+		Type: stream.SubscribeMessage,
+		Filter: &stream.Filter{
+			Method:  "post",
+			Path:    "/orders/*",
+			Headers: map[string]string{"X-Event": "created"},
+		},
+	})
+	t_util.RequireNoError(t, err)
+	msg := mustReceiveMessage(t, ws)
+	t_util.AssertEquals(t, msg.Type, stream.SubscribedMessage)
+	t_util.AssertStringEquals(t, msg.Filter.Path, "/orders/*")
+
+	mustSendToInbox(t, srv.URL, inbox.ID, http.MethodGet, "/orders/1", map[string]string{"X-Event": "created"})
+	mustSendToInbox(t, srv.URL, inbox.ID, http.MethodPost, "/users/1", map[string]string{"X-Event": "created"})
+	mustSendToInbox(t, srv.URL, inbox.ID, http.MethodPost, "/orders/1", map[string]string{"X-Event": "deleted"})
+	mustSendToInbox(t, srv.URL, inbox.ID, http.MethodPost, "/orders/2", map[string]string{"X-Event": "created"})
+
+	msg = mustReceiveMessage(t, ws)
+	t_util.AssertEquals(t, msg.Type, stream.RequestMessage)
+	t_util.AssertStringContains(t, msg.Request.URI, "/orders/2")
+	t_util.AssertStringEquals(t, msg.Request.Method, http.MethodPost)
+}
+
+func TestWatchInboxRequestsUnsubscribe(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, hub, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	ws := mustDialWebSocket(t, srv.URL, inbox.ID)
+	waitForSubscriber(t, hub, inbox.ID)
+
+	t_util.RequireNoError(t, websocket.JSON.Send(ws, stream.Message{Type: stream.SubscribeMessage}))
+	t_util.AssertEquals(t, mustReceiveMessage(t, ws).Type, stream.SubscribedMessage)
+	t_util.RequireNoError(t, websocket.JSON.Send(ws, stream.Message{Type: stream.UnsubscribeMessage}))
+	t_util.AssertEquals(t, mustReceiveMessage(t, ws).Type, stream.UnsubscribedMessage)
+
+	mustSendToInbox(t, srv.URL, inbox.ID, http.MethodPost, "/ignored", nil)
+	t_util.RequireNoError(t, websocket.JSON.Send(ws, stream.Message{Type: stream.SubscribeMessage}))
+	t_util.AssertEquals(t, mustReceiveMessage(t, ws).Type, stream.SubscribedMessage)
+	mustSendToInbox(t, srv.URL, inbox.ID, http.MethodPost, "/received", nil)
+
+	msg := mustReceiveMessage(t, ws)
+	t_util.AssertEquals(t, msg.Type, stream.RequestMessage)
+	t_util.AssertStringContains(t, msg.Request.URI, "/received")
+}
+
+func TestWatchInboxRequestsInvalidMessages(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, _, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	ws := mustDialWebSocket(t, srv.URL, inbox.ID)
+
+	testCases := []struct {
+		desc    string
+		message string
+	}{
+		{"not a json", "{"},
+		{"unknown type", `{"type":"other"}`},
+		{"invalid path glob", `{"type":"subscribe","filter":{"path":"[a-"}}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t_util.RequireNoError(t, websocket.Message.Send(ws, tc.message))
+			msg := mustReceiveMessage(t, ws)
+			t_util.AssertEquals(t, msg.Type, stream.ErrorMessage)
+			t_util.AssertStringNotEquals(t, msg.Error, "")
+		})
+	}
+}
+
+func TestWatchInboxRequestsRejectsOrigin(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, _, srv := mustGetStreamServer(t)
+	inbox := mustCreateInboxWithoutCallbacks(t, dao)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/" + inbox.ID.String() + "/ws"
+
+	_, err := websocket.Dial(url, "", "https://evil.example.com")
+
+	t_util.AssertError(t, err)
+}
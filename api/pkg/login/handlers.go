@@ -106,6 +106,10 @@ func (lh *loginHandler) HandleCallback(c *gin.Context) {
 		c.AbortWithStatusJSON(model.ErrorResponseMsg("Failed to parse user info", http.StatusInternalServerError))
 		return
 	}
+	// The provider does not know the email preferences, they are kept from the stored user
+	if stored, err := lh.dao.GetUser(c.Request.Context(), user.ID); err == nil {
+		user.EmailPreferences = stored.EmailPreferences
+	}
 	isNewUser, err := lh.dao.UpsertUser(c.Request.Context(), user)
 	if err != nil {
 		instrumentation.LogError(c, err, "Failed to save user", "user", user)
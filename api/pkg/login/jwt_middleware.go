@@ -17,6 +17,7 @@ const (
 	IS_LOGGED_WITH_API_KEY_CONTEXT_KEY = "logged_with_api_key"
 	IS_LOGGED_WITH_COOKIE_CONTEXT_KEY  = "logged_with_cookie"
 	LOGIN_ERROR_CONTEXT_KEY            = "login_error"
+	API_KEY_CONTEXT_KEY                = "api_key"
 )
 
 func JWTMiddleware() gin.HandlerFunc {
@@ -26,6 +27,7 @@ func JWTMiddleware() gin.HandlerFunc {
 		c.Set(USER_CONTEXT_KEY, nil)
 		c.Set(IS_LOGGED_WITH_COOKIE_CONTEXT_KEY, false)
 		c.Set(IS_LOGGED_WITH_API_KEY_CONTEXT_KEY, false)
+		c.Set(API_KEY_CONTEXT_KEY, nil)
 
 		token, _ := c.Cookie(AuthTokenCookieName)
 		if token == "" {
@@ -86,5 +88,50 @@ func APIKeyMiddleware(dao database.Repository) gin.HandlerFunc {
 
 		c.Set(IS_LOGGED_WITH_COOKIE_CONTEXT_KEY, false)
 		c.Set(IS_LOGGED_WITH_API_KEY_CONTEXT_KEY, true)
+		c.Set(API_KEY_CONTEXT_KEY, ak)
 	}
 }
+
+func APIKeyPermissionMiddleware(domain model.PermissionDomain, action model.PermissionAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(IS_LOGGED_WITH_API_KEY_CONTEXT_KEY) {
+			return
+		}
+		ak, err := GetAPIKey(c)
+		if err != nil {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(err, http.StatusForbidden))
+			return
+		}
+		if !ak.Permissions.Allows(domain, action) {
+			c.AbortWithStatusJSON(model.ErrorResponseFromError(
+				fmt.Errorf("API key does not have %s permission on %s", action, domain),
+				http.StatusForbidden))
+			return
+		}
+	}
+}
+
+// RejectAPIKeyMiddleware rejects requests authenticated with an API key, whatever its permissions.
+// It guards the routes that manage the account, so a leaked key can not create keys or delete the user.
+func RejectAPIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(IS_LOGGED_WITH_API_KEY_CONTEXT_KEY) {
+			return
+		}
+		c.AbortWithStatusJSON(model.ErrorResponseFromError(
+			fmt.Errorf("this endpoint can not be used with an API key"),
+			http.StatusForbidden))
+	}
+}
+
+func GetAPIKey(c *gin.Context) (model.APIKey, error) {
+	akVal, exists := c.Get(API_KEY_CONTEXT_KEY)
+	if !exists {
+		return model.APIKey{}, fmt.Errorf("the request is not authenticated with an API key")
+	}
+	ak, ok := akVal.(model.APIKey)
+	if !ok {
+		return model.APIKey{}, fmt.Errorf("the request is not authenticated with an API key")
+	}
+	return ak, nil
+}
@@ -196,3 +196,97 @@ func TestAPIKeyMiddlewareNoToken(t *testing.T) {
 	}
 	t_util.AssertStatusCode(t, resp.StatusCode, http.StatusOK)
 }
+
+func TestAPIKeyPermissionMiddleware(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, closer := mustGetInboxDao()
+	defer closer()
+
+	testCases := []struct {
+		desc           string
+		permissions    model.APIKeyPermissions
+		useCookie      bool
+		action         model.PermissionAction
+		expectedStatus int
+	}{
+		{desc: "Key with the permission", permissions: model.APIKeyPermissions{model.Inboxes: {model.Read: true}}, action: model.Read, expectedStatus: http.StatusOK},
+		{desc: "Key without the permission", permissions: model.APIKeyPermissions{model.Inboxes: {model.Read: true}}, action: model.Delete, expectedStatus: http.StatusForbidden},
+		{desc: "Legacy key without permissions", permissions: nil, action: model.Delete, expectedStatus: http.StatusOK},
+		{desc: "User logged with cookie is not checked", useCookie: true, action: model.Delete, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/", nil)
+
+			user := model.GenerateUser()
+			_, err := dao.UpsertUser(c, user)
+			t_util.RequireNoError(t, err)
+			if tc.useCookie {
+				token, err := GenerateJWT(user, time.Hour)
+				t_util.RequireNoError(t, err)
+				c.Request.AddCookie(&http.Cookie{Name: AuthTokenCookieName, Value: token})
+			} else {
+				apiKey, err := model.NewAPIKey(user.ID)
+				t_util.RequireNoError(t, err)
+				apiKey.Permissions = tc.permissions
+				t_util.RequireNoError(t, dao.CreateAPIKey(c, apiKey))
+				c.Request.Header.Add("X-API-KEY", apiKey.APIKey)
+			}
+
+			JWTMiddleware()(c)
+			APIKeyMiddleware(dao)(c)
+			APIKeyPermissionMiddleware(model.Inboxes, tc.action)(c)
+
+			t_util.AssertEquals(t, c.IsAborted(), tc.expectedStatus != http.StatusOK)
+			t_util.AssertStatusCode(t, w.Code, tc.expectedStatus)
+		})
+	}
+}
+
+func TestRejectAPIKeyMiddleware(t *testing.T) {
+	config.LoadConfig(config.Test)
+	dao, closer := mustGetInboxDao()
+	defer closer()
+
+	testCases := []struct {
+		desc           string
+		useCookie      bool
+		expectedStatus int
+	}{
+		{desc: "User logged with API key is rejected", expectedStatus: http.StatusForbidden},
+		{desc: "User logged with cookie is allowed", useCookie: true, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/", nil)
+
+			user := model.GenerateUser()
+			_, err := dao.UpsertUser(c, user)
+			t_util.RequireNoError(t, err)
+			if tc.useCookie {
+				token, err := GenerateJWT(user, time.Hour)
+				t_util.RequireNoError(t, err)
+				c.Request.AddCookie(&http.Cookie{Name: AuthTokenCookieName, Value: token})
+			} else {
+				apiKey, err := model.NewAPIKey(user.ID)
+				t_util.RequireNoError(t, err)
+				apiKey.Permissions = model.NewFullAccessPermissions()
+				t_util.RequireNoError(t, dao.CreateAPIKey(c, apiKey))
+				c.Request.Header.Add("X-API-KEY", apiKey.APIKey)
+			}
+
+			JWTMiddleware()(c)
+			APIKeyMiddleware(dao)(c)
+			RejectAPIKeyMiddleware()(c)
+
+			t_util.AssertEquals(t, c.IsAborted(), tc.expectedStatus != http.StatusOK)
+			t_util.AssertStatusCode(t, w.Code, tc.expectedStatus)
+		})
+	}
+}
@@ -22,6 +22,31 @@ const API_KEY_SIZE = 32
 
 type APIKeyPermissions map[PermissionDomain]map[PermissionAction]bool
 
+// PermissionActionsByDomain lists every action that can be granted on each domain.
+var PermissionActionsByDomain = map[PermissionDomain][]PermissionAction{
+	Inboxes: {Create, Read, Update, Delete},
+}
+
+func NewFullAccessPermissions() APIKeyPermissions {
+	permissions := APIKeyPermissions{}
+	for domain, actions := range PermissionActionsByDomain {
+		permissions[domain] = map[PermissionAction]bool{}
+		for _, action := range actions {
+			permissions[domain][action] = true
+		}
+	}
+	return permissions
+}
+
+// Allows reports whether the action is granted on the domain.
+// API keys created before scopes existed have no permissions at all and keep full access.
+func (p APIKeyPermissions) Allows(domain PermissionDomain, action PermissionAction) bool {
+	if p == nil {
+		return true
+	}
+	return p[domain][action]
+}
+
 type APIKey struct {
 	ID           uuid.UUID
 	Name         string
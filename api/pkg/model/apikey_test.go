@@ -65,3 +65,27 @@ func TestNewApiKeyID(t *testing.T) {
 		t.Errorf("Expected a valid UUID, got error: %v", err)
 	}
 }
+
+func TestAPIKeyPermissionsAllows(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		permissions APIKeyPermissions
+		action      PermissionAction
+		expected    bool
+	}{
+		{desc: "Legacy key without permissions has full access", permissions: nil, action: Delete, expected: true},
+		{desc: "Full access allows delete", permissions: NewFullAccessPermissions(), action: Delete, expected: true},
+		{desc: "Read only allows read", permissions: APIKeyPermissions{Inboxes: {Read: true}}, action: Read, expected: true},
+		{desc: "Read only does not allow update", permissions: APIKeyPermissions{Inboxes: {Read: true}}, action: Update, expected: false},
+		{desc: "Explicitly denied action", permissions: APIKeyPermissions{Inboxes: {Read: true, Create: false}}, action: Create, expected: false},
+		{desc: "Empty permissions do not allow anything", permissions: APIKeyPermissions{}, action: Read, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := tc.permissions.Allows(Inboxes, tc.action); got != tc.expected {
+				t.Errorf("Allows(%s, %s) = %v, expected %v", Inboxes, tc.action, got, tc.expected)
+			}
+		})
+	}
+}
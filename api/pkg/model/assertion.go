@@ -0,0 +1,62 @@
+package model
+
+// Assertion expects a number of requests matching the matcher.
+type Assertion struct {
+	Matcher RequestMatcher
+	// Count is the minimum number of matching requests, 1 when it is 0 and Exactly is not set
+	Count int
+	// Exactly requires exactly Count matching requests, so a 0 Count asserts that there are none
+	Exactly bool
+	// Since only considers the requests received from this unix milliseconds
+	Since int64
+	// TimeoutSeconds is how long a wait blocks, the server default is used when it is 0
+	TimeoutSeconds int
+}
+
+// RequestMatcher selects requests, empty fields match any request.
+type RequestMatcher struct {
+	Method string
+	// Path is a glob (see path.Match) over the path sent after "/in", e.g. "/orders/*"
+	Path    string
+	Headers []HeaderMatcher
+	// Body checks gjson paths of the body, an empty Value only checks that the path exists
+	Body []BodyMatcher
+}
+
+// HeaderMatcher matches when a value of the header equals Equals or matches Regex, or when it is present if both are empty.
+type HeaderMatcher struct {
+	Name   string
+	Equals string
+	Regex  string
+}
+
+// ExpectedCount returns the number of matching requests the assertion needs.
+func (a Assertion) ExpectedCount() int {
+	if a.Count == 0 && !a.Exactly {
+		return 1
+	}
+	return a.Count
+}
+
+type AssertionResult struct {
+	Passed   bool
+	Expected int
+	Matched  int
+	// Requests are the matching requests, ordered by ID
+	Requests []Request
+	// Closest is the non-matching request with fewer differences, only set when the assertion fails
+	Closest *ClosestRequest `json:",omitempty"`
+}
+
+// ClosestRequest is a request that does not match and how it differs from the matcher.
+type ClosestRequest struct {
+	Request Request
+	Diff    []Mismatch
+}
+
+// Mismatch is a field of a request that does not match, e.g. "method", "header X-Token" or "body order.id".
+type Mismatch struct {
+	Field    string
+	Expected string
+	Actual   string
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	BodyEncodingUTF8   = "utf8"
+	BodyEncodingBase64 = "base64"
+)
+
+// EncodeBody returns the body as text and the encoding used, binary bodies are encoded as base64.
+func EncodeBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), BodyEncodingUTF8
+	}
+	return base64.StdEncoding.EncodeToString(b), BodyEncodingBase64
+}
+
+// DecodeBody returns the bytes of a body encoded with EncodeBody.
+func DecodeBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", BodyEncodingUTF8:
+		return []byte(body), nil
+	case BodyEncodingBase64:
+		return base64.StdEncoding.DecodeString(body)
+	default:
+		return nil, fmt.Errorf("unknown body encoding %q", encoding)
+	}
+}
+
+// RawBody returns the received body bytes, up to the body size limit.
+func (r Request) RawBody() ([]byte, error) {
+	return DecodeBody(r.Body, r.BodyEncoding)
+}
+
+// BodyText returns the text of the body that body matchers read, decompressed when possible, or an empty string
+// for binary bodies.
+func (r Request) BodyText() string {
+	if r.DecodedBodyEncoding != "" {
+		if r.DecodedBodyEncoding == BodyEncodingUTF8 {
+			return r.DecodedBody
+		}
+		return ""
+	}
+	if r.BodyEncoding == BodyEncodingBase64 {
+		return ""
+	}
+	return r.Body
+}
+
+// DecodedBodyBytes returns the decompressed body bytes, it is the raw body when the request had no content encoding.
+func (r Request) DecodedBodyBytes() ([]byte, error) {
+	if r.DecodedBodyEncoding == "" {
+		return r.RawBody()
+	}
+	return DecodeBody(r.DecodedBody, r.DecodedBodyEncoding)
+}
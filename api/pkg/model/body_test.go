@@ -0,0 +1,87 @@
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+func TestEncodeBody(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		body             []byte
+		expectedBody     string
+		expectedEncoding string
+	}{
+		{"empty body", []byte{}, "", model.BodyEncodingUTF8},
+		{"text body", []byte(`{"key":"válue"}`), `{"key":"válue"}`, model.BodyEncodingUTF8},
+		{"binary body", []byte{0x1f, 0x8b, 0xff, 0x00}, "H4v/AA==", model.BodyEncodingBase64},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			body, encoding := model.EncodeBody(tc.body)
+			if body != tc.expectedBody {
+				t.Errorf("EncodeBody() body = %q, want %q", body, tc.expectedBody)
+			}
+			if encoding != tc.expectedEncoding {
+				t.Errorf("EncodeBody() encoding = %q, want %q", encoding, tc.expectedEncoding)
+			}
+			decoded, err := model.DecodeBody(body, encoding)
+			if err != nil {
+				t.Fatalf("DecodeBody() unexpected error: %v", err)
+			}
+			if !bytes.Equal(decoded, tc.body) {
+				t.Errorf("DecodeBody() = %v, want %v", decoded, tc.body)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyUnknownEncoding(t *testing.T) {
+	_, err := model.DecodeBody("body", "rot13")
+	if err == nil {
+		t.Error("DecodeBody() expected an error")
+	}
+}
+
+func TestRequestDecodedBodyBytes(t *testing.T) {
+	req := model.Request{Body: "H4v/AA==", BodyEncoding: model.BodyEncodingBase64}
+	b, err := req.DecodedBodyBytes()
+	if err != nil {
+		t.Fatalf("DecodedBodyBytes() unexpected error: %v", err)
+	}
+	if !bytes.Equal(b, []byte{0x1f, 0x8b, 0xff, 0x00}) {
+		t.Errorf("DecodedBodyBytes() without content encoding = %v, want the raw body", b)
+	}
+
+	req.DecodedBody = "decoded"
+	req.DecodedBodyEncoding = model.BodyEncodingUTF8
+	b, err = req.DecodedBodyBytes()
+	if err != nil {
+		t.Fatalf("DecodedBodyBytes() unexpected error: %v", err)
+	}
+	if string(b) != "decoded" {
+		t.Errorf("DecodedBodyBytes() = %q, want %q", b, "decoded")
+	}
+}
+
+func TestRequestBodyText(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		req      model.Request
+		expected string
+	}{
+		{"text body", model.Request{Body: `{"a":1}`, BodyEncoding: model.BodyEncodingUTF8}, `{"a":1}`},
+		{"binary body", model.Request{Body: "H4v/AA==", BodyEncoding: model.BodyEncodingBase64}, ""},
+		{"decoded text body", model.Request{Body: "H4sI", BodyEncoding: model.BodyEncodingBase64, DecodedBody: `{"a":1}`, DecodedBodyEncoding: model.BodyEncodingUTF8}, `{"a":1}`},
+		{"decoded binary body", model.Request{Body: "H4sI", BodyEncoding: model.BodyEncodingBase64, DecodedBody: "AA==", DecodedBodyEncoding: model.BodyEncodingBase64}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := tc.req.BodyText(); got != tc.expected {
+				t.Errorf("BodyText() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -9,6 +9,19 @@ type Callback struct {
 	Headers             map[string]string `dynamodbav:"headers"`
 	Body                string            `dynamodbav:"body"`
 	IsForwardingHeaders bool              `dynamodbav:"isForwardingHeaders"`
+	Retry               RetryPolicy       `dynamodbav:"retry"`
+	Signing             Signing           `dynamodbav:"signing"`
+}
+
+// RetryPolicy resends a callback while it fails. The zero value sends it only once.
+type RetryPolicy struct {
+	MaxAttempts int
+	// The wait before the n-th retry is InitialBackoffMillis * 2^(n-1), up to MaxBackoffMillis
+	InitialBackoffMillis int
+	MaxBackoffMillis     int
+	// RetryOnStatus are the response codes that are retried, connection errors are always retried.
+	// When it is empty 429 and 5xx responses are retried.
+	RetryOnStatus []int
 }
 
 type CallbackResponse struct {
@@ -20,6 +33,15 @@ type CallbackResponse struct {
 	CodeTemplate string
 	Body         string
 	Headers      map[string]string
+	// Attempts is the history of deliveries, the response fields are the ones of the last attempt
+	Attempts []CallbackAttempt `dynamodbav:",omitempty"`
+}
+
+type CallbackAttempt struct {
+	Timestamp      int64
+	DurationMillis int64
+	Code           int
+	Error          string
 }
 
 func NewCallback() Callback {
@@ -0,0 +1,20 @@
+package model
+
+import "github.com/google/uuid"
+
+// DeadLetter is a callback that kept failing after all its attempts, it can be sent again.
+type DeadLetter struct {
+	ID            uuid.UUID
+	Timestamp     int64
+	RequestID     int
+	CallbackIndex int
+	// Callback is the callback as it was sent, with its templates already rendered
+	Callback Callback
+	Response CallbackResponse
+}
+
+// WithoutSecrets returns the dead letter without the signing secret of its callback.
+func (dl DeadLetter) WithoutSecrets() DeadLetter {
+	dl.Callback.Signing.Secret = ""
+	return dl
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EmailDigestHourly = "hourly"
+	EmailDigestDaily  = "daily"
+)
+
+const unsubscribeTokenSize = 32
+
+// EmailPreferences are the emails a user receives about the activity of their inboxes.
+type EmailPreferences struct {
+	// Alerts send an email as soon as an inbox receives a matching request
+	Alerts []EmailAlert
+	// Digest is the period of the activity summary, hourly or daily, no digest is sent when it is empty
+	Digest string
+	// AlertTemplate and DigestTemplate render the email bodies, empty uses the default ones
+	AlertTemplate  string
+	DigestTemplate string
+	// Unsubscribed stops every email until the user saves the preferences again
+	Unsubscribed bool
+	// UnsubscribeToken authenticates the unsubscribe link of the emails
+	UnsubscribeToken string `json:"-"`
+	// LastDigest is the unix milliseconds when the last digest period ended
+	LastDigest int64
+}
+
+// EmailAlert matches the requests of an inbox like a response rule, empty fields match any request.
+type EmailAlert struct {
+	InboxID uuid.UUID
+	Method  string
+	Path    string
+	Headers map[string]string
+	Body    []BodyMatcher
+}
+
+// Rule returns the response rule that matches the same requests as the alert.
+func (a EmailAlert) Rule() ResponseRule {
+	return ResponseRule{Method: a.Method, Path: a.Path, Headers: a.Headers, Body: a.Body}
+}
+
+// DigestPeriod returns the time between digests, 0 when digests are disabled.
+func (p EmailPreferences) DigestPeriod() time.Duration {
+	switch p.Digest {
+	case EmailDigestHourly:
+		return time.Hour
+	case EmailDigestDaily:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+func NewUnsubscribeToken() (string, error) {
+	return randomString(unsubscribeTokenSize)
+}
@@ -0,0 +1,27 @@
+package model
+
+const (
+	// FaultConnectionReset closes the connection abruptly without answering
+	FaultConnectionReset = "connection_reset"
+	// FaultEmptyResponse closes the connection without writing anything
+	FaultEmptyResponse = "empty_response"
+	// FaultTruncatedBody announces the whole body length but only writes half of it
+	FaultTruncatedBody = "truncated_body"
+	// FaultSlowBody writes the body in small chunks with a pause between them
+	FaultSlowBody = "slow_body"
+)
+
+// Delay is waited before writing the response.
+// It is a random duration between MinMillis and MaxMillis when MaxMillis is greater, or MinMillis otherwise.
+type Delay struct {
+	MinMillis int
+	MaxMillis int
+}
+
+// Fault breaks the response to simulate an unreliable upstream. An empty Type answers normally.
+type Fault struct {
+	Type string
+	// ChunkBytes and IntervalMillis pace the body of FaultSlowBody, zero values use the defaults
+	ChunkBytes     int
+	IntervalMillis int
+}
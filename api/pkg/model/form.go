@@ -0,0 +1,23 @@
+package model
+
+// Form is the parsed body of multipart/form-data and application/x-www-form-urlencoded requests.
+type Form struct {
+	Fields map[string][]string
+	// Files are ordered as received, their position is the index used to download them
+	Files []FormFile
+}
+
+// FormFile describes a file part of a multipart body, its content is stored apart from the request.
+type FormFile struct {
+	FieldName   string
+	Filename    string
+	ContentType string
+	Size        int64
+	SHA256      string
+}
+
+// RequestFile is a file received in a request with its content.
+type RequestFile struct {
+	FormFile
+	Content []byte
+}
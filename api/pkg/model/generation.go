@@ -45,8 +45,24 @@ func GenerateInbox() Inbox {
 			},
 			IsDynamic: false,
 		},
+		ResponseRules: []ResponseRule{
+			{
+				Name:    "rule " + mustRandomString(5),
+				Method:  "PUT",
+				Path:    "/rules/*",
+				Query:   map[string]string{"rule": ""},
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    []BodyMatcher{{Path: "rule.enabled", Value: "true"}},
+				Response: Response{
+					Code:    201,
+					Body:    "rule response body" + mustRandomString(5),
+					Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"},
+				},
+			},
+		},
 		Requests:              []Request{GenerateRequest(1), GenerateRequest(2)},
 		ObfuscateHeaderFields: []string{"Authorization"},
+		Retention:             RetentionPolicy{MaxRequests: 100, MaxAgeSeconds: 24 * 60 * 60},
 		Callbacks: []Callback{
 			{
 				IsEnabled: true,
@@ -59,6 +75,25 @@ func GenerateInbox() Inbox {
 				Body: `{"event": "request_received"}`,
 			},
 		},
+		Proxy: Proxy{
+			IsEnabled: false,
+			ToURL:     "http://example.com/upstream",
+			Headers:   map[string]string{"X-Forwarded-By": "request-inbox"},
+		},
+		Verification: Verification{
+			IsEnabled: false,
+			Provider:  VerificationGitHub,
+			Secret:    mustRandomString(10),
+		},
+		Notifications: []Notification{
+			{
+				IsEnabled:       true,
+				Type:            NotificationSlack,
+				URL:             "http://example.com/slack",
+				Template:        "{{.Request.Method}} {{.Request.URI}}",
+				ThrottleSeconds: 60,
+			},
+		},
 		OwnerID:   uuid.Nil,
 		IsPrivate: false,
 	}
@@ -81,6 +116,8 @@ func GenerateRequest(id int) Request {
 		},
 		URI:           "http://host:80/a/path?query=param#fragment",
 		Body:          body,
+		BodyEncoding:  BodyEncodingUTF8,
+		BodySize:      int64(len(body)),
 		Host:          "localhost:8080",
 		Protocol:      "HTTP/1.1",
 		ContentLength: int64(len(body)),
@@ -105,7 +142,13 @@ func GenerateRequest(id int) Request {
 func CopyInbox(inbox Inbox) Inbox {
 	copy := inbox
 
-	copy.Response.Headers = collection.CopySimpleMap(inbox.Response.Headers)
+	copy.Response = CopyResponse(inbox.Response)
+	copy.Scenario = CopyScenario(inbox.Scenario)
+
+	copy.ResponseRules = make([]ResponseRule, len(inbox.ResponseRules))
+	for i, rule := range inbox.ResponseRules {
+		copy.ResponseRules[i] = CopyResponseRule(rule)
+	}
 
 	copy.Requests = make([]Request, len(inbox.Requests))
 	for _, req := range inbox.Requests {
@@ -114,6 +157,29 @@ func CopyInbox(inbox Inbox) Inbox {
 
 	copy.ObfuscateHeaderFields = collection.CopySlice(inbox.ObfuscateHeaderFields)
 	copy.Callbacks = collection.CopySlice(inbox.Callbacks)
+	copy.Notifications = collection.CopySlice(inbox.Notifications)
+	copy.Proxy.Headers = collection.CopySimpleMap(inbox.Proxy.Headers)
+	return copy
+}
+
+func CopyResponseRule(rule ResponseRule) ResponseRule {
+	copy := rule
+	copy.Query = collection.CopySimpleMap(rule.Query)
+	copy.Headers = collection.CopySimpleMap(rule.Headers)
+	copy.Body = collection.CopySlice(rule.Body)
+	copy.Response = CopyResponse(rule.Response)
+	return copy
+}
+
+func CopyResponse(resp Response) Response {
+	copy := resp
+	copy.Headers = collection.CopySimpleMap(resp.Headers)
+	if resp.Sequence != nil {
+		copy.Sequence = make([]Response, len(resp.Sequence))
+		for i, r := range resp.Sequence {
+			copy.Sequence[i] = CopyResponse(r)
+		}
+	}
 	return copy
 }
 
@@ -12,7 +12,8 @@ import (
 func TestGenerateInbox(t *testing.T) {
 	t.Run("it should have random values", func(t *testing.T) {
 		inbox := model.GenerateInboxWithOwner()
-		if hasEmptyField(t, inbox, nil) {
+		// The scenario is only changed by the requests the inbox answers
+		if hasEmptyField(t, inbox, []string{"Scenario"}) {
 			t.Errorf("Expected no empty fields in %+v", inbox)
 		}
 	})
@@ -30,7 +31,9 @@ func TestGenerateRequest(t *testing.T) {
 			t.Errorf("GenerateRequest(20).ID = %v, want %v", req.ID, 20)
 		}
 
-		if hasEmptyField(t, req, []string{"Path"}) {
+		// The body of generated requests is not compressed, truncated nor a form, and they are not proxied nor verified
+		ignoredFields := []string{"Path", "BodyTruncated", "DecodedBody", "DecodedBodyEncoding", "DecodedBodyTruncated", "Form", "ProxyResponse", "Verification"}
+		if hasEmptyField(t, req, ignoredFields) {
 			t.Errorf("Expected no empty fields in %+v", req)
 		}
 	})
@@ -2,6 +2,7 @@ package model
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,14 +17,27 @@ const (
 
 type Inbox struct {
 	ID                    uuid.UUID
-	Name                  string     `dynamodbav:"alias"`
-	Timestamp             int64      `dynamodbav:"unixTimestamp"`
-	Response              Response   `dynamodbav:"resp"`
-	Requests              []Request  `dynamodbav:"req"`
-	ObfuscateHeaderFields []string   `dynamodbav:"ofuscate"`
-	Callbacks             []Callback `dynamodbav:"Callbacks"`
-	OwnerID               uuid.UUID  `dynamodbav:"OwnerID"`
-	IsPrivate             bool       `dynamodbav:"IsPrivate"`
+	Name                  string          `dynamodbav:"alias"`
+	Timestamp             int64           `dynamodbav:"unixTimestamp"`
+	Response              Response        `dynamodbav:"resp"`
+	ResponseRules         []ResponseRule  `dynamodbav:"rules"`
+	Requests              []Request       `dynamodbav:"req"`
+	ObfuscateHeaderFields []string        `dynamodbav:"ofuscate"`
+	Callbacks             []Callback      `dynamodbav:"Callbacks"`
+	OwnerID               uuid.UUID       `dynamodbav:"OwnerID"`
+	IsPrivate             bool            `dynamodbav:"IsPrivate"`
+	Retention             RetentionPolicy `dynamodbav:"retention"`
+	Proxy                 Proxy           `dynamodbav:"proxy"`
+	Verification          Verification    `dynamodbav:"verification"`
+	Notifications         []Notification  `dynamodbav:"notifications"`
+	// Scenario is stored apart from the inbox, it is only updated with the repository UpdateInboxScenario method
+	Scenario Scenario `dynamodbav:"-"`
+}
+
+// RetentionPolicy limits the requests kept by an inbox. Zero values use the server defaults.
+type RetentionPolicy struct {
+	MaxRequests   int
+	MaxAgeSeconds int64
 }
 
 type Response struct {
@@ -32,20 +46,59 @@ type Response struct {
 	Body         string
 	Headers      map[string]string
 	IsDynamic    bool
+	// Sequence responses are returned one per call instead of this one, see SequenceMode
+	Sequence     []Response
+	SequenceMode string
+	Delay        Delay
+	Fault        Fault
 }
 
 type Request struct {
-	ID                int
-	Timestamp         int64 `dynamodbav:"unixTimestamp"`
-	URI               string
-	Host              string
-	RemoteAddr        string
-	Protocol          string
-	Headers           map[string][]string
-	Method            string `dynamodbav:"httpMethod"`
-	ContentLength     int64
-	Body              string
+	ID            int
+	Timestamp     int64 `dynamodbav:"unixTimestamp"`
+	URI           string
+	Host          string
+	RemoteAddr    string
+	Protocol      string
+	Headers       map[string][]string
+	Method        string `dynamodbav:"httpMethod"`
+	ContentLength int64
+	Body          string
+	// BodyEncoding is how Body represents the received bytes, utf8 or base64 for binary bodies
+	BodyEncoding string
+	// BodySize is the number of bytes received, Body only keeps up to the configured limit
+	BodySize      int64
+	BodyTruncated bool
+	// DecodedBody is the body decompressed following the Content-Encoding header, empty when there is no encoding
+	DecodedBody          string
+	DecodedBodyEncoding  string
+	DecodedBodyTruncated bool
+	// Form is set when the body is a form, nil otherwise
+	Form              *Form `dynamodbav:",omitempty"`
 	CallbackResponses []CallbackResponse
+	// CallbacksPending is set while the callbacks are delivered asynchronously, CallbackResponses is filled when they finish
+	CallbacksPending bool `dynamodbav:",omitempty"`
+	// ProxyResponse is the upstream response returned to the caller when the inbox proxy is enabled
+	ProxyResponse *ProxyResponse `dynamodbav:",omitempty"`
+	// Verification is the signature check result, nil when the inbox does not verify signatures
+	Verification *VerificationResult `dynamodbav:",omitempty"`
+}
+
+// InboxPath returns the path the request was sent to after the "/in" of the inbox, without the query.
+// It is empty when the request was sent to "/in" itself.
+func (r Request) InboxPath() string {
+	i := strings.Index(r.URI, "/in/")
+	if i == -1 {
+		i = strings.Index(r.URI, "/in?")
+	}
+	if i == -1 {
+		return ""
+	}
+	path := r.URI[i+len("/in"):]
+	if q := strings.Index(path, "?"); q != -1 {
+		return path[:q]
+	}
+	return path
 }
 
 func NewInbox() Inbox {
@@ -59,10 +112,94 @@ func NewInbox() Inbox {
 			Headers: map[string]string{ContentTypeHeader: DefaultContentTypeHeader},
 			Body:    DefaultBody,
 		},
+		ResponseRules:         []ResponseRule{},
 		Requests:              []Request{},
 		ObfuscateHeaderFields: []string{},
 		Callbacks:             []Callback{},
+		Notifications:         []Notification{},
+		Proxy:                 Proxy{Headers: map[string]string{}},
 		IsPrivate:             false,
 		OwnerID:               uuid.UUID{},
 	}
 }
+
+// WithoutSecrets returns the inbox without its secrets, they are write-only. The secrets are the signing and
+// verification secrets, the notification webhook URLs, which carry their tokens, and the values of the proxy headers.
+func (in Inbox) WithoutSecrets() Inbox {
+	in.Verification.Secret = ""
+	if in.Callbacks != nil {
+		callbacks := make([]Callback, len(in.Callbacks))
+		for i, cb := range in.Callbacks {
+			cb.Signing.Secret = ""
+			callbacks[i] = cb
+		}
+		in.Callbacks = callbacks
+	}
+	if in.Notifications != nil {
+		notifications := make([]Notification, len(in.Notifications))
+		for i, n := range in.Notifications {
+			n.URL = ""
+			notifications[i] = n
+		}
+		in.Notifications = notifications
+	}
+	if in.Proxy.Headers != nil {
+		headers := make(map[string]string, len(in.Proxy.Headers))
+		for k := range in.Proxy.Headers {
+			headers[k] = ""
+		}
+		in.Proxy.Headers = headers
+	}
+	return in
+}
+
+// KeepSecrets fills the empty secrets with the ones of the stored inbox, so an inbox read from the API can be
+// sent back without them. Callbacks are matched by URL and method, in order when several share them, so
+// removing or reordering callbacks does not hand a secret to another target.
+func (in *Inbox) KeepSecrets(stored Inbox) {
+	if in.Verification.Secret == "" {
+		in.Verification.Secret = stored.Verification.Secret
+	}
+	secrets := map[callbackTarget][]string{}
+	for _, cb := range stored.Callbacks {
+		target := callbackTarget{cb.ToURL, cb.Method}
+		secrets[target] = append(secrets[target], cb.Signing.Secret)
+	}
+	for i := range in.Callbacks {
+		cb := &in.Callbacks[i]
+		target := callbackTarget{cb.ToURL, cb.Method}
+		if len(secrets[target]) == 0 {
+			continue
+		}
+		if cb.Signing.Secret == "" {
+			cb.Signing.Secret = secrets[target][0]
+		}
+		secrets[target] = secrets[target][1:]
+	}
+	// Notifications have nothing else to tell them apart, so a URL is only kept when its type is not repeated.
+	// Otherwise the URLs have to be sent again, rather than sending the requests to another webhook.
+	storedURLs := map[string][]string{}
+	for _, n := range stored.Notifications {
+		storedURLs[n.Type] = append(storedURLs[n.Type], n.URL)
+	}
+	sentTypes := map[string]int{}
+	for _, n := range in.Notifications {
+		sentTypes[n.Type]++
+	}
+	for i := range in.Notifications {
+		n := &in.Notifications[i]
+		if n.URL == "" && len(storedURLs[n.Type]) == 1 && sentTypes[n.Type] == 1 {
+			n.URL = storedURLs[n.Type][0]
+		}
+	}
+	for k, v := range in.Proxy.Headers {
+		if v == "" {
+			in.Proxy.Headers[k] = stored.Proxy.Headers[k]
+		}
+	}
+}
+
+type callbackTarget struct {
+	url    string
+	method string
+}
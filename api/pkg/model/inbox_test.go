@@ -0,0 +1,128 @@
+package model
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestKeepSecrets(t *testing.T) {
+	stored := NewInbox()
+	stored.Callbacks = []Callback{
+		{ToURL: "https://first.dev", Method: http.MethodPost, Signing: Signing{IsEnabled: true, Secret: "first secret"}},
+		{ToURL: "https://second.dev", Method: http.MethodPost, Signing: Signing{IsEnabled: true, Secret: "second secret"}},
+		{ToURL: "https://second.dev", Method: http.MethodPut, Signing: Signing{IsEnabled: true, Secret: "put secret"}},
+	}
+
+	testCases := []struct {
+		desc      string
+		callbacks []Callback
+		expected  []string
+	}{
+		{
+			desc:      "unchanged callbacks",
+			callbacks: stored.WithoutSecrets().Callbacks,
+			expected:  []string{"first secret", "second secret", "put secret"},
+		},
+		{
+			desc:      "first callback removed",
+			callbacks: stored.WithoutSecrets().Callbacks[1:],
+			expected:  []string{"second secret", "put secret"},
+		},
+		{
+			desc: "callbacks reordered",
+			callbacks: []Callback{
+				{ToURL: "https://second.dev", Method: http.MethodPut},
+				{ToURL: "https://first.dev", Method: http.MethodPost},
+			},
+			expected: []string{"put secret", "first secret"},
+		},
+		{
+			desc: "new target does not get a secret",
+			callbacks: []Callback{
+				{ToURL: "https://other.dev", Method: http.MethodPost},
+				{ToURL: "https://first.dev", Method: http.MethodGet},
+			},
+			expected: []string{"", ""},
+		},
+		{
+			desc: "new secret is kept",
+			callbacks: []Callback{
+				{ToURL: "https://first.dev", Method: http.MethodPost, Signing: Signing{Secret: "new secret"}},
+			},
+			expected: []string{"new secret"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			in := stored.WithoutSecrets()
+			in.Callbacks = tc.callbacks
+
+			in.KeepSecrets(stored)
+
+			for i, cb := range in.Callbacks {
+				if cb.Signing.Secret != tc.expected[i] {
+					t.Errorf("Callbacks[%d].Signing.Secret = %q, want %q", i, cb.Signing.Secret, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKeepSecretsOfNotificationsAndProxy(t *testing.T) {
+	stored := NewInbox()
+	stored.Notifications = []Notification{
+		{Type: NotificationSlack, URL: "https://hooks.slack.com/services/secret"},
+		{Type: NotificationGeneric, URL: "https://first.dev/token"},
+		{Type: NotificationGeneric, URL: "https://second.dev/token"},
+	}
+	stored.Proxy.Headers = map[string]string{"Authorization": "Bearer secret"}
+
+	in := stored.WithoutSecrets()
+	for _, n := range in.Notifications {
+		if n.URL != "" {
+			t.Errorf("WithoutSecrets() kept the notification URL %q", n.URL)
+		}
+	}
+	if v := in.Proxy.Headers["Authorization"]; v != "" {
+		t.Errorf("WithoutSecrets() kept the proxy header value %q", v)
+	}
+	if stored.Notifications[0].URL == "" || stored.Proxy.Headers["Authorization"] == "" {
+		t.Error("WithoutSecrets() changed the stored inbox")
+	}
+
+	// The first webhook is removed and a header is added
+	in.Notifications = []Notification{in.Notifications[0], in.Notifications[2]}
+	in.Proxy.Headers["X-Env"] = "test"
+	in.KeepSecrets(stored)
+
+	if in.Notifications[0].URL != stored.Notifications[0].URL {
+		t.Errorf("Notifications[0].URL = %q, want %q", in.Notifications[0].URL, stored.Notifications[0].URL)
+	}
+	// Several webhooks can not be told apart, the remaining one has to be sent again
+	if in.Notifications[1].URL != "" {
+		t.Errorf("Notifications[1].URL = %q, want it empty", in.Notifications[1].URL)
+	}
+	if in.Proxy.Headers["Authorization"] != "Bearer secret" || in.Proxy.Headers["X-Env"] != "test" {
+		t.Errorf("Proxy.Headers = %v, want the stored value and the new header", in.Proxy.Headers)
+	}
+}
+
+func TestRequestInboxPath(t *testing.T) {
+	testCases := []struct {
+		uri      string
+		expected string
+	}{
+		{"/api/v1/inboxes/123/in/orders/1?page=2", "/orders/1"},
+		{"/api/v1/inboxes/123/in?page=2", ""},
+		{"/api/v1/inboxes/123/in/", "/"},
+		{"/api/v1/inboxes/123/in", ""},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			if got := (Request{URI: tc.uri}).InboxPath(); got != tc.expected {
+				t.Errorf("InboxPath() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -11,3 +11,17 @@ func NewItemList[T any](l []T) ItemList[T] {
 		Results: l,
 	}
 }
+
+type Page[T any] struct {
+	Count      int    `json:"count"`
+	Results    []T    `json:"results"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func NewPage[T any](l []T, nextCursor string) Page[T] {
+	return Page[T]{
+		Count:      len(l),
+		Results:    l,
+		NextCursor: nextCursor,
+	}
+}
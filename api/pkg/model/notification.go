@@ -0,0 +1,25 @@
+package model
+
+const (
+	// NotificationSlack posts {"text": message} to a Slack incoming webhook
+	NotificationSlack = "slack"
+	// NotificationDiscord posts {"content": message} to a Discord webhook
+	NotificationDiscord = "discord"
+	// NotificationTeams posts a message card to a Microsoft Teams connector
+	NotificationTeams = "teams"
+	// NotificationGeneric posts a JSON document with the message, the inbox and the request
+	NotificationGeneric = "generic"
+)
+
+// Notification sends a chat message when the inbox receives a request.
+type Notification struct {
+	IsEnabled bool
+	Type      string
+	URL       string
+	// Template renders the message with .Inbox, .Request and .Suppressed, empty uses a default message
+	Template string
+	// ThrottleSeconds sends at most one message per period. When the period ends, the last request received
+	// meanwhile is sent with the number of the other ones in .Suppressed, in a lambda they are counted in the
+	// next message instead. 0 uses the server default
+	ThrottleSeconds int
+}
@@ -0,0 +1,28 @@
+package model
+
+// Proxy forwards the requests of the inbox to an upstream and answers with its response instead of the inbox response.
+type Proxy struct {
+	IsEnabled bool
+	// ToURL is the upstream base URL, the path sent after "/in" and the query are appended to it
+	ToURL string
+	// Headers are set on the forwarded request over the received ones
+	Headers map[string]string
+}
+
+// ProxyResponse is the upstream response to a proxied request. Its body is kept like request bodies and its
+// headers with all their values, so it can be returned to the caller as it was received.
+type ProxyResponse struct {
+	URL    string
+	Method string
+	Error  string
+	Code   int
+	Body   string
+	// BodyEncoding is how Body represents the received bytes, utf8 or base64 for binary bodies
+	BodyEncoding string
+	Headers      map[string][]string
+}
+
+// RawBody returns the body bytes received from the upstream.
+func (r ProxyResponse) RawBody() ([]byte, error) {
+	return DecodeBody(r.Body, r.BodyEncoding)
+}
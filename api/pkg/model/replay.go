@@ -0,0 +1,9 @@
+package model
+
+// ReplayRequest resends a captured request to ToURL with its original method, headers and body.
+// Headers are set over the captured ones and Body replaces the captured body when it is set.
+type ReplayRequest struct {
+	ToURL   string
+	Headers map[string]string
+	Body    *string
+}
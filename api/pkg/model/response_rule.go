@@ -0,0 +1,25 @@
+package model
+
+// ResponseRule replaces the inbox response when a request matches all its conditions.
+// Empty conditions match every request.
+type ResponseRule struct {
+	Name   string
+	Method string
+	// Path is a glob (see path.Match) over the path sent after "/in", e.g. "/orders/*"
+	Path string
+	// Query and Headers values must be present in the request, an empty value only checks the presence
+	Query   map[string]string
+	Headers map[string]string
+	Body    []BodyMatcher
+	// State is the scenario state required by the rule, any state matches when it is empty
+	State string
+	// NextState is the scenario state after the rule answers a request, the state does not change when it is empty
+	NextState string
+	Response  Response
+}
+
+// BodyMatcher checks a gjson path of the request body, an empty Value only checks that the path exists.
+type BodyMatcher struct {
+	Path  string
+	Value string
+}
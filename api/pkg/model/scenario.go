@@ -0,0 +1,74 @@
+package model
+
+import "fmt"
+
+const (
+	// ScenarioStarted is the state of an inbox that has not transitioned yet
+	ScenarioStarted = "Started"
+
+	// SequenceModeSticky repeats the last response of the sequence once it is exhausted, it is the default mode
+	SequenceModeSticky = "sticky"
+	// SequenceModeLoop starts the sequence again once it is exhausted
+	SequenceModeLoop = "loop"
+
+	defaultResponseKey = "default"
+)
+
+// Scenario is the state of an inbox that changes with the requests it answers.
+type Scenario struct {
+	State string
+	// Calls counts the requests answered by each response with a sequence, keyed by ResponseKey
+	Calls map[string]int
+}
+
+// CurrentState returns the state of the scenario, ScenarioStarted when it has not transitioned yet.
+func (s Scenario) CurrentState() string {
+	if s.State == "" {
+		return ScenarioStarted
+	}
+	return s.State
+}
+
+// ResponseKey identifies the response of a rule, or the inbox response with a negative index, in Scenario.Calls.
+func ResponseKey(ruleIndex int) string {
+	if ruleIndex < 0 {
+		return defaultResponseKey
+	}
+	return fmt.Sprintf("rule#%d", ruleIndex)
+}
+
+// HasScenario reports whether answering a request can change the scenario of the inbox.
+func (in Inbox) HasScenario() bool {
+	if len(in.Response.Sequence) > 0 {
+		return true
+	}
+	for _, rule := range in.ResponseRules {
+		if rule.State != "" || rule.NextState != "" || len(rule.Response.Sequence) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SequenceResponse returns the response of the sequence for the given number of previous calls.
+func (r Response) SequenceResponse(calls int) Response {
+	if len(r.Sequence) == 0 {
+		return r
+	}
+	i := calls
+	if r.SequenceMode == SequenceModeLoop {
+		i = calls % len(r.Sequence)
+	} else if i >= len(r.Sequence) {
+		i = len(r.Sequence) - 1
+	}
+	return r.Sequence[i]
+}
+
+func CopyScenario(s Scenario) Scenario {
+	copy := s
+	copy.Calls = make(map[string]int, len(s.Calls))
+	for k, v := range s.Calls {
+		copy.Calls[k] = v
+	}
+	return copy
+}
@@ -0,0 +1,26 @@
+package model
+
+const (
+	SigningSHA256 = "sha256"
+	SigningSHA1   = "sha1"
+	SigningSHA512 = "sha512"
+
+	// SignatureFormatHex is the hex encoded HMAC of the body
+	SignatureFormatHex = "hex"
+	// SignatureFormatBase64 is the base64 encoded HMAC of the body
+	SignatureFormatBase64 = "base64"
+	// SignatureFormatGitHub is "<algorithm>=<hex HMAC of the body>"
+	SignatureFormatGitHub = "github"
+	// SignatureFormatStripe is "t=<unix seconds>,v1=<hex HMAC of "<unix seconds>.<body>">"
+	SignatureFormatStripe = "stripe"
+)
+
+// Signing adds an HMAC signature header to the callbacks, computed over the body after templating.
+// Empty values use sha256, the hex format and the usual header of the format.
+type Signing struct {
+	IsEnabled bool
+	Secret    string
+	Algorithm string
+	Format    string
+	Header    string
+}
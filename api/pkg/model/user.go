@@ -22,6 +22,8 @@ type User struct {
 	Organization string       `dynamodbav:"organization"`
 	Provider     UserProvider `dynamodbav:"provider" json:"-"`
 	Timestamp    int64        `dynamodbav:"unixTimestamp"`
+	// EmailPreferences are kept out of the JWT, they are read from the stored user
+	EmailPreferences EmailPreferences `dynamodbav:"emailPreferences" json:"-"`
 }
 
 const UserEntityName = "User"
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"path"
+	"regexp"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
 	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
@@ -29,7 +32,7 @@ func IsValidInbox(inbox model.Inbox) (bool, error) {
 	if inbox.Timestamp == 0 {
 		return false, &ValidationError{message: "Inbox Timestamp cannot be empty"}
 	}
-	if _, err := IsHTTPStatusCode(inbox.Response.Code); err != nil {
+	if valid, err := IsValidResponse(inbox.Response); !valid {
 		return false, err
 	}
 	if len(inbox.Callbacks) > config.GetInt(config.MaxCallbacksKey) {
@@ -40,10 +43,136 @@ func IsValidInbox(inbox model.Inbox) (bool, error) {
 			return false, err
 		}
 	}
+	if valid, err := IsValidRetentionPolicy(inbox.Retention); !valid {
+		return false, err
+	}
+	if valid, err := IsValidProxy(inbox.Proxy); !valid {
+		return false, err
+	}
+	if valid, err := IsValidVerification(inbox.Verification); !valid {
+		return false, err
+	}
+	if len(inbox.Notifications) > config.GetInt(config.MaxNotificationsKey) {
+		return false, &ValidationError{message: fmt.Sprintf("Inbox cannot have more than %d notifications", config.GetInt(config.MaxNotificationsKey))}
+	}
+	for _, n := range inbox.Notifications {
+		if valid, err := IsValidNotification(n); !valid {
+			return false, err
+		}
+	}
+	if len(inbox.ResponseRules) > config.GetInt(config.MaxResponseRulesKey) {
+		return false, &ValidationError{message: fmt.Sprintf("Inbox cannot have more than %d response rules", config.GetInt(config.MaxResponseRulesKey))}
+	}
+	for _, rule := range inbox.ResponseRules {
+		if valid, err := IsValidResponseRule(rule); !valid {
+			return false, err
+		}
+	}
 
 	return true, nil
 }
 
+func IsValidResponseRule(rule model.ResponseRule) (bool, error) {
+	if _, err := path.Match(rule.Path, ""); err != nil {
+		return false, &ValidationError{message: fmt.Sprintf("Response rule %q path %q is not a valid pattern", rule.Name, rule.Path)}
+	}
+	for k := range rule.Query {
+		if strings.TrimSpace(k) == "" {
+			return false, &ValidationError{message: fmt.Sprintf("Response rule %q query parameter name cannot be empty", rule.Name)}
+		}
+	}
+	for k := range rule.Headers {
+		if strings.TrimSpace(k) == "" {
+			return false, &ValidationError{message: fmt.Sprintf("Response rule %q header name cannot be empty", rule.Name)}
+		}
+	}
+	for _, m := range rule.Body {
+		if strings.TrimSpace(m.Path) == "" {
+			return false, &ValidationError{message: fmt.Sprintf("Response rule %q body path cannot be empty", rule.Name)}
+		}
+	}
+	if valid, err := IsValidResponse(rule.Response); !valid {
+		return false, err
+	}
+	return true, nil
+}
+
+func IsValidResponse(resp model.Response) (bool, error) {
+	if valid, err := isValidSingleResponse(resp); !valid {
+		return false, err
+	}
+	if resp.SequenceMode != "" && resp.SequenceMode != model.SequenceModeSticky && resp.SequenceMode != model.SequenceModeLoop {
+		return false, &ValidationError{message: fmt.Sprintf("Response sequence mode %q is not valid", resp.SequenceMode)}
+	}
+	if len(resp.Sequence) > config.GetInt(config.MaxResponseSequenceKey) {
+		return false, &ValidationError{message: fmt.Sprintf("Response sequence cannot have more than %d responses", config.GetInt(config.MaxResponseSequenceKey))}
+	}
+	for _, r := range resp.Sequence {
+		if len(r.Sequence) > 0 {
+			return false, &ValidationError{message: "Responses of a sequence cannot have a sequence"}
+		}
+		if valid, err := isValidSingleResponse(r); !valid {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func isValidSingleResponse(resp model.Response) (bool, error) {
+	if _, err := IsHTTPStatusCode(resp.Code); err != nil {
+		return false, err
+	}
+	if valid, err := IsValidDelay(resp.Delay); !valid {
+		return false, err
+	}
+	return IsValidFault(resp.Fault)
+}
+
+func IsValidDelay(d model.Delay) (bool, error) {
+	if d.MinMillis < 0 || d.MaxMillis < 0 {
+		return false, &ValidationError{message: "Response delay cannot be negative"}
+	}
+	if d.MaxMillis != 0 && d.MaxMillis < d.MinMillis {
+		return false, &ValidationError{message: "Response maximum delay cannot be lower than the minimum"}
+	}
+	maxDelay := config.GetInt(config.MaxResponseDelayMillisKey)
+	if d.MinMillis > maxDelay || d.MaxMillis > maxDelay {
+		return false, &ValidationError{message: fmt.Sprintf("Response delay cannot be longer than %d milliseconds", maxDelay)}
+	}
+	return true, nil
+}
+
+func IsValidFault(f model.Fault) (bool, error) {
+	switch f.Type {
+	case "", model.FaultConnectionReset, model.FaultEmptyResponse, model.FaultTruncatedBody, model.FaultSlowBody:
+	default:
+		return false, &ValidationError{message: fmt.Sprintf("Response fault %q is not valid", f.Type)}
+	}
+	if f.ChunkBytes < 0 || f.IntervalMillis < 0 {
+		return false, &ValidationError{message: "Response fault values cannot be negative"}
+	}
+	maxSlowBody := config.GetInt(config.MaxSlowBodyMillisKey)
+	if f.IntervalMillis > maxSlowBody {
+		return false, &ValidationError{message: fmt.Sprintf("Response fault interval cannot be longer than %d milliseconds", maxSlowBody)}
+	}
+	return true, nil
+}
+
+func IsValidRetentionPolicy(p model.RetentionPolicy) (bool, error) {
+	if p.MaxRequests < 0 || p.MaxAgeSeconds < 0 {
+		return false, &ValidationError{message: "Retention values cannot be negative"}
+	}
+	maxRequests := config.GetInt(config.RetentionMaxRequests)
+	if maxRequests > 0 && p.MaxRequests > maxRequests {
+		return false, &ValidationError{message: fmt.Sprintf("Inbox cannot keep more than %d requests", maxRequests)}
+	}
+	maxAge := int64(config.GetInt(config.RetentionMaxAgeSeconds))
+	if maxAge > 0 && p.MaxAgeSeconds > maxAge {
+		return false, &ValidationError{message: fmt.Sprintf("Inbox cannot keep requests for more than %d seconds", maxAge)}
+	}
+	return true, nil
+}
+
 func IsValidCallback(cb model.Callback) (bool, error) {
 	if !cb.IsEnabled {
 		return true, nil
@@ -51,6 +180,152 @@ func IsValidCallback(cb model.Callback) (bool, error) {
 	if _, err := IsValidCallbackURL(cb.ToURL); err != nil {
 		return false, err
 	}
+	if _, err := IsValidRetryPolicy(cb.Retry); err != nil {
+		return false, err
+	}
+	if _, err := IsValidSigning(cb.Signing); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func IsValidSigning(s model.Signing) (bool, error) {
+	if !s.IsEnabled {
+		return true, nil
+	}
+	if s.Secret == "" {
+		return false, &ValidationError{message: "Signing secret cannot be empty"}
+	}
+	switch s.Algorithm {
+	case "", model.SigningSHA256, model.SigningSHA1, model.SigningSHA512:
+	default:
+		return false, &ValidationError{message: fmt.Sprintf("Signing algorithm %q is not valid", s.Algorithm)}
+	}
+	switch s.Format {
+	case "", model.SignatureFormatHex, model.SignatureFormatBase64, model.SignatureFormatGitHub, model.SignatureFormatStripe:
+	default:
+		return false, &ValidationError{message: fmt.Sprintf("Signature format %q is not valid", s.Format)}
+	}
+	if s.Header != "" && strings.ContainsAny(s.Header, " :\t\r\n") {
+		return false, &ValidationError{message: fmt.Sprintf("Signature header %q is not valid", s.Header)}
+	}
+	return true, nil
+}
+
+func IsValidRetryPolicy(p model.RetryPolicy) (bool, error) {
+	if p.MaxAttempts < 0 || p.InitialBackoffMillis < 0 || p.MaxBackoffMillis < 0 {
+		return false, &ValidationError{message: "Callback retry values cannot be negative"}
+	}
+	maxAttempts := config.GetInt(config.CallbackMaxAttempts)
+	if p.MaxAttempts > maxAttempts {
+		return false, &ValidationError{message: fmt.Sprintf("Callback cannot be sent more than %d times", maxAttempts)}
+	}
+	for _, code := range p.RetryOnStatus {
+		if _, err := IsHTTPStatusCode(code); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func IsValidProxy(p model.Proxy) (bool, error) {
+	if !p.IsEnabled {
+		return true, nil
+	}
+	if _, err := IsValidCallbackURL(p.ToURL); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func IsValidVerification(v model.Verification) (bool, error) {
+	if !v.IsEnabled {
+		return true, nil
+	}
+	switch v.Provider {
+	case model.VerificationGitHub, model.VerificationStripe, model.VerificationSlack, model.VerificationShopify:
+	case model.VerificationCustom:
+		if _, err := IsValidSigning(model.Signing{IsEnabled: true, Secret: v.Secret, Algorithm: v.Algorithm, Format: v.Format, Header: v.Header}); err != nil {
+			return false, err
+		}
+	default:
+		return false, &ValidationError{message: fmt.Sprintf("Verification provider %q is not valid", v.Provider)}
+	}
+	if v.Secret == "" {
+		return false, &ValidationError{message: "Verification secret cannot be empty"}
+	}
+	if v.ToleranceSeconds < 0 {
+		return false, &ValidationError{message: "Verification tolerance cannot be negative"}
+	}
+	if v.RejectStatus != 0 {
+		if _, err := IsHTTPStatusCode(v.RejectStatus); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func IsValidNotification(n model.Notification) (bool, error) {
+	if !n.IsEnabled {
+		return true, nil
+	}
+	switch n.Type {
+	case model.NotificationSlack, model.NotificationDiscord, model.NotificationTeams, model.NotificationGeneric:
+	default:
+		return false, &ValidationError{message: fmt.Sprintf("Notification type %q is not valid", n.Type)}
+	}
+	if valid, err := IsValidCallbackURL(n.URL); !valid {
+		return false, err
+	}
+	if n.ThrottleSeconds < 0 {
+		return false, &ValidationError{message: "Notification throttle cannot be negative"}
+	}
+	return true, nil
+}
+
+func IsValidEmailPreferences(p model.EmailPreferences) (bool, error) {
+	switch p.Digest {
+	case "", model.EmailDigestHourly, model.EmailDigestDaily:
+	default:
+		return false, &ValidationError{message: fmt.Sprintf("Email digest %q is not valid, use %q or %q", p.Digest, model.EmailDigestHourly, model.EmailDigestDaily)}
+	}
+	if len(p.Alerts) > config.GetInt(config.MaxEmailAlertsKey) {
+		return false, &ValidationError{message: fmt.Sprintf("Cannot have more than %d email alerts", config.GetInt(config.MaxEmailAlertsKey))}
+	}
+	for _, a := range p.Alerts {
+		if a.InboxID == uuid.Nil {
+			return false, &ValidationError{message: "Email alert inbox ID cannot be empty"}
+		}
+		if _, err := path.Match(a.Path, ""); err != nil {
+			return false, &ValidationError{message: fmt.Sprintf("Email alert path %q is not a valid pattern", a.Path)}
+		}
+	}
+	return true, nil
+}
+
+func IsValidAssertion(a model.Assertion) (bool, error) {
+	if a.Count < 0 {
+		return false, &ValidationError{message: "Assertion count cannot be negative"}
+	}
+	if a.TimeoutSeconds < 0 || a.TimeoutSeconds > config.GetInt(config.AssertionMaxTimeoutSeconds) {
+		return false, &ValidationError{message: fmt.Sprintf("Assertion timeout should be between 0 and %d seconds", config.GetInt(config.AssertionMaxTimeoutSeconds))}
+	}
+	if _, err := path.Match(a.Matcher.Path, ""); err != nil {
+		return false, &ValidationError{message: fmt.Sprintf("Matcher path %q is not a valid pattern", a.Matcher.Path)}
+	}
+	for _, h := range a.Matcher.Headers {
+		if strings.TrimSpace(h.Name) == "" {
+			return false, &ValidationError{message: "Matcher header name cannot be empty"}
+		}
+		if _, err := regexp.Compile(h.Regex); err != nil {
+			return false, &ValidationError{message: fmt.Sprintf("Matcher header %q regex is not valid: %v", h.Name, err)}
+		}
+	}
+	for _, b := range a.Matcher.Body {
+		if strings.TrimSpace(b.Path) == "" {
+			return false, &ValidationError{message: "Matcher body path cannot be empty"}
+		}
+	}
 	return true, nil
 }
 
@@ -68,6 +343,28 @@ func IsAPIKey(code string) (bool, error) {
 	return true, nil
 }
 
+func IsValidAPIKeyPermissions(permissions model.APIKeyPermissions) (bool, error) {
+	granted := 0
+	for domain, actions := range permissions {
+		validActions, ok := model.PermissionActionsByDomain[domain]
+		if !ok {
+			return false, &ValidationError{message: fmt.Sprintf("Unknown permission domain %q", domain)}
+		}
+		for action, allowed := range actions {
+			if !collection.SliceContains(validActions, action) {
+				return false, &ValidationError{message: fmt.Sprintf("Unknown permission action %q for domain %q", action, domain)}
+			}
+			if allowed {
+				granted++
+			}
+		}
+	}
+	if granted == 0 {
+		return false, &ValidationError{message: "API key must grant at least one permission"}
+	}
+	return true, nil
+}
+
 func IsValidCallbackURL(urlStr string) (bool, error) {
 	if !config.GetBool(config.EnableCallbackURLValidation) {
 		return true, nil
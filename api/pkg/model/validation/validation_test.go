@@ -3,7 +3,9 @@ package validation
 import (
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
 
 func TestIsHTTPStatusCode(t *testing.T) {
@@ -311,3 +313,363 @@ func TestIsSelfURL(t *testing.T) {
 		})
 	}
 }
+
+func TestIsValidAPIKeyPermissions(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		permissions model.APIKeyPermissions
+		isValid     bool
+	}{
+		{desc: "Full access", permissions: model.NewFullAccessPermissions(), isValid: true},
+		{desc: "Read only", permissions: model.APIKeyPermissions{model.Inboxes: {model.Read: true}}, isValid: true},
+		{desc: "Nothing granted", permissions: model.APIKeyPermissions{model.Inboxes: {model.Read: false}}, isValid: false},
+		{desc: "Empty", permissions: model.APIKeyPermissions{}, isValid: false},
+		{desc: "Unknown domain", permissions: model.APIKeyPermissions{"Users": {model.Read: true}}, isValid: false},
+		{desc: "Unknown action", permissions: model.APIKeyPermissions{model.Inboxes: {"Share": true}}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidAPIKeyPermissions(tc.permissions)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidRetentionPolicy(t *testing.T) {
+	config.LoadConfig(config.Test)
+	testCases := []struct {
+		desc    string
+		policy  model.RetentionPolicy
+		isValid bool
+	}{
+		{desc: "Server defaults", policy: model.RetentionPolicy{}, isValid: true},
+		{desc: "Within limits", policy: model.RetentionPolicy{MaxRequests: 10, MaxAgeSeconds: 60}, isValid: true},
+		{desc: "At the limits", policy: model.RetentionPolicy{
+			MaxRequests:   config.RetentionMaxRequestsDefault,
+			MaxAgeSeconds: int64(config.RetentionMaxAgeSecondsDefault),
+		}, isValid: true},
+		{desc: "Negative max requests", policy: model.RetentionPolicy{MaxRequests: -1}, isValid: false},
+		{desc: "Negative max age", policy: model.RetentionPolicy{MaxAgeSeconds: -1}, isValid: false},
+		{desc: "Too many requests", policy: model.RetentionPolicy{MaxRequests: config.RetentionMaxRequestsDefault + 1}, isValid: false},
+		{desc: "Too old requests", policy: model.RetentionPolicy{MaxAgeSeconds: int64(config.RetentionMaxAgeSecondsDefault) + 1}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidRetentionPolicy(tc.policy)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidResponseRule(t *testing.T) {
+	validResponse := model.Response{Code: 200}
+	testCases := []struct {
+		desc    string
+		rule    model.ResponseRule
+		isValid bool
+	}{
+		{desc: "Empty rule", rule: model.ResponseRule{Response: validResponse}, isValid: true},
+		{desc: "Complete rule", rule: model.ResponseRule{
+			Name:     "paid orders",
+			Method:   "POST",
+			Path:     "/orders/*",
+			Query:    map[string]string{"status": "paid"},
+			Headers:  map[string]string{"X-Tenant": ""},
+			Body:     []model.BodyMatcher{{Path: "order.paid", Value: "true"}},
+			Response: validResponse,
+		}, isValid: true},
+		{desc: "Invalid path pattern", rule: model.ResponseRule{Path: "/orders/[", Response: validResponse}, isValid: false},
+		{desc: "Empty query name", rule: model.ResponseRule{Query: map[string]string{" ": "a"}, Response: validResponse}, isValid: false},
+		{desc: "Empty header name", rule: model.ResponseRule{Headers: map[string]string{"": "a"}, Response: validResponse}, isValid: false},
+		{desc: "Empty body path", rule: model.ResponseRule{Body: []model.BodyMatcher{{Value: "a"}}, Response: validResponse}, isValid: false},
+		{desc: "Invalid status code", rule: model.ResponseRule{Response: model.Response{Code: 42}}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidResponseRule(tc.rule)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidInboxResponseRulesLimit(t *testing.T) {
+	config.LoadConfig(config.Test)
+	inbox := model.GenerateInbox()
+	inbox.Callbacks = []model.Callback{}
+	if valid, err := IsValidInbox(inbox); !valid {
+		t.Fatalf("Expected a valid inbox, got %v", err)
+	}
+	inbox.ResponseRules = make([]model.ResponseRule, config.MaxResponseRulesDefault+1)
+	for i := range inbox.ResponseRules {
+		inbox.ResponseRules[i] = model.ResponseRule{Response: model.Response{Code: 200}}
+	}
+	if valid, _ := IsValidInbox(inbox); valid {
+		t.Error("Expected an inbox with too many response rules to be invalid")
+	}
+}
+
+func TestIsValidResponse(t *testing.T) {
+	config.LoadConfig(config.Test)
+	testCases := []struct {
+		desc    string
+		resp    model.Response
+		isValid bool
+	}{
+		{desc: "Without sequence", resp: model.Response{Code: 200}, isValid: true},
+		{desc: "Sticky sequence", resp: model.Response{Code: 200, Sequence: []model.Response{{Code: 503}, {Code: 200}}}, isValid: true},
+		{desc: "Loop sequence", resp: model.Response{Code: 200, SequenceMode: model.SequenceModeLoop, Sequence: []model.Response{{Code: 503}}}, isValid: true},
+		{desc: "Unknown sequence mode", resp: model.Response{Code: 200, SequenceMode: "shuffle", Sequence: []model.Response{{Code: 503}}}, isValid: false},
+		{desc: "Invalid sequence status code", resp: model.Response{Code: 200, Sequence: []model.Response{{Code: 42}}}, isValid: false},
+		{desc: "Nested sequence", resp: model.Response{Code: 200, Sequence: []model.Response{{Code: 200, Sequence: []model.Response{{Code: 200}}}}}, isValid: false},
+		{desc: "Random delay", resp: model.Response{Code: 200, Delay: model.Delay{MinMillis: 10, MaxMillis: 100}}, isValid: true},
+		{desc: "Negative delay", resp: model.Response{Code: 200, Delay: model.Delay{MinMillis: -1}}, isValid: false},
+		{desc: "Maximum delay lower than minimum", resp: model.Response{Code: 200, Delay: model.Delay{MinMillis: 100, MaxMillis: 10}}, isValid: false},
+		{desc: "Too long delay", resp: model.Response{Code: 200, Delay: model.Delay{MinMillis: config.MaxResponseDelayMillisDefault + 1}}, isValid: false},
+		{desc: "Slow body fault", resp: model.Response{Code: 200, Fault: model.Fault{Type: model.FaultSlowBody, ChunkBytes: 1, IntervalMillis: 100}}, isValid: true},
+		{desc: "Unknown fault", resp: model.Response{Code: 200, Fault: model.Fault{Type: "explode"}}, isValid: false},
+		{desc: "Too long fault interval", resp: model.Response{Code: 200, Fault: model.Fault{Type: model.FaultSlowBody, IntervalMillis: config.MaxSlowBodyMillisDefault + 1}}, isValid: false},
+		{desc: "Invalid fault in sequence", resp: model.Response{Code: 200, Sequence: []model.Response{{Code: 200, Fault: model.Fault{Type: "explode"}}}}, isValid: false},
+		{desc: "Too long sequence", resp: model.Response{Code: 200, Sequence: make([]model.Response, config.MaxResponseSequenceDefault+1)}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidResponse(tc.resp)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidProxy(t *testing.T) {
+	config.LoadConfig(config.Test)
+	testCases := []struct {
+		desc    string
+		proxy   model.Proxy
+		isValid bool
+	}{
+		{desc: "Disabled proxy without URL", proxy: model.Proxy{}, isValid: true},
+		{desc: "Enabled proxy", proxy: model.Proxy{IsEnabled: true, ToURL: "https://example.com/api"}, isValid: true},
+		{desc: "Enabled proxy without URL", proxy: model.Proxy{IsEnabled: true}, isValid: false},
+		{desc: "Enabled proxy to a private address", proxy: model.Proxy{IsEnabled: true, ToURL: "http://127.0.0.1:8080"}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidProxy(tc.proxy)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidRetryPolicy(t *testing.T) {
+	config.LoadConfig(config.Test)
+	testCases := []struct {
+		desc    string
+		policy  model.RetryPolicy
+		isValid bool
+	}{
+		{desc: "Empty policy", policy: model.RetryPolicy{}, isValid: true},
+		{desc: "Full policy", policy: model.RetryPolicy{MaxAttempts: 3, InitialBackoffMillis: 100, MaxBackoffMillis: 1000, RetryOnStatus: []int{500, 503}}, isValid: true},
+		{desc: "Negative attempts", policy: model.RetryPolicy{MaxAttempts: -1}, isValid: false},
+		{desc: "Negative backoff", policy: model.RetryPolicy{InitialBackoffMillis: -1}, isValid: false},
+		{desc: "Too many attempts", policy: model.RetryPolicy{MaxAttempts: config.CallbackMaxAttemptsDefault + 1}, isValid: false},
+		{desc: "Invalid status code", policy: model.RetryPolicy{RetryOnStatus: []int{42}}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidRetryPolicy(tc.policy)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidSigning(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		signing model.Signing
+		isValid bool
+	}{
+		{desc: "Disabled signing without secret", signing: model.Signing{}, isValid: true},
+		{desc: "Defaults", signing: model.Signing{IsEnabled: true, Secret: "s"}, isValid: true},
+		{desc: "Stripe sha512", signing: model.Signing{IsEnabled: true, Secret: "s", Format: model.SignatureFormatStripe, Algorithm: model.SigningSHA512}, isValid: true},
+		{desc: "Empty secret", signing: model.Signing{IsEnabled: true}, isValid: false},
+		{desc: "Unknown algorithm", signing: model.Signing{IsEnabled: true, Secret: "s", Algorithm: "md5"}, isValid: false},
+		{desc: "Unknown format", signing: model.Signing{IsEnabled: true, Secret: "s", Format: "jwt"}, isValid: false},
+		{desc: "Invalid header", signing: model.Signing{IsEnabled: true, Secret: "s", Header: "X Signature"}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidSigning(tc.signing)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidVerification(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		verification model.Verification
+		isValid      bool
+	}{
+		{desc: "Disabled verification", verification: model.Verification{Provider: "unknown"}, isValid: true},
+		{desc: "GitHub", verification: model.Verification{IsEnabled: true, Provider: model.VerificationGitHub, Secret: "s"}, isValid: true},
+		{desc: "Custom", verification: model.Verification{IsEnabled: true, Provider: model.VerificationCustom, Secret: "s", Format: model.SignatureFormatBase64, Header: "X-Sig"}, isValid: true},
+		{desc: "Rejecting", verification: model.Verification{IsEnabled: true, Provider: model.VerificationSlack, Secret: "s", RejectStatus: 401}, isValid: true},
+		{desc: "Unknown provider", verification: model.Verification{IsEnabled: true, Provider: "gitlab", Secret: "s"}, isValid: false},
+		{desc: "Empty secret", verification: model.Verification{IsEnabled: true, Provider: model.VerificationStripe}, isValid: false},
+		{desc: "Custom with unknown format", verification: model.Verification{IsEnabled: true, Provider: model.VerificationCustom, Secret: "s", Format: "jwt"}, isValid: false},
+		{desc: "Negative tolerance", verification: model.Verification{IsEnabled: true, Provider: model.VerificationStripe, Secret: "s", ToleranceSeconds: -1}, isValid: false},
+		{desc: "Invalid reject status", verification: model.Verification{IsEnabled: true, Provider: model.VerificationShopify, Secret: "s", RejectStatus: 42}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidVerification(tc.verification)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidNotification(t *testing.T) {
+	config.LoadConfig(config.Test)
+	testCases := []struct {
+		desc         string
+		notification model.Notification
+		isValid      bool
+	}{
+		{desc: "Disabled notification", notification: model.Notification{Type: "unknown"}, isValid: true},
+		{desc: "Slack", notification: model.Notification{IsEnabled: true, Type: model.NotificationSlack, URL: "https://hooks.slack.com/services/T/B/X"}, isValid: true},
+		{desc: "Teams with throttle", notification: model.Notification{IsEnabled: true, Type: model.NotificationTeams, URL: "https://example.webhook.office.com/x", ThrottleSeconds: 300}, isValid: true},
+		{desc: "Unknown type", notification: model.Notification{IsEnabled: true, Type: "irc", URL: "https://example.com"}, isValid: false},
+		{desc: "Empty URL", notification: model.Notification{IsEnabled: true, Type: model.NotificationDiscord}, isValid: false},
+		{desc: "Invalid scheme", notification: model.Notification{IsEnabled: true, Type: model.NotificationGeneric, URL: "ftp://example.com"}, isValid: false},
+		{desc: "Negative throttle", notification: model.Notification{IsEnabled: true, Type: model.NotificationSlack, URL: "https://example.com", ThrottleSeconds: -1}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidNotification(tc.notification)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidEmailPreferences(t *testing.T) {
+	config.LoadConfig(config.Test)
+	inboxID := uuid.New()
+	testCases := []struct {
+		desc        string
+		preferences model.EmailPreferences
+		isValid     bool
+	}{
+		{desc: "Empty preferences", preferences: model.EmailPreferences{}, isValid: true},
+		{desc: "Daily digest", preferences: model.EmailPreferences{Digest: model.EmailDigestDaily}, isValid: true},
+		{desc: "Alert", preferences: model.EmailPreferences{Alerts: []model.EmailAlert{{InboxID: inboxID, Method: "POST", Path: "/orders/*"}}}, isValid: true},
+		{desc: "Unknown digest", preferences: model.EmailPreferences{Digest: "weekly"}, isValid: false},
+		{desc: "Alert without inbox", preferences: model.EmailPreferences{Alerts: []model.EmailAlert{{Method: "POST"}}}, isValid: false},
+		{desc: "Alert with invalid path", preferences: model.EmailPreferences{Alerts: []model.EmailAlert{{InboxID: inboxID, Path: "/orders/["}}}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidEmailPreferences(tc.preferences)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
+
+func TestIsValidAssertion(t *testing.T) {
+	config.LoadConfig(config.Test)
+	testCases := []struct {
+		desc      string
+		assertion model.Assertion
+		isValid   bool
+	}{
+		{desc: "Empty assertion", assertion: model.Assertion{}, isValid: true},
+		{desc: "Full matcher", assertion: model.Assertion{
+			Matcher: model.RequestMatcher{
+				Method:  "POST",
+				Path:    "/orders/*",
+				Headers: []model.HeaderMatcher{{Name: "Authorization", Regex: "^Bearer .+$"}},
+				Body:    []model.BodyMatcher{{Path: "items.#", Value: "2"}},
+			},
+			Count:          3,
+			Exactly:        true,
+			TimeoutSeconds: 5,
+		}, isValid: true},
+		{desc: "Negative count", assertion: model.Assertion{Count: -1}, isValid: false},
+		{desc: "Negative timeout", assertion: model.Assertion{TimeoutSeconds: -1}, isValid: false},
+		{desc: "Timeout too long", assertion: model.Assertion{TimeoutSeconds: 3600}, isValid: false},
+		{desc: "Invalid path", assertion: model.Assertion{Matcher: model.RequestMatcher{Path: "/orders/["}}, isValid: false},
+		{desc: "Header without name", assertion: model.Assertion{Matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Equals: "x"}}}}, isValid: false},
+		{desc: "Invalid header regex", assertion: model.Assertion{Matcher: model.RequestMatcher{Headers: []model.HeaderMatcher{{Name: "X", Regex: "("}}}}, isValid: false},
+		{desc: "Body without path", assertion: model.Assertion{Matcher: model.RequestMatcher{Body: []model.BodyMatcher{{Value: "x"}}}}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := IsValidAssertion(tc.assertion)
+			if got != tc.isValid {
+				t.Errorf("Expected %v, got %v", tc.isValid, got)
+			}
+			if tc.isValid == (err != nil) {
+				t.Errorf("Unexpected error value %v", err)
+			}
+		})
+	}
+}
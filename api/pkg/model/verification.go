@@ -0,0 +1,39 @@
+package model
+
+const (
+	// VerificationGitHub checks X-Hub-Signature-256, or X-Hub-Signature for sha1 signatures
+	VerificationGitHub = "github"
+	// VerificationStripe checks the Stripe-Signature timestamp and v1 signatures
+	VerificationStripe = "stripe"
+	// VerificationSlack checks X-Slack-Signature over "v0:<X-Slack-Request-Timestamp>:<body>"
+	VerificationSlack = "slack"
+	// VerificationShopify checks the base64 signature of X-Shopify-Hmac-Sha256
+	VerificationShopify = "shopify"
+	// VerificationCustom checks a signature with the algorithm, format and header of the verification
+	VerificationCustom = "custom"
+
+	VerificationValid   = "valid"
+	VerificationInvalid = "invalid"
+	VerificationMissing = "missing"
+)
+
+// Verification checks the signature of the requests received by the inbox.
+type Verification struct {
+	IsEnabled bool
+	Provider  string
+	Secret    string
+	// Algorithm, Format and Header describe the signature of the custom provider, as in Signing
+	Algorithm string
+	Format    string
+	Header    string
+	// ToleranceSeconds is the accepted age of signed timestamps, 0 uses the server default
+	ToleranceSeconds int
+	// RejectStatus answers requests that are not validly signed with this status, 0 accepts them
+	RejectStatus int
+}
+
+type VerificationResult struct {
+	Provider string
+	Status   string
+	Reason   string
+}
@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database/option"
+	"github.com/jesusnoseq/request-inbox/pkg/dynamic_response"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// DefaultAlertTemplate is the body of the alerts without a template.
+const DefaultAlertTemplate = `{{.Inbox.Name}} received {{.Request.Method}} {{.Request.URI}}` +
+	`{{if .Suppressed}}, and {{.Suppressed}} more matching requests since the last alert{{end}}.
+
+Headers:
+{{range $name, $values := .Request.Headers}}  {{$name}}: {{join $values ", "}}
+{{end}}
+Body:
+{{.Request.Body}}
+`
+
+// DefaultDigestTemplate is the body of the digests without a template.
+const DefaultDigestTemplate = `Your inboxes received {{.Total}} requests between {{.From.Format "2006-01-02 15:04 MST"}} and {{.To.Format "2006-01-02 15:04 MST"}}.
+{{range .Inboxes}}
+{{.Inbox.Name}}: {{.Count}}{{if .HasMore}}+{{end}} requests
+{{range .Latest}}  {{.Method}} {{.URI}}
+{{end}}{{end}}`
+
+// digestLatest is the number of requests of each inbox listed in a digest.
+const digestLatest = 5
+
+type EmailStore interface {
+	GetUser(context.Context, uuid.UUID) (model.User, error)
+	UpsertUser(context.Context, model.User) (bool, error)
+	ListInbox(context.Context) ([]model.Inbox, error)
+	ListInboxRequests(context.Context, uuid.UUID, ...option.ListRequestsOption) (model.Page[model.Request], error)
+}
+
+// DigestInbox is the activity of an inbox in a digest.
+type DigestInbox struct {
+	Inbox model.Inbox
+	Count int
+	// HasMore is set when the inbox received more requests than the ones counted
+	HasMore bool
+	Latest  []model.Request
+}
+
+// EmailNotifier sends the email alerts and digests of the inbox owners following their preferences.
+type EmailNotifier struct {
+	store    EmailStore
+	mailer   Mailer
+	now      func() time.Time
+	throttle *throttler
+}
+
+// NewEmailNotifier returns nil when there is no mailer, so emails are disabled.
+func NewEmailNotifier(store EmailStore, mailer Mailer) *EmailNotifier {
+	if mailer == nil {
+		return nil
+	}
+	return &EmailNotifier{
+		store:    store,
+		mailer:   mailer,
+		now:      time.Now,
+		throttle: newThrottler(),
+	}
+}
+
+// Alert emails the owner of the inbox when one of their alerts matches the request.
+// Alerts of the same inbox are throttled, the next one counts the suppressed requests.
+func (e *EmailNotifier) Alert(ctx context.Context, inbox model.Inbox, request model.Request) {
+	user, err := e.store.GetUser(ctx, inbox.OwnerID)
+	if err != nil {
+		slog.Debug("inbox owner not found for email alerts", "error", err, "inbox_id", inbox.ID)
+		return
+	}
+	prefs := user.EmailPreferences
+	if prefs.Unsubscribed || user.Email == "" || !matchAlerts(prefs.Alerts, inbox.ID, request) {
+		return
+	}
+	period := time.Duration(config.GetInt(config.EmailAlertThrottleSeconds)) * time.Second
+	suppressed, ok := e.throttle.allow(windowKey{inboxID: inbox.ID}, e.now(), period, nil)
+	if !ok {
+		return
+	}
+	body, err := dynamic_response.ParseEmail(orDefault(prefs.AlertTemplate, DefaultAlertTemplate), map[string]any{
+		"User":       user,
+		"Inbox":      &inbox,
+		"Request":    request,
+		"Suppressed": suppressed,
+	})
+	if err != nil {
+		slog.Error("error parsing email alert", "error", err, "inbox_id", inbox.ID, "user_id", user.ID)
+		return
+	}
+	subject := fmt.Sprintf("[Request Inbox] %s received %s %s", inbox.Name, request.Method, request.URI)
+	if err := e.send(ctx, user, subject, body); err != nil {
+		slog.Error("error sending email alert", "error", err, "inbox_id", inbox.ID, "user_id", user.ID)
+	}
+}
+
+func matchAlerts(alerts []model.EmailAlert, inboxID uuid.UUID, request model.Request) bool {
+	for _, a := range alerts {
+		if a.InboxID == inboxID && dynamic_response.MatchRule(a.Rule(), request) {
+			return true
+		}
+	}
+	return false
+}
+
+// SendDigests emails the digest of every owner whose digest period has ended and returns the number sent.
+func (e *EmailNotifier) SendDigests(ctx context.Context) (int, error) {
+	inboxes, err := e.store.ListInbox(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byOwner := map[uuid.UUID][]model.Inbox{}
+	for _, inbox := range inboxes {
+		if inbox.OwnerID != uuid.Nil {
+			byOwner[inbox.OwnerID] = append(byOwner[inbox.OwnerID], inbox)
+		}
+	}
+	sent := 0
+	for ownerID, owned := range byOwner {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		ok, err := e.sendDigest(ctx, ownerID, owned)
+		if err != nil {
+			slog.Error("error sending email digest", "error", err, "user_id", ownerID)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendDigest emails the activity of the inboxes since the last digest, nothing is sent when there was none.
+func (e *EmailNotifier) sendDigest(ctx context.Context, ownerID uuid.UUID, inboxes []model.Inbox) (bool, error) {
+	user, err := e.store.GetUser(ctx, ownerID)
+	if err != nil {
+		return false, nil
+	}
+	prefs := user.EmailPreferences
+	period := prefs.DigestPeriod()
+	if period == 0 || prefs.Unsubscribed || user.Email == "" {
+		return false, nil
+	}
+	now := e.now()
+	from := time.UnixMilli(prefs.LastDigest)
+	if prefs.LastDigest == 0 {
+		from = now.Add(-period)
+	}
+	if now.Sub(from) < period {
+		return false, nil
+	}
+
+	total := 0
+	activity := []DigestInbox{}
+	for _, inbox := range inboxes {
+		page, err := e.store.ListInboxRequests(ctx, inbox.ID,
+			option.WithTimeRange(from.UnixMilli()+1, now.UnixMilli()),
+			option.WithOrder(option.OrderDesc),
+			option.WithLimit(option.MaxRequestsLimit))
+		if err != nil {
+			return false, err
+		}
+		if page.Count == 0 {
+			continue
+		}
+		total += page.Count
+		activity = append(activity, DigestInbox{
+			Inbox:   inbox,
+			Count:   page.Count,
+			HasMore: page.NextCursor != "",
+			Latest:  page.Results[:min(digestLatest, len(page.Results))],
+		})
+	}
+
+	sent := false
+	if total > 0 {
+		body, err := dynamic_response.ParseEmail(orDefault(prefs.DigestTemplate, DefaultDigestTemplate), map[string]any{
+			"User":    user,
+			"Inboxes": activity,
+			"Total":   total,
+			"From":    from,
+			"To":      now,
+		})
+		if err != nil {
+			return false, err
+		}
+		subject := fmt.Sprintf("[Request Inbox] Your %s digest: %d requests", prefs.Digest, total)
+		if err := e.send(ctx, user, subject, body); err != nil {
+			return false, err
+		}
+		sent = true
+	}
+	user.EmailPreferences.LastDigest = now.UnixMilli()
+	if _, err := e.store.UpsertUser(ctx, user); err != nil {
+		return sent, err
+	}
+	return sent, nil
+}
+
+// Start sends the due digests every interval until the context is done.
+func (e *EmailNotifier) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sent, err := e.SendDigests(ctx)
+				if err != nil {
+					slog.Error("error sending email digests", "error", err)
+				}
+				if sent > 0 {
+					slog.Info("email digests sent", "count", sent)
+				}
+			}
+		}
+	}()
+}
+
+func (e *EmailNotifier) send(ctx context.Context, user model.User, subject, body string) error {
+	unsubscribeURL := UnsubscribeURL(user)
+	if unsubscribeURL != "" {
+		body += "\n--\nUnsubscribe from these emails: " + unsubscribeURL + "\n"
+	}
+	return e.mailer.Send(ctx, Email{
+		To:             user.Email,
+		Subject:        subject,
+		Body:           body,
+		UnsubscribeURL: unsubscribeURL,
+	})
+}
+
+// UnsubscribeURL returns the link that stops the emails of the user, empty when the user has no unsubscribe token.
+func UnsubscribeURL(user model.User) string {
+	token := user.EmailPreferences.UnsubscribeToken
+	if token == "" {
+		return ""
+	}
+	query := url.Values{"user": {user.ID.String()}, "token": {token}}
+	return config.GetString(config.EmailUnsubscribeURL) + "?" + query.Encode()
+}
+
+func orDefault(template, defaultTemplate string) string {
+	if template == "" {
+		return defaultTemplate
+	}
+	return template
+}
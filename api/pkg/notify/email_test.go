@@ -0,0 +1,187 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/database"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func mustGetEmailNotifier(t *testing.T) (*EmailNotifier, database.Repository, *smtpServer) {
+	t.Helper()
+	config.LoadConfig(config.Test)
+	ctx := context.Background()
+	dao, err := database.NewRepository(ctx, database.Badger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := dao.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	server := newSMTPServer(t)
+	mailer := NewSMTPMailer("127.0.0.1", server.port(), "", "", "no-reply@request-inbox.dev")
+	return NewEmailNotifier(dao, mailer), dao, server
+}
+
+func mustCreateOwner(t *testing.T, dao database.Repository, prefs model.EmailPreferences) (model.User, model.Inbox) {
+	t.Helper()
+	ctx := context.Background()
+	user := model.NewUser("owner@mail.dev")
+	user.EmailPreferences = prefs
+	if _, err := dao.UpsertUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	inbox := model.GenerateInbox()
+	inbox.OwnerID = user.ID
+	inbox.Notifications = []model.Notification{}
+	inbox, err := dao.CreateInbox(ctx, inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, inbox
+}
+
+func messageBody(t *testing.T, m smtpMessage) (*mail.Message, string) {
+	t.Helper()
+	parsed, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed, string(body)
+}
+
+func newRequest(method, path string) model.Request {
+	req := model.GenerateRequest(0)
+	req.Method = method
+	req.URI = "/api/v1/inboxes/" + uuid.NewString() + "/in" + path
+	return req
+}
+
+func TestEmailAlert(t *testing.T) {
+	en, dao, server := mustGetEmailNotifier(t)
+	now := time.Now()
+	en.now = func() time.Time { return now }
+	_, inbox := mustCreateOwner(t, dao, model.EmailPreferences{UnsubscribeToken: "token"})
+	user, err := dao.GetUser(context.Background(), inbox.OwnerID)
+	t_util.AssertNoError(t, err)
+	user.EmailPreferences.Alerts = []model.EmailAlert{{InboxID: inbox.ID, Method: http.MethodPost, Path: "/orders/*"}}
+	_, err = dao.UpsertUser(context.Background(), user)
+	t_util.AssertNoError(t, err)
+	ctx := context.Background()
+
+	en.Alert(ctx, inbox, newRequest(http.MethodGet, "/orders/1"))
+	t_util.AssertLen(t, server.received(), 0)
+
+	en.Alert(ctx, inbox, newRequest(http.MethodPost, "/orders/1"))
+	en.Alert(ctx, inbox, newRequest(http.MethodPost, "/orders/2"))
+	messages := server.received()
+	t_util.AssertLen(t, messages, 1)
+	parsed, body := messageBody(t, messages[0])
+	t_util.AssertStringContains(t, parsed.Header.Get("Subject"), inbox.Name+" received POST /api/v1/inboxes/")
+	t_util.AssertStringContains(t, body, "Unsubscribe from these emails: "+UnsubscribeURL(user))
+
+	now = now.Add(time.Duration(config.GetInt(config.EmailAlertThrottleSeconds)+1) * time.Second)
+	en.Alert(ctx, inbox, newRequest(http.MethodPost, "/orders/3"))
+	messages = server.received()
+	t_util.AssertLen(t, messages, 2)
+	_, body = messageBody(t, messages[1])
+	t_util.AssertStringContains(t, body, "and 1 more matching requests since the last alert")
+}
+
+func TestEmailAlertThroughNotifier(t *testing.T) {
+	en, dao, server := mustGetEmailNotifier(t)
+	user, inbox := mustCreateOwner(t, dao, model.EmailPreferences{})
+	user.EmailPreferences.Alerts = []model.EmailAlert{{InboxID: inbox.ID}}
+	_, err := dao.UpsertUser(context.Background(), user)
+	t_util.AssertNoError(t, err)
+	n := NewNotifier(en)
+
+	n.Notify(context.Background(), inbox, newRequest(http.MethodPut, "/"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t_util.AssertNoError(t, n.Close(ctx))
+
+	t_util.AssertLen(t, server.received(), 1)
+}
+
+func TestEmailAlertUnsubscribed(t *testing.T) {
+	en, dao, server := mustGetEmailNotifier(t)
+	user, inbox := mustCreateOwner(t, dao, model.EmailPreferences{Unsubscribed: true})
+	user.EmailPreferences.Alerts = []model.EmailAlert{{InboxID: inbox.ID}}
+	_, err := dao.UpsertUser(context.Background(), user)
+	t_util.AssertNoError(t, err)
+
+	en.Alert(context.Background(), inbox, newRequest(http.MethodPost, "/"))
+
+	t_util.AssertLen(t, server.received(), 0)
+}
+
+func TestSendDigests(t *testing.T) {
+	en, dao, server := mustGetEmailNotifier(t)
+	ctx := context.Background()
+	lastDigest := time.Now().Add(-2 * time.Hour).UnixMilli()
+	user, inbox := mustCreateOwner(t, dao, model.EmailPreferences{
+		Digest:           model.EmailDigestHourly,
+		LastDigest:       lastDigest,
+		UnsubscribeToken: "token",
+	})
+	for _, uri := range []string{"/a", "/b", "/c"} {
+		if _, err := dao.AddRequestToInbox(ctx, inbox.ID, newRequest(http.MethodPost, uri)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent, err := en.SendDigests(ctx)
+
+	t_util.AssertNoError(t, err)
+	t_util.AssertEquals(t, sent, 1)
+	messages := server.received()
+	t_util.AssertLen(t, messages, 1)
+	parsed, body := messageBody(t, messages[0])
+	t_util.AssertStringEquals(t, parsed.Header.Get("Subject"), "[Request Inbox] Your hourly digest: 3 requests")
+	t_util.AssertStringContains(t, body, inbox.Name+": 3 requests")
+	t_util.AssertStringContains(t, body, "/in/c")
+	stored, err := dao.GetUser(ctx, user.ID)
+	t_util.AssertNoError(t, err)
+	t_util.AssertTrue(t, stored.EmailPreferences.LastDigest > lastDigest, "digest period moved")
+
+	sent, err = en.SendDigests(ctx)
+	t_util.AssertNoError(t, err)
+	t_util.AssertEquals(t, sent, 0)
+}
+
+func TestSendDigestsWithoutActivity(t *testing.T) {
+	en, dao, server := mustGetEmailNotifier(t)
+	ctx := context.Background()
+	lastDigest := time.Now().Add(-25 * time.Hour).UnixMilli()
+	user, _ := mustCreateOwner(t, dao, model.EmailPreferences{Digest: model.EmailDigestDaily, LastDigest: lastDigest})
+
+	sent, err := en.SendDigests(ctx)
+
+	t_util.AssertNoError(t, err)
+	t_util.AssertEquals(t, sent, 0)
+	t_util.AssertLen(t, server.received(), 0)
+	stored, err := dao.GetUser(ctx, user.ID)
+	t_util.AssertNoError(t, err)
+	t_util.AssertTrue(t, stored.EmailPreferences.LastDigest > lastDigest, "empty digest period is skipped")
+}
+
+func TestNewEmailNotifierDisabledWithoutMailer(t *testing.T) {
+	t_util.AssertTrue(t, NewEmailNotifier(nil, nil) == nil, "email notifier is disabled")
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+)
+
+// Email is a plain text message to a single recipient.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+	// UnsubscribeURL is announced in the List-Unsubscribe header when it is set
+	UnsubscribeURL string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(context.Context, Email) error
+}
+
+// SMTPMailer sends emails through an SMTP server, using STARTTLS when the server offers it.
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+// NewMailer returns the configured mailer, nil when no SMTP host is configured.
+func NewMailer() Mailer {
+	if config.GetString(config.SMTPHost) == "" {
+		return nil
+	}
+	return NewSMTPMailer(
+		config.GetString(config.SMTPHost),
+		config.GetInt(config.SMTPPort),
+		config.GetString(config.SMTPUsername),
+		config.GetString(config.SMTPPassword),
+		config.GetString(config.SMTPFrom),
+	)
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+		timeout:  time.Duration(config.GetInt(config.CallbackTimeoutSeconds)) * time.Second,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, e Email) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.from, err)
+	}
+	to, err := mail.ParseAddress(e.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", e.To, err)
+	}
+	msg, err := buildMessage(from, to, e, time.Now())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	dialer := &net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error connecting to the SMTP server: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(m.timeout))
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("error starting the SMTP session: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("error starting TLS: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("error authenticating to the SMTP server: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("error setting the sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("error setting the recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error starting the message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("error writing the message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending the message: %w", err)
+	}
+	return client.Quit()
+}
+
+func buildMessage(from, to *mail.Address, e Email, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", e.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	if e.UnsubscribeURL != "" {
+		header("List-Unsubscribe", "<"+e.UnsubscribeURL+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	buf.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(e.Body)); err != nil {
+		return nil, fmt.Errorf("error encoding the message: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding the message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+// smtpMessage is a message received by the local SMTP stand-in.
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpServer is a minimal SMTP server without TLS nor authentication that keeps the received messages.
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: l}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage{}, s.messages...)
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost stand-in")
+	msg := smtpMessage{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.From = strings.Trim(strings.TrimSpace(line)[10:], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = smtpMessage{}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	config.LoadConfig(config.Test)
+	server := newSMTPServer(t)
+	mailer := NewSMTPMailer("127.0.0.1", server.port(), "", "", "Request Inbox <no-reply@request-inbox.dev>")
+
+	err := mailer.Send(context.Background(), Email{
+		To:             "owner@mail.dev",
+		Subject:        "[Request Inbox] Orders received POST /orders",
+		Body:           "Body: {\"total\": 10}\n",
+		UnsubscribeURL: "https://api.request-inbox.dev/api/v1/email/unsubscribe?user=1&token=2",
+	})
+
+	t_util.AssertNoError(t, err)
+	messages := server.received()
+	t_util.AssertLen(t, messages, 1)
+	t_util.AssertStringEquals(t, messages[0].From, "no-reply@request-inbox.dev")
+	t_util.AssertStringEquals(t, strings.Join(messages[0].To, ","), "owner@mail.dev")
+	parsed, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	t_util.AssertNoError(t, err)
+	t_util.AssertStringEquals(t, parsed.Header.Get("Subject"), "[Request Inbox] Orders received POST /orders")
+	t_util.AssertStringEquals(t, parsed.Header.Get("List-Unsubscribe"), "<https://api.request-inbox.dev/api/v1/email/unsubscribe?user=1&token=2>")
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	t_util.AssertNoError(t, err)
+	t_util.AssertStringContains(t, string(body), `Body: {"total": 10}`)
+}
+
+func TestSMTPMailerInvalidRecipient(t *testing.T) {
+	config.LoadConfig(config.Test)
+	mailer := NewSMTPMailer("127.0.0.1", 25, "", "", "no-reply@request-inbox.dev")
+
+	err := mailer.Send(context.Background(), Email{To: "not an address"})
+
+	t_util.AssertError(t, err)
+}
+
+func TestNewMailerDisabledWithoutHost(t *testing.T) {
+	config.LoadConfig(config.Test)
+
+	t_util.AssertTrue(t, NewMailer() == nil, "mailer is disabled")
+}
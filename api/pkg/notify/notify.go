@@ -0,0 +1,197 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/dynamic_response"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/model/validation"
+)
+
+// DefaultTemplate is the message of the notifications without a template.
+const DefaultTemplate = `{{.Inbox.Name}} received {{.Request.Method}} {{.Request.URI}}` +
+	`{{if .Suppressed}} ({{.Suppressed}} more since the last notification){{end}}`
+
+// discordMaxContent is the maximum length of a Discord message content.
+const discordMaxContent = 2000
+
+// Notifier sends the inbox notifications of the received requests. Throttling is kept in memory,
+// so each instance of the service throttles on its own.
+type Notifier struct {
+	client *http.Client
+	now    func() time.Time
+	// inline sends before Notify returns, a lambda is frozen once it answers
+	inline   bool
+	throttle *throttler
+	// email sends the email alerts of the inbox owners, they are disabled when it is nil
+	email *EmailNotifier
+	wg    sync.WaitGroup
+	// mu guards closed, no notification is started once the notifier is closed
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewNotifier(email *EmailNotifier) *Notifier {
+	n := &Notifier{
+		client:   &http.Client{Timeout: time.Duration(config.GetInt(config.CallbackTimeoutSeconds)) * time.Second},
+		now:      time.Now,
+		inline:   config.GetString(config.APIMode) == config.APIModeLambda,
+		throttle: newThrottler(),
+		email:    email,
+	}
+	// A lambda may not run when the windows close, the next allowed message reports the suppressed ones
+	if !n.inline {
+		n.throttle.afterFunc = time.AfterFunc
+	}
+	return n
+}
+
+// Notify sends the enabled notifications of the inbox that are not throttled and the email alerts of its owner.
+// A nil Notifier does nothing.
+func (n *Notifier) Notify(ctx context.Context, inbox model.Inbox, request model.Request) {
+	if n == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for k, nt := range inbox.Notifications {
+		if !nt.IsEnabled {
+			continue
+		}
+		suppressed, ok := n.throttle.allow(windowKey{inboxID: inbox.ID, url: nt.URL}, n.now(), throttle(nt), func(suppressed int) {
+			n.run(func() { n.send(ctx, inbox, k, nt, request, suppressed) })
+		})
+		if !ok {
+			continue
+		}
+		n.run(func() { n.send(ctx, inbox, k, nt, request, suppressed) })
+	}
+	if n.email != nil && inbox.OwnerID != uuid.Nil {
+		n.run(func() { n.email.Alert(ctx, inbox, request) })
+	}
+}
+
+func (n *Notifier) run(f func()) {
+	if n.inline {
+		f()
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		f()
+	}()
+}
+
+// Close stops sending notifications, including the throttled ones waiting for their window to close, and waits
+// for the notifications being sent until the context is done.
+func (n *Notifier) Close(ctx context.Context) error {
+	n.mu.Lock()
+	n.closed = true
+	n.mu.Unlock()
+	n.throttle.stop()
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func throttle(nt model.Notification) time.Duration {
+	seconds := nt.ThrottleSeconds
+	if seconds == 0 {
+		seconds = config.GetInt(config.NotificationThrottleSeconds)
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (n *Notifier) send(ctx context.Context, inbox model.Inbox, k int, nt model.Notification, request model.Request, suppressed int) {
+	if valid, err := validation.IsValidCallbackURL(nt.URL); !valid {
+		slog.Error("invalid notification URL", "error", err, "inbox_id", inbox.ID, "notification_index", k)
+		return
+	}
+	template := nt.Template
+	if template == "" {
+		template = DefaultTemplate
+	}
+	message, err := dynamic_response.ParseNotification(template, inbox, request, suppressed)
+	if err != nil {
+		slog.Error("error parsing notification", "error", err, "inbox_id", inbox.ID, "notification_index", k)
+		return
+	}
+	body, err := json.Marshal(Payload(nt.Type, inbox, request, message, suppressed))
+	if err != nil {
+		slog.Error("error encoding notification", "error", err, "inbox_id", inbox.ID, "notification_index", k)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nt.URL, bytes.NewReader(body))
+	if err != nil {
+		slog.Error("error creating notification request", "error", err, "inbox_id", inbox.ID, "notification_index", k)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		slog.Error("error sending notification", "error", err, "inbox_id", inbox.ID, "notification_index", k)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		slog.Error("notification rejected", "status_code", resp.StatusCode, "inbox_id", inbox.ID, "notification_index", k)
+		return
+	}
+	slog.Info("notification sent", "inbox_id", inbox.ID, "notification_index", k, "type", nt.Type, "suppressed", suppressed)
+}
+
+// Payload returns the JSON document expected by the integration of the notification type.
+func Payload(notificationType string, inbox model.Inbox, request model.Request, message string, suppressed int) any {
+	switch notificationType {
+	case model.NotificationSlack:
+		return map[string]any{"text": message}
+	case model.NotificationDiscord:
+		return map[string]any{"content": truncate(message, discordMaxContent)}
+	case model.NotificationTeams:
+		title := fmt.Sprintf("Request Inbox: %s", inbox.Name)
+		return map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  title,
+			"title":    title,
+			"text":     message,
+		}
+	default:
+		return map[string]any{
+			"message":    message,
+			"suppressed": suppressed,
+			"inbox":      map[string]any{"id": inbox.ID, "name": inbox.Name},
+			"request":    request,
+		}
+	}
+}
+
+func truncate(s string, limit int) string {
+	r := []rune(s)
+	if len(r) <= limit {
+		return s
+	}
+	return string(r[:limit-1]) + "…"
+}
@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	bodies []map[string]any
+}
+
+func (rc *recorder) received() []map[string]any {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]map[string]any{}, rc.bodies...)
+}
+
+func newRecordingServer(t *testing.T) (*httptest.Server, *recorder) {
+	t.Helper()
+	rc := &recorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := map[string]any{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("notification body is not JSON: %v", err)
+		}
+		rc.mu.Lock()
+		rc.bodies = append(rc.bodies, payload)
+		rc.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, rc
+}
+
+func newTestNotifier(t *testing.T, now *time.Time) *Notifier {
+	t.Helper()
+	config.LoadConfig(config.Test)
+	config.Set(config.EnableCallbackURLValidation, false)
+	t.Cleanup(func() { config.Set(config.EnableCallbackURLValidation, true) })
+	n := NewNotifier(nil)
+	n.now = func() time.Time { return *now }
+	return n
+}
+
+func notifyAndWait(t *testing.T, n *Notifier, inbox model.Inbox, request model.Request) {
+	t.Helper()
+	n.Notify(context.Background(), inbox, request)
+	waitForNotifications(t, n)
+}
+
+// waitForNotifications waits for the notifications being sent without closing the notifier.
+func waitForNotifications(t *testing.T, n *Notifier) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifications were not sent")
+	}
+}
+
+func TestNotifyPayloads(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		nType   string
+		field   string
+		message string
+	}{
+		{desc: "Slack", nType: model.NotificationSlack, field: "text", message: "POST /hook"},
+		{desc: "Discord", nType: model.NotificationDiscord, field: "content", message: "POST /hook"},
+		{desc: "Teams", nType: model.NotificationTeams, field: "text", message: "POST /hook"},
+		{desc: "Generic", nType: model.NotificationGeneric, field: "message", message: "POST /hook"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			now := time.Now()
+			n := newTestNotifier(t, &now)
+			server, rc := newRecordingServer(t)
+			inbox := model.GenerateInbox()
+			inbox.Notifications = []model.Notification{
+				{IsEnabled: true, Type: tc.nType, URL: server.URL, Template: "{{.Request.Method}} {{.Request.URI}}"},
+			}
+			request := model.GenerateRequest(1)
+			request.URI = "/hook"
+
+			notifyAndWait(t, n, inbox, request)
+
+			bodies := rc.received()
+			t_util.AssertLen(t, bodies, 1)
+			t_util.AssertEquals(t, bodies[0][tc.field], any(tc.message))
+		})
+	}
+}
+
+func TestNotifyGenericPayloadHasInboxAndRequest(t *testing.T) {
+	inbox := model.GenerateInbox()
+	request := model.GenerateRequest(7)
+
+	payload := Payload(model.NotificationGeneric, inbox, request, "message", 3).(map[string]any)
+
+	t_util.AssertEquals(t, payload["suppressed"], any(3))
+	t_util.AssertEquals(t, payload["request"].(model.Request).ID, 7)
+	t_util.AssertEquals(t, payload["inbox"].(map[string]any)["name"], any(inbox.Name))
+}
+
+func TestNotifyDiscordTruncatesContent(t *testing.T) {
+	payload := Payload(model.NotificationDiscord, model.Inbox{}, model.Request{}, strings.Repeat("a", 3000), 0).(map[string]any)
+
+	t_util.AssertEquals(t, len([]rune(payload["content"].(string))), discordMaxContent)
+}
+
+func TestNotifyThrottlesBursts(t *testing.T) {
+	now := time.Now()
+	n := newTestNotifier(t, &now)
+	server, rc := newRecordingServer(t)
+	inbox := model.GenerateInbox()
+	inbox.Notifications = []model.Notification{
+		{IsEnabled: true, Type: model.NotificationSlack, URL: server.URL, ThrottleSeconds: 60},
+	}
+
+	for i := 0; i < 1000; i++ {
+		n.Notify(context.Background(), inbox, model.GenerateRequest(i))
+	}
+	notifyAndWait(t, n, inbox, model.GenerateRequest(1000))
+	t_util.AssertLen(t, rc.received(), 1)
+
+	now = now.Add(61 * time.Second)
+	notifyAndWait(t, n, inbox, model.GenerateRequest(1001))
+
+	bodies := rc.received()
+	t_util.AssertLen(t, bodies, 2)
+	t_util.AssertStringContains(t, bodies[1]["text"].(string), "1000 more since the last notification")
+}
+
+func TestNotifyFlushesThrottledRequests(t *testing.T) {
+	now := time.Now()
+	n := newTestNotifier(t, &now)
+	closes := []func(){}
+	n.throttle.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		t_util.AssertEquals(t, d, 60*time.Second)
+		closes = append(closes, f)
+		return nil
+	}
+	server, rc := newRecordingServer(t)
+	inbox := model.GenerateInbox()
+	inbox.Notifications = []model.Notification{
+		{IsEnabled: true, Type: model.NotificationSlack, URL: server.URL, ThrottleSeconds: 60},
+	}
+	for i := 0; i < 3; i++ {
+		request := model.GenerateRequest(i)
+		request.URI = fmt.Sprintf("/%d", i)
+		notifyAndWait(t, n, inbox, request)
+	}
+	t_util.AssertLen(t, rc.received(), 1)
+	t_util.AssertLen(t, closes, 1)
+
+	closes[0]()
+	waitForNotifications(t, n)
+
+	bodies := rc.received()
+	t_util.AssertLen(t, bodies, 2)
+	t_util.AssertStringContains(t, bodies[1]["text"].(string), "/2 (1 more since the last notification)")
+	// The window opened by the trailing notification closes without sending anything
+	t_util.AssertLen(t, closes, 2)
+	closes[1]()
+	waitForNotifications(t, n)
+	t_util.AssertLen(t, rc.received(), 2)
+}
+
+func TestNotifyCloseStopsThrottledRequests(t *testing.T) {
+	now := time.Now()
+	n := newTestNotifier(t, &now)
+	closes := []func(){}
+	timers := []*time.Timer{}
+	n.throttle.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		closes = append(closes, f)
+		timers = append(timers, time.AfterFunc(time.Hour, f))
+		return timers[len(timers)-1]
+	}
+	server, rc := newRecordingServer(t)
+	inbox := model.GenerateInbox()
+	inbox.Notifications = []model.Notification{
+		{IsEnabled: true, Type: model.NotificationSlack, URL: server.URL, ThrottleSeconds: 60},
+	}
+	notifyAndWait(t, n, inbox, model.GenerateRequest(0))
+	notifyAndWait(t, n, inbox, model.GenerateRequest(1))
+
+	t_util.AssertNoError(t, n.Close(context.Background()))
+
+	t_util.AssertLen(t, timers, 1)
+	t_util.AssertFalse(t, timers[0].Stop(), "the window timer is stopped by Close")
+	// A window closing anyway sends nothing after Close
+	closes[0]()
+	t_util.AssertNoError(t, n.Close(context.Background()))
+	t_util.AssertLen(t, rc.received(), 1)
+}
+
+func TestNotifyThrottlesByURL(t *testing.T) {
+	now := time.Now()
+	n := newTestNotifier(t, &now)
+	slack, slackRC := newRecordingServer(t)
+	generic, genericRC := newRecordingServer(t)
+	inbox := model.GenerateInbox()
+	inbox.Notifications = []model.Notification{
+		{IsEnabled: true, Type: model.NotificationGeneric, URL: generic.URL, ThrottleSeconds: 60},
+		{IsEnabled: true, Type: model.NotificationSlack, URL: slack.URL, ThrottleSeconds: 60},
+	}
+	notifyAndWait(t, n, inbox, model.GenerateRequest(0))
+
+	// Removing the first notification keeps the window of the other one
+	inbox.Notifications = inbox.Notifications[1:]
+	notifyAndWait(t, n, inbox, model.GenerateRequest(1))
+
+	t_util.AssertLen(t, genericRC.received(), 1)
+	t_util.AssertLen(t, slackRC.received(), 1)
+}
+
+func TestNotifySkipsDisabledAndBrokenNotifications(t *testing.T) {
+	now := time.Now()
+	n := newTestNotifier(t, &now)
+	server, rc := newRecordingServer(t)
+	inbox := model.GenerateInbox()
+	inbox.Notifications = []model.Notification{
+		{IsEnabled: false, Type: model.NotificationSlack, URL: server.URL},
+		{IsEnabled: true, Type: model.NotificationSlack, URL: server.URL, Template: "{{.Missing"},
+	}
+
+	notifyAndWait(t, n, inbox, model.GenerateRequest(1))
+
+	t_util.AssertLen(t, rc.received(), 0)
+}
+
+func TestNotifyNilNotifier(t *testing.T) {
+	var n *Notifier
+	n.Notify(context.Background(), model.GenerateInbox(), model.GenerateRequest(1))
+}
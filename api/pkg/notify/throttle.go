@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sweepInterval is how often the expired throttle windows are dropped.
+const sweepInterval = time.Minute
+
+// staleWindow is how long the count of throttled requests is kept after the window expires.
+const staleWindow = 24 * time.Hour
+
+// windowKey identifies the notifications throttled together. They are keyed by URL, so a window is kept
+// when other notifications of the inbox are added or removed.
+type windowKey struct {
+	inboxID uuid.UUID
+	url     string
+}
+
+// window is the throttle period of a notification, requests received before until are only counted.
+type window struct {
+	until      time.Time
+	suppressed int
+	// flush sends the last suppressed message with the number of the other ones
+	flush func(suppressed int)
+	timer *time.Timer
+}
+
+// throttler allows one message per window and counts the ones suppressed meanwhile.
+// When afterFunc is set the last suppressed message is also sent when its window closes, otherwise the count
+// is only reported by the next allowed message.
+type throttler struct {
+	mu        sync.Mutex
+	windows   map[windowKey]*window
+	lastSweep time.Time
+	afterFunc func(time.Duration, func()) *time.Timer
+	// stopped windows do not close anymore
+	stopped bool
+}
+
+func newThrottler() *throttler {
+	return &throttler{windows: map[windowKey]*window{}}
+}
+
+// allow reports whether the message can be sent now and how many were suppressed since the last one.
+// When it is suppressed, flush replaces the one of the previous suppressed message.
+func (t *throttler) allow(key windowKey, now time.Time, period time.Duration, flush func(suppressed int)) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep(now)
+	w, ok := t.windows[key]
+	if ok && now.Before(w.until) {
+		w.suppressed++
+		w.flush = flush
+		return 0, false
+	}
+	suppressed := 0
+	if ok {
+		suppressed = w.suppressed
+	}
+	if period <= 0 {
+		delete(t.windows, key)
+		return suppressed, true
+	}
+	t.open(key, now.Add(period), period)
+	return suppressed, true
+}
+
+// open starts a window until the given time, it must be called with the lock held.
+func (t *throttler) open(key windowKey, until time.Time, period time.Duration) {
+	w := &window{until: until}
+	t.windows[key] = w
+	if t.afterFunc != nil && !t.stopped {
+		w.timer = t.afterFunc(period, func() { t.close(key, w, period) })
+	}
+}
+
+// stop cancels the pending window closes, their suppressed messages are not sent.
+func (t *throttler) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	for _, w := range t.windows {
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+	}
+}
+
+// close sends the last message suppressed in the window and starts another one, so the trailing message is
+// throttled too. Windows replaced meanwhile are ignored.
+func (t *throttler) close(key windowKey, w *window, period time.Duration) {
+	t.mu.Lock()
+	if t.windows[key] != w || t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	if w.suppressed == 0 || w.flush == nil {
+		delete(t.windows, key)
+		t.mu.Unlock()
+		return
+	}
+	suppressed, flush := w.suppressed, w.flush
+	t.open(key, w.until.Add(period), period)
+	t.mu.Unlock()
+	flush(suppressed - 1)
+}
+
+// sweep drops the expired windows without suppressed messages and the stale ones, it must be called with the lock held.
+func (t *throttler) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < sweepInterval {
+		return
+	}
+	t.lastSweep = now
+	for key, w := range t.windows {
+		if (w.suppressed == 0 && now.After(w.until)) || now.Sub(w.until) > staleWindow {
+			delete(t.windows, key)
+		}
+	}
+}
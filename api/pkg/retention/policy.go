@@ -0,0 +1,58 @@
+package retention
+
+import (
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// DefaultPolicy returns the server-wide retention policy.
+func DefaultPolicy() model.RetentionPolicy {
+	return model.RetentionPolicy{
+		MaxRequests:   config.GetInt(config.RetentionMaxRequests),
+		MaxAgeSeconds: int64(config.GetInt(config.RetentionMaxAgeSeconds)),
+	}
+}
+
+// EffectivePolicy fills the unset values of the inbox policy with the server defaults
+// and caps them to those defaults. Zero values in the result mean unlimited.
+func EffectivePolicy(p model.RetentionPolicy) model.RetentionPolicy {
+	d := DefaultPolicy()
+	if p.MaxRequests <= 0 || (d.MaxRequests > 0 && p.MaxRequests > d.MaxRequests) {
+		p.MaxRequests = d.MaxRequests
+	}
+	if p.MaxAgeSeconds <= 0 || (d.MaxAgeSeconds > 0 && p.MaxAgeSeconds > d.MaxAgeSeconds) {
+		p.MaxAgeSeconds = d.MaxAgeSeconds
+	}
+	return p
+}
+
+// Cutoff returns the unix milliseconds before which requests are expired, 0 if they never expire.
+func Cutoff(p model.RetentionPolicy, now time.Time) int64 {
+	if p.MaxAgeSeconds <= 0 {
+		return 0
+	}
+	return now.Add(-time.Duration(p.MaxAgeSeconds) * time.Second).UnixMilli()
+}
+
+// ExpiresAt returns the unix seconds when a request received at timestamp (unix milliseconds) expires, 0 if never.
+func ExpiresAt(p model.RetentionPolicy, timestamp int64) int64 {
+	if p.MaxAgeSeconds <= 0 {
+		return 0
+	}
+	return time.UnixMilli(timestamp).Unix() + p.MaxAgeSeconds
+}
+
+// Apply returns the requests, sorted from oldest to newest, that the policy keeps.
+func Apply(p model.RetentionPolicy, requests []model.Request, now time.Time) []model.Request {
+	cutoff := Cutoff(p, now)
+	start := 0
+	for start < len(requests) && requests[start].Timestamp < cutoff {
+		start++
+	}
+	if p.MaxRequests > 0 && len(requests)-start > p.MaxRequests {
+		start = len(requests) - p.MaxRequests
+	}
+	return requests[start:]
+}
@@ -0,0 +1,84 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestEffectivePolicy(t *testing.T) {
+	config.LoadConfig(config.Test)
+	config.Set(config.RetentionMaxRequests, 100)
+	config.Set(config.RetentionMaxAgeSeconds, 3600)
+	defer config.Set(config.RetentionMaxRequests, config.RetentionMaxRequestsDefault)
+	defer config.Set(config.RetentionMaxAgeSeconds, config.RetentionMaxAgeSecondsDefault)
+
+	testCases := []struct {
+		desc   string
+		policy model.RetentionPolicy
+		want   model.RetentionPolicy
+	}{
+		{"unset uses defaults", model.RetentionPolicy{}, model.RetentionPolicy{MaxRequests: 100, MaxAgeSeconds: 3600}},
+		{"lower values are kept", model.RetentionPolicy{MaxRequests: 10, MaxAgeSeconds: 60}, model.RetentionPolicy{MaxRequests: 10, MaxAgeSeconds: 60}},
+		{"higher values are capped", model.RetentionPolicy{MaxRequests: 1000, MaxAgeSeconds: 7200}, model.RetentionPolicy{MaxRequests: 100, MaxAgeSeconds: 3600}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, EffectivePolicy(tc.policy)); diff != "" {
+				t.Errorf("EffectivePolicy(%v) diff: %s", tc.policy, diff)
+			}
+		})
+	}
+}
+
+func TestEffectivePolicyUnlimitedDefaults(t *testing.T) {
+	config.LoadConfig(config.Test)
+	config.Set(config.RetentionMaxRequests, 0)
+	config.Set(config.RetentionMaxAgeSeconds, 0)
+	defer config.Set(config.RetentionMaxRequests, config.RetentionMaxRequestsDefault)
+	defer config.Set(config.RetentionMaxAgeSeconds, config.RetentionMaxAgeSecondsDefault)
+
+	t_util.AssertEquals(t, EffectivePolicy(model.RetentionPolicy{}), model.RetentionPolicy{})
+	t_util.AssertEquals(t, EffectivePolicy(model.RetentionPolicy{MaxRequests: 5}), model.RetentionPolicy{MaxRequests: 5})
+}
+
+func TestApply(t *testing.T) {
+	now := time.Now()
+	requests := []model.Request{
+		{ID: 0, Timestamp: now.Add(-3 * time.Hour).UnixMilli()},
+		{ID: 1, Timestamp: now.Add(-2 * time.Hour).UnixMilli()},
+		{ID: 2, Timestamp: now.Add(-1 * time.Minute).UnixMilli()},
+		{ID: 3, Timestamp: now.UnixMilli()},
+	}
+	testCases := []struct {
+		desc    string
+		policy  model.RetentionPolicy
+		wantIDs []int
+	}{
+		{"unlimited", model.RetentionPolicy{}, []int{0, 1, 2, 3}},
+		{"max requests", model.RetentionPolicy{MaxRequests: 2}, []int{2, 3}},
+		{"max age", model.RetentionPolicy{MaxAgeSeconds: 150 * 60}, []int{1, 2, 3}},
+		{"max age and max requests", model.RetentionPolicy{MaxRequests: 3, MaxAgeSeconds: 60 * 60}, []int{2, 3}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ids := []int{}
+			for _, r := range Apply(tc.policy, requests, now) {
+				ids = append(ids, r.ID)
+			}
+			if diff := cmp.Diff(tc.wantIDs, ids); diff != "" {
+				t.Errorf("Apply(%v) diff: %s", tc.policy, diff)
+			}
+		})
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t_util.AssertEquals(t, ExpiresAt(model.RetentionPolicy{}, ts.UnixMilli()), 0)
+	t_util.AssertEquals(t, ExpiresAt(model.RetentionPolicy{MaxAgeSeconds: 60}, ts.UnixMilli()), ts.Unix()+60)
+}
@@ -0,0 +1,70 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+type Pruner interface {
+	ListInbox(context.Context) ([]model.Inbox, error)
+	PruneInboxRequests(context.Context, uuid.UUID, model.RetentionPolicy) (int, error)
+}
+
+// Sweeper periodically applies the retention policy of every inbox,
+// so inboxes that stop receiving requests are also pruned.
+type Sweeper struct {
+	pruner   Pruner
+	interval time.Duration
+}
+
+func NewSweeper(pruner Pruner, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		pruner:   pruner,
+		interval: interval,
+	}
+}
+
+// Sweep prunes all inboxes once and returns the number of removed requests.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	inboxes, err := s.pruner.ListInbox(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, inbox := range inboxes {
+		if ctx.Err() != nil {
+			return removed, ctx.Err()
+		}
+		n, err := s.pruner.PruneInboxRequests(ctx, inbox.ID, EffectivePolicy(inbox.Retention))
+		if err != nil {
+			slog.Error("error pruning inbox requests", "inbox_id", inbox.ID, "error", err)
+			continue
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// Start sweeps every interval until the context is done.
+func (s *Sweeper) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := s.Sweep(ctx)
+				if err != nil {
+					slog.Error("error sweeping inboxes", "error", err)
+				}
+				slog.Info("retention sweep finished", "removed_requests", removed)
+			}
+		}
+	}()
+}
@@ -0,0 +1,79 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+type fakePruner struct {
+	inboxes  []model.Inbox
+	policies map[uuid.UUID]model.RetentionPolicy
+	fail     map[uuid.UUID]bool
+	pruned   chan uuid.UUID
+}
+
+func (f *fakePruner) ListInbox(context.Context) ([]model.Inbox, error) {
+	return f.inboxes, nil
+}
+
+func (f *fakePruner) PruneInboxRequests(_ context.Context, id uuid.UUID, p model.RetentionPolicy) (int, error) {
+	if f.pruned != nil {
+		f.pruned <- id
+	}
+	if f.fail[id] {
+		return 0, errors.New("prune failed")
+	}
+	f.policies[id] = p
+	return 1, nil
+}
+
+func newFakePruner(inboxes ...model.Inbox) *fakePruner {
+	return &fakePruner{
+		inboxes:  inboxes,
+		policies: map[uuid.UUID]model.RetentionPolicy{},
+		fail:     map[uuid.UUID]bool{},
+	}
+}
+
+func TestSweep(t *testing.T) {
+	config.LoadConfig(config.Test)
+	withPolicy := model.GenerateInbox()
+	withPolicy.Retention = model.RetentionPolicy{MaxRequests: 5, MaxAgeSeconds: 60}
+	withDefaults := model.GenerateInbox()
+	withDefaults.Retention = model.RetentionPolicy{}
+	failing := model.GenerateInbox()
+	pruner := newFakePruner(withPolicy, withDefaults, failing)
+	pruner.fail[failing.ID] = true
+
+	removed, err := NewSweeper(pruner, time.Hour).Sweep(context.Background())
+
+	t_util.AssertNoError(t, err)
+	t_util.AssertEquals(t, removed, 2)
+	t_util.AssertEquals(t, pruner.policies[withPolicy.ID], withPolicy.Retention)
+	t_util.AssertEquals(t, pruner.policies[withDefaults.ID], DefaultPolicy())
+}
+
+func TestSweeperStart(t *testing.T) {
+	config.LoadConfig(config.Test)
+	inbox := model.GenerateInbox()
+	pruner := newFakePruner(inbox)
+	pruner.pruned = make(chan uuid.UUID, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	NewSweeper(pruner, 10*time.Millisecond).Start(ctx)
+
+	select {
+	case id := <-pruner.pruned:
+		t_util.AssertEquals(t, id, inbox.ID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("sweeper did not prune the inbox")
+	}
+}
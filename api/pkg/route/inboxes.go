@@ -4,7 +4,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jesusnoseq/request-inbox/pkg/handler"
 	"github.com/jesusnoseq/request-inbox/pkg/handler/apikey"
+	"github.com/jesusnoseq/request-inbox/pkg/handler/email"
 	"github.com/jesusnoseq/request-inbox/pkg/login"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
 )
 
 const APIBasePath = "/api/v1"
@@ -22,18 +24,37 @@ func SetInboxRoutes(r gin.IRouter, ih handler.InboxService) {
 	{
 		inboxes := v1.Group("/inboxes")
 		{
-			inboxes.GET("", ih.ListInbox)
-			inboxes.POST("", ih.CreateInbox)
-			inboxes.DELETE("/:id", ih.DeleteInbox)
-			inboxes.GET("/:id", ih.GetInbox)
-			inboxes.PUT("/:id", ih.UpdateInbox)
-			inboxes.DELETE("/:id/requests", ih.DeleteInboxRequests)
+			inboxes.GET("", inboxPermission(model.Read), ih.ListInbox)
+			inboxes.POST("", inboxPermission(model.Create), ih.CreateInbox)
+			inboxes.DELETE("/:id", inboxPermission(model.Delete), ih.DeleteInbox)
+			inboxes.GET("/:id", inboxPermission(model.Read), ih.GetInbox)
+			inboxes.PUT("/:id", inboxPermission(model.Update), ih.UpdateInbox)
+			inboxes.GET("/:id/requests", inboxPermission(model.Read), ih.ListInboxRequests)
+			inboxes.GET("/:id/requests/:requestId/body", inboxPermission(model.Read), ih.GetInboxRequestBody)
+			inboxes.GET("/:id/requests/:requestId/files/:index", inboxPermission(model.Read), ih.GetInboxRequestFile)
+			inboxes.POST("/:id/requests/:requestId/replay", inboxPermission(model.Update), ih.ReplayInboxRequest)
+			inboxes.GET("/:id/stream", inboxPermission(model.Read), ih.StreamInboxRequests)
+			inboxes.GET("/:id/ws", inboxPermission(model.Read), ih.WatchInboxRequests)
+			inboxes.DELETE("/:id/requests", inboxPermission(model.Delete), ih.DeleteInboxRequests)
+			inboxes.DELETE("/:id/scenario", inboxPermission(model.Update), ih.ResetInboxScenario)
+			inboxes.GET("/:id/export/stubs", inboxPermission(model.Read), ih.ExportInboxStubs)
+			inboxes.GET("/:id/dead-letters", inboxPermission(model.Read), ih.ListDeadLetters)
+			inboxes.POST("/:id/dead-letters/:deadLetterId/retry", inboxPermission(model.Update), ih.RetryDeadLetter)
+			inboxes.DELETE("/:id/dead-letters/:deadLetterId", inboxPermission(model.Delete), ih.DeleteDeadLetter)
+			inboxes.POST("/:id/assertions/wait", inboxPermission(model.Read), ih.WaitForRequests)
+			inboxes.POST("/:id/assertions/verify", inboxPermission(model.Read), ih.VerifyRequests)
 			inboxes.Any("/:id/in", ih.RegisterInboxRequest)
 			inboxes.Any("/:id/in/*path", ih.RegisterInboxRequest)
 		}
 	}
 }
 
+// inboxPermission rejects requests authenticated with an API key that lacks the action on inboxes.
+// Requests to "/in" are not guarded because anybody can send requests to an inbox.
+func inboxPermission(action model.PermissionAction) gin.HandlerFunc {
+	return login.APIKeyPermissionMiddleware(model.Inboxes, action)
+}
+
 func SetLoginRoutes(r gin.IRouter, lh login.LoginHandler) {
 	v1 := r.Group(APIBasePath)
 	{
@@ -41,7 +62,7 @@ func SetLoginRoutes(r gin.IRouter, lh login.LoginHandler) {
 		{
 			auth.GET("/:provider/login", lh.HandleLogin)
 			auth.GET("/user", lh.HandleLoginUser)
-			auth.DELETE("/user", lh.HandleDeleteLoginUser)
+			auth.DELETE("/user", login.RejectAPIKeyMiddleware(), lh.HandleDeleteLoginUser)
 			auth.GET("/logout", lh.HandleLogout)
 			auth.GET("/:provider/callback", lh.HandleCallback)
 		}
@@ -51,7 +72,8 @@ func SetLoginRoutes(r gin.IRouter, lh login.LoginHandler) {
 func SetAPIKeyRoutes(r gin.IRouter, ah apikey.APIKeyHandler) {
 	v1 := r.Group(APIBasePath)
 	{
-		apikey := v1.Group("/api-keys")
+		// API keys can not manage API keys, otherwise a scoped key could create a key with more permissions
+		apikey := v1.Group("/api-keys", login.RejectAPIKeyMiddleware())
 		{
 			apikey.GET("/:id", ah.GetAPIKey)
 			apikey.POST("", ah.CreateAPIKey)
@@ -60,3 +82,17 @@ func SetAPIKeyRoutes(r gin.IRouter, ah apikey.APIKeyHandler) {
 		}
 	}
 }
+
+func SetEmailRoutes(r gin.IRouter, eh email.EmailHandler) {
+	v1 := r.Group(APIBasePath)
+	{
+		emails := v1.Group("/email", login.RejectAPIKeyMiddleware())
+		{
+			emails.GET("/preferences", eh.GetEmailPreferences)
+			emails.PUT("/preferences", eh.UpdateEmailPreferences)
+			// GET only asks for confirmation, POST also answers the one-click unsubscribe of mail clients (RFC 8058)
+			emails.GET("/unsubscribe", eh.UnsubscribePage)
+			emails.POST("/unsubscribe", eh.Unsubscribe)
+		}
+	}
+}
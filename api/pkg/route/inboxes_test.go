@@ -8,8 +8,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/jesusnoseq/request-inbox/pkg/handler/apikey/apikey_mock"
+	"github.com/jesusnoseq/request-inbox/pkg/handler/email/email_mock"
 	"github.com/jesusnoseq/request-inbox/pkg/handler/handler_mock"
+	"github.com/jesusnoseq/request-inbox/pkg/login"
 	"github.com/jesusnoseq/request-inbox/pkg/login/login_mock"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
 	"github.com/jesusnoseq/request-inbox/pkg/route"
 )
 
@@ -28,7 +31,20 @@ func TestSetInboxRoutes(t *testing.T) {
 	ih.EXPECT().DeleteInbox(gomock.Any()).Do(returnOk).Times(1)
 	ih.EXPECT().GetInbox(gomock.Any()).Do(returnOk).Times(1)
 	ih.EXPECT().UpdateInbox(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().ListInboxRequests(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().GetInboxRequestBody(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().GetInboxRequestFile(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().ReplayInboxRequest(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().StreamInboxRequests(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().WatchInboxRequests(gomock.Any()).Do(returnOk).Times(1)
 	ih.EXPECT().DeleteInboxRequests(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().ResetInboxScenario(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().ExportInboxStubs(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().ListDeadLetters(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().RetryDeadLetter(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().DeleteDeadLetter(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().WaitForRequests(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().VerifyRequests(gomock.Any()).Do(returnOk).Times(1)
 	ih.EXPECT().RegisterInboxRequest(gomock.Any()).Do(returnOk).Times(2)
 	hh.EXPECT().Health(gomock.Any()).Do(returnOk).Times(1)
 
@@ -46,7 +62,20 @@ func TestSetInboxRoutes(t *testing.T) {
 		{"get inbox detail path", http.MethodGet, "/api/v1/inboxes/123", false},
 		{"update inbox detail", http.MethodPut, "/api/v1/inboxes/123", false},
 		{"delete inbox detail", http.MethodDelete, "/api/v1/inboxes/123", false},
+		{"list inbox requests", http.MethodGet, "/api/v1/inboxes/123/requests", false},
+		{"get inbox request body", http.MethodGet, "/api/v1/inboxes/123/requests/1/body", false},
+		{"get inbox request file", http.MethodGet, "/api/v1/inboxes/123/requests/1/files/0", false},
+		{"replay inbox request", http.MethodPost, "/api/v1/inboxes/123/requests/1/replay", false},
+		{"stream inbox requests", http.MethodGet, "/api/v1/inboxes/123/stream", false},
+		{"watch inbox requests", http.MethodGet, "/api/v1/inboxes/123/ws", false},
 		{"delete inbox requests", http.MethodDelete, "/api/v1/inboxes/123/requests", false},
+		{"reset inbox scenario", http.MethodDelete, "/api/v1/inboxes/123/scenario", false},
+		{"export inbox stubs", http.MethodGet, "/api/v1/inboxes/123/export/stubs", false},
+		{"list dead letters", http.MethodGet, "/api/v1/inboxes/123/dead-letters", false},
+		{"retry dead letter", http.MethodPost, "/api/v1/inboxes/123/dead-letters/456/retry", false},
+		{"delete dead letter", http.MethodDelete, "/api/v1/inboxes/123/dead-letters/456", false},
+		{"wait for requests", http.MethodPost, "/api/v1/inboxes/123/assertions/wait", false},
+		{"verify requests", http.MethodPost, "/api/v1/inboxes/123/assertions/verify", false},
 		{"make request to the inbox", http.MethodTrace, "/api/v1/inboxes/111/in", false},
 		{"make request to the inbox with more complex path", http.MethodPost, "/api/v1/inboxes/222/in/some/path", false},
 		{"get health", http.MethodGet, "/api/v1/health", false},
@@ -73,6 +102,64 @@ func TestSetInboxRoutes(t *testing.T) {
 	}
 }
 
+func TestSetInboxRoutesAPIKeyPermissions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(login.IS_LOGGED_WITH_API_KEY_CONTEXT_KEY, true)
+		c.Set(login.API_KEY_CONTEXT_KEY, model.APIKey{
+			Permissions: model.APIKeyPermissions{model.Inboxes: {model.Read: true}},
+		})
+	})
+	ih := handler_mock.NewMockInboxService(mockCtrl)
+	returnOk := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	ih.EXPECT().ListInbox(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().GetInbox(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().ListInboxRequests(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().WaitForRequests(gomock.Any()).Do(returnOk).Times(1)
+	ih.EXPECT().RegisterInboxRequest(gomock.Any()).Do(returnOk).Times(1)
+
+	route.SetInboxRoutes(r, ih)
+
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"list inbox is allowed", http.MethodGet, "/api/v1/inboxes", http.StatusOK},
+		{"get inbox is allowed", http.MethodGet, "/api/v1/inboxes/123", http.StatusOK},
+		{"list inbox requests is allowed", http.MethodGet, "/api/v1/inboxes/123/requests", http.StatusOK},
+		{"wait for requests is allowed", http.MethodPost, "/api/v1/inboxes/123/assertions/wait", http.StatusOK},
+		{"requests to the inbox are not checked", http.MethodPost, "/api/v1/inboxes/123/in", http.StatusOK},
+		{"create inbox is forbidden", http.MethodPost, "/api/v1/inboxes", http.StatusForbidden},
+		{"update inbox is forbidden", http.MethodPut, "/api/v1/inboxes/123", http.StatusForbidden},
+		{"delete inbox is forbidden", http.MethodDelete, "/api/v1/inboxes/123", http.StatusForbidden},
+		{"delete inbox requests is forbidden", http.MethodDelete, "/api/v1/inboxes/123/requests", http.StatusForbidden},
+		{"replay inbox request is forbidden", http.MethodPost, "/api/v1/inboxes/123/requests/1/replay", http.StatusForbidden},
+		{"retry dead letter is forbidden", http.MethodPost, "/api/v1/inboxes/123/dead-letters/456/retry", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, but got %d", tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
 func TestSetUserRoutes(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -170,3 +257,104 @@ func TestSetAPIKeyRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestSetAccountRoutesRejectAPIKeys(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(login.IS_LOGGED_WITH_API_KEY_CONTEXT_KEY, true)
+		c.Set(login.API_KEY_CONTEXT_KEY, model.APIKey{Permissions: model.NewFullAccessPermissions()})
+	})
+	lh := login_mock.NewMockLoginHandler(mockCtrl)
+	ah := apikey_mock.NewMockAPIKeyHandler(mockCtrl)
+	eh := email_mock.NewMockEmailHandler(mockCtrl)
+	returnOk := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	lh.EXPECT().HandleLoginUser(gomock.Any()).Do(returnOk).Times(1)
+
+	route.SetLoginRoutes(r, lh)
+	route.SetAPIKeyRoutes(r, ah)
+	route.SetEmailRoutes(r, eh)
+
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"get user is allowed", http.MethodGet, "/api/v1/auth/user", http.StatusOK},
+		{"delete user is forbidden", http.MethodDelete, "/api/v1/auth/user", http.StatusForbidden},
+		{"get API key is forbidden", http.MethodGet, "/api/v1/api-keys/123", http.StatusForbidden},
+		{"create API key is forbidden", http.MethodPost, "/api/v1/api-keys", http.StatusForbidden},
+		{"list API keys is forbidden", http.MethodGet, "/api/v1/api-keys", http.StatusForbidden},
+		{"delete API key is forbidden", http.MethodDelete, "/api/v1/api-keys/123", http.StatusForbidden},
+		{"get email preferences is forbidden", http.MethodGet, "/api/v1/email/preferences", http.StatusForbidden},
+		{"update email preferences is forbidden", http.MethodPut, "/api/v1/email/preferences", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, but got %d", tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestSetEmailRoutes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	eh := email_mock.NewMockEmailHandler(mockCtrl)
+	returnOk := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	eh.EXPECT().GetEmailPreferences(gomock.Any()).Do(returnOk).Times(1)
+	eh.EXPECT().UpdateEmailPreferences(gomock.Any()).Do(returnOk).Times(1)
+	eh.EXPECT().UnsubscribePage(gomock.Any()).Do(returnOk).Times(1)
+	eh.EXPECT().Unsubscribe(gomock.Any()).Do(returnOk).Times(1)
+
+	r := gin.New()
+	route.SetEmailRoutes(r, eh)
+
+	testCases := []struct {
+		desc      string
+		method    string
+		path      string
+		expectErr bool
+	}{
+		{"get email preferences path", http.MethodGet, "/api/v1/email/preferences", false},
+		{"update email preferences path", http.MethodPut, "/api/v1/email/preferences", false},
+		{"unsubscribe link path", http.MethodGet, "/api/v1/email/unsubscribe?user=1&token=2", false},
+		{"one-click unsubscribe path", http.MethodPost, "/api/v1/email/unsubscribe?user=1&token=2", false},
+		{"not defined route", http.MethodPost, "/api/v1/email/preferences", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if !tc.expectErr && w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+			if tc.expectErr && w.Code != http.StatusNotFound {
+				t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	DefaultHeader       = "X-Signature"
+	GitHubHeader        = "X-Hub-Signature-256"
+	GitHubHeaderSHA1    = "X-Hub-Signature"
+	StripeHeader        = "Stripe-Signature"
+	stripeSchemeVersion = "v1"
+)
+
+// HMAC returns the HMAC of the payload with the algorithm, sha256 when it is empty.
+func HMAC(algorithm string, secret string, payload []byte) ([]byte, error) {
+	newHash, err := hashFunc(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", model.SigningSHA256:
+		return sha256.New, nil
+	case model.SigningSHA1:
+		return sha1.New, nil
+	case model.SigningSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+func algorithmName(algorithm string) string {
+	if algorithm == "" {
+		return model.SigningSHA256
+	}
+	return algorithm
+}
+
+// Header returns the name of the signature header, the configured one or the usual one of the format.
+func Header(s model.Signing) string {
+	if s.Header != "" {
+		return s.Header
+	}
+	switch s.Format {
+	case model.SignatureFormatGitHub:
+		if s.Algorithm == model.SigningSHA1 {
+			return GitHubHeaderSHA1
+		}
+		return GitHubHeader
+	case model.SignatureFormatStripe:
+		return StripeHeader
+	default:
+		return DefaultHeader
+	}
+}
+
+// Sign returns the signature header name and value of the body, now is the timestamp of the formats that include it.
+func Sign(s model.Signing, body []byte, now time.Time) (string, string, error) {
+	var value string
+	switch s.Format {
+	case "", model.SignatureFormatHex:
+		mac, err := HMAC(s.Algorithm, s.Secret, body)
+		if err != nil {
+			return "", "", err
+		}
+		value = hex.EncodeToString(mac)
+	case model.SignatureFormatBase64:
+		mac, err := HMAC(s.Algorithm, s.Secret, body)
+		if err != nil {
+			return "", "", err
+		}
+		value = base64.StdEncoding.EncodeToString(mac)
+	case model.SignatureFormatGitHub:
+		mac, err := HMAC(s.Algorithm, s.Secret, body)
+		if err != nil {
+			return "", "", err
+		}
+		value = algorithmName(s.Algorithm) + "=" + hex.EncodeToString(mac)
+	case model.SignatureFormatStripe:
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		mac, err := HMAC(s.Algorithm, s.Secret, StripePayload(timestamp, body))
+		if err != nil {
+			return "", "", err
+		}
+		value = "t=" + timestamp + "," + stripeSchemeVersion + "=" + hex.EncodeToString(mac)
+	default:
+		return "", "", fmt.Errorf("unsupported signature format %q", s.Format)
+	}
+	return Header(s), value, nil
+}
+
+// StripePayload is the signed content of the Stripe format.
+func StripePayload(timestamp string, body []byte) []byte {
+	return append([]byte(timestamp+"."), body...)
+}
@@ -0,0 +1,95 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestSign(t *testing.T) {
+	// Example from the GitHub webhook documentation
+	secret := "It's a Secret to Everybody"
+	body := []byte("Hello, World!")
+	now := time.Unix(1700000000, 0)
+	testCases := []struct {
+		desc           string
+		signing        model.Signing
+		expectedHeader string
+		expectedValue  string
+	}{
+		{
+			"hex by default",
+			model.Signing{Secret: secret},
+			DefaultHeader,
+			"757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		},
+		{
+			"base64",
+			model.Signing{Secret: secret, Format: model.SignatureFormatBase64},
+			DefaultHeader,
+			"dXEH6g6yUJ/CESIczphLijdXC211hsIsRvQ3nIsEPhc=",
+		},
+		{
+			"github",
+			model.Signing{Secret: secret, Format: model.SignatureFormatGitHub},
+			GitHubHeader,
+			"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		},
+		{
+			"github sha1",
+			model.Signing{Secret: secret, Format: model.SignatureFormatGitHub, Algorithm: model.SigningSHA1},
+			GitHubHeaderSHA1,
+			"sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59",
+		},
+		{
+			"custom header",
+			model.Signing{Secret: secret, Header: "X-Custom"},
+			"X-Custom",
+			"757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		},
+		{
+			"stripe",
+			model.Signing{Secret: secret, Format: model.SignatureFormatStripe},
+			StripeHeader,
+			"t=1700000000,v1=" + hexHMAC(secret, "1700000000.Hello, World!"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			header, value, err := Sign(tc.signing, body, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t_util.AssertStringEquals(t, header, tc.expectedHeader)
+			t_util.AssertStringEquals(t, value, tc.expectedValue)
+		})
+	}
+}
+
+func TestSignSHA512Length(t *testing.T) {
+	_, value, err := Sign(model.Signing{Secret: "s", Algorithm: model.SigningSHA512}, []byte("body"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t_util.AssertEquals(t, len(value), 128)
+}
+
+func TestSignUnsupported(t *testing.T) {
+	if _, _, err := Sign(model.Signing{Secret: "s", Algorithm: "md5"}, nil, time.Now()); err == nil {
+		t.Error("Expected an error for an unsupported algorithm")
+	}
+	if _, _, err := Sign(model.Signing{Secret: "s", Format: "jwt"}, nil, time.Now()); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func hexHMAC(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
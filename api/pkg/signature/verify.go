@@ -0,0 +1,203 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+const (
+	SlackHeader          = "X-Slack-Signature"
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+	ShopifyHeader        = "X-Shopify-Hmac-Sha256"
+	slackVersion         = "v0"
+)
+
+// Verify checks the signature of a received request with the verification settings of its inbox.
+func Verify(v model.Verification, headers http.Header, body []byte, now time.Time) model.VerificationResult {
+	result := model.VerificationResult{Provider: v.Provider}
+	var err error
+	switch v.Provider {
+	case model.VerificationGitHub:
+		err = verifyGitHub(v, headers, body)
+	case model.VerificationStripe:
+		err = verifyStripe(v.Algorithm, v.Secret, headers.Get(StripeHeader), body, now, tolerance(v))
+	case model.VerificationSlack:
+		err = verifySlack(v, headers, body, now)
+	case model.VerificationShopify:
+		err = verifyShopify(v, headers, body)
+	case model.VerificationCustom:
+		err = verifyCustom(v, headers, body, now)
+	default:
+		err = fmt.Errorf("unsupported provider %q", v.Provider)
+	}
+	var missing missingError
+	switch {
+	case err == nil:
+		result.Status = model.VerificationValid
+	case errors.As(err, &missing):
+		result.Status = model.VerificationMissing
+		result.Reason = err.Error()
+	default:
+		result.Status = model.VerificationInvalid
+		result.Reason = err.Error()
+	}
+	return result
+}
+
+type missingError struct {
+	header string
+}
+
+func (e missingError) Error() string {
+	return fmt.Sprintf("%s header is missing", e.header)
+}
+
+func tolerance(v model.Verification) time.Duration {
+	seconds := v.ToleranceSeconds
+	if seconds <= 0 {
+		seconds = config.GetInt(config.VerificationToleranceSeconds)
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func verifyGitHub(v model.Verification, headers http.Header, body []byte) error {
+	value, algorithm := headers.Get(GitHubHeader), model.SigningSHA256
+	if value == "" && headers.Get(GitHubHeaderSHA1) != "" {
+		value, algorithm = headers.Get(GitHubHeaderSHA1), model.SigningSHA1
+	}
+	if value == "" {
+		return missingError{header: GitHubHeader}
+	}
+	return verifyPrefixed(algorithm, v.Secret, value, algorithm+"=", body)
+}
+
+func verifySlack(v model.Verification, headers http.Header, body []byte, now time.Time) error {
+	value := headers.Get(SlackHeader)
+	if value == "" {
+		return missingError{header: SlackHeader}
+	}
+	timestamp := headers.Get(SlackTimestampHeader)
+	if timestamp == "" {
+		return missingError{header: SlackTimestampHeader}
+	}
+	if err := checkTimestamp(timestamp, now, tolerance(v)); err != nil {
+		return err
+	}
+	payload := append([]byte(slackVersion+":"+timestamp+":"), body...)
+	return verifyPrefixed(model.SigningSHA256, v.Secret, value, slackVersion+"=", payload)
+}
+
+func verifyShopify(v model.Verification, headers http.Header, body []byte) error {
+	value := headers.Get(ShopifyHeader)
+	if value == "" {
+		return missingError{header: ShopifyHeader}
+	}
+	return verifyBase64(model.SigningSHA256, v.Secret, value, body)
+}
+
+func verifyCustom(v model.Verification, headers http.Header, body []byte, now time.Time) error {
+	s := model.Signing{Secret: v.Secret, Algorithm: v.Algorithm, Format: v.Format, Header: v.Header}
+	header := Header(s)
+	value := headers.Get(header)
+	if value == "" {
+		return missingError{header: header}
+	}
+	switch v.Format {
+	case "", model.SignatureFormatHex:
+		return verifyPrefixed(v.Algorithm, v.Secret, value, "", body)
+	case model.SignatureFormatBase64:
+		return verifyBase64(v.Algorithm, v.Secret, value, body)
+	case model.SignatureFormatGitHub:
+		return verifyPrefixed(v.Algorithm, v.Secret, value, algorithmName(v.Algorithm)+"=", body)
+	case model.SignatureFormatStripe:
+		return verifyStripe(v.Algorithm, v.Secret, value, body, now, tolerance(v))
+	default:
+		return fmt.Errorf("unsupported signature format %q", v.Format)
+	}
+}
+
+// verifyPrefixed checks a hex signature that follows prefix.
+func verifyPrefixed(algorithm, secret, value, prefix string, payload []byte) error {
+	signature, found := strings.CutPrefix(value, prefix)
+	if !found {
+		return fmt.Errorf("signature does not start with %q", prefix)
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("signature is not hex encoded")
+	}
+	return compare(algorithm, secret, payload, got)
+}
+
+// verifyBase64 checks a base64 signature.
+func verifyBase64(algorithm, secret, value string, payload []byte) error {
+	got, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("signature is not base64 encoded")
+	}
+	return compare(algorithm, secret, payload, got)
+}
+
+func verifyStripe(algorithm, secret, value string, body []byte, now time.Time, tolerance time.Duration) error {
+	if value == "" {
+		return missingError{header: StripeHeader}
+	}
+	timestamp := ""
+	signatures := [][]byte{}
+	for _, part := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = val
+		case stripeSchemeVersion:
+			if sig, err := hex.DecodeString(val); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("signature has no timestamp or %s signature", stripeSchemeVersion)
+	}
+	if err := checkTimestamp(timestamp, now, tolerance); err != nil {
+		return err
+	}
+	for _, sig := range signatures {
+		if compare(algorithm, secret, StripePayload(timestamp, body), sig) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match")
+}
+
+func checkTimestamp(timestamp string, now time.Time, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("timestamp %q is not valid", timestamp)
+	}
+	if math.Abs(float64(now.Unix()-seconds)) > tolerance.Seconds() {
+		return fmt.Errorf("timestamp is outside the tolerance of %s", tolerance)
+	}
+	return nil
+}
+
+func compare(algorithm, secret string, payload []byte, got []byte) error {
+	expected, err := HMAC(algorithm, secret, payload)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, got) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jesusnoseq/request-inbox/pkg/config"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestVerify(t *testing.T) {
+	config.LoadConfig(config.Test)
+	secret := "It's a Secret to Everybody"
+	body := []byte("Hello, World!")
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)
+	github := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	stripe := "t=" + timestamp + ",v1=" + hexHMAC(secret, timestamp+".Hello, World!")
+	staleStripe := "t=" + stale + ",v1=" + hexHMAC(secret, stale+".Hello, World!")
+	slack := "v0=" + hexHMAC(secret, "v0:"+timestamp+":Hello, World!")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	shopify := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	testCases := []struct {
+		desc           string
+		verification   model.Verification
+		headers        map[string]string
+		expectedStatus string
+	}{
+		{"github valid", model.Verification{Provider: model.VerificationGitHub}, map[string]string{GitHubHeader: github}, model.VerificationValid},
+		{"github sha1 valid", model.Verification{Provider: model.VerificationGitHub}, map[string]string{GitHubHeaderSHA1: "sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59"}, model.VerificationValid},
+		{"github invalid", model.Verification{Provider: model.VerificationGitHub}, map[string]string{GitHubHeader: "sha256=00"}, model.VerificationInvalid},
+		{"github missing", model.Verification{Provider: model.VerificationGitHub}, map[string]string{}, model.VerificationMissing},
+		{"stripe valid", model.Verification{Provider: model.VerificationStripe}, map[string]string{StripeHeader: stripe}, model.VerificationValid},
+		{"stripe with several signatures", model.Verification{Provider: model.VerificationStripe}, map[string]string{StripeHeader: stripe + ",v1=00,v0=11"}, model.VerificationValid},
+		{"stripe stale", model.Verification{Provider: model.VerificationStripe}, map[string]string{StripeHeader: staleStripe}, model.VerificationInvalid},
+		{"stripe stale within tolerance", model.Verification{Provider: model.VerificationStripe, ToleranceSeconds: 7200}, map[string]string{StripeHeader: staleStripe}, model.VerificationValid},
+		{"stripe missing", model.Verification{Provider: model.VerificationStripe}, map[string]string{}, model.VerificationMissing},
+		{"slack valid", model.Verification{Provider: model.VerificationSlack}, map[string]string{SlackHeader: slack, SlackTimestampHeader: timestamp}, model.VerificationValid},
+		{"slack without timestamp", model.Verification{Provider: model.VerificationSlack}, map[string]string{SlackHeader: slack}, model.VerificationMissing},
+		{"slack invalid", model.Verification{Provider: model.VerificationSlack}, map[string]string{SlackHeader: slack, SlackTimestampHeader: stale}, model.VerificationInvalid},
+		{"shopify valid", model.Verification{Provider: model.VerificationShopify}, map[string]string{ShopifyHeader: shopify}, model.VerificationValid},
+		{"shopify invalid", model.Verification{Provider: model.VerificationShopify}, map[string]string{ShopifyHeader: "AAAA"}, model.VerificationInvalid},
+		{"custom hex", model.Verification{Provider: model.VerificationCustom, Header: "X-Sig"}, map[string]string{"X-Sig": github[len("sha256="):]}, model.VerificationValid},
+		{"custom base64", model.Verification{Provider: model.VerificationCustom, Format: model.SignatureFormatBase64}, map[string]string{DefaultHeader: shopify}, model.VerificationValid},
+		{"custom missing", model.Verification{Provider: model.VerificationCustom, Header: "X-Sig"}, map[string]string{}, model.VerificationMissing},
+		{"unknown provider", model.Verification{Provider: "gitlab"}, map[string]string{}, model.VerificationInvalid},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.verification.IsEnabled = true
+			tc.verification.Secret = secret
+			headers := http.Header{}
+			for k, v := range tc.headers {
+				headers.Set(k, v)
+			}
+
+			result := Verify(tc.verification, headers, body, now)
+
+			t_util.AssertStringEquals(t, result.Status, tc.expectedStatus)
+			t_util.AssertStringEquals(t, result.Provider, tc.verification.Provider)
+			if tc.expectedStatus != model.VerificationValid && result.Reason == "" {
+				t.Error("Expected a reason")
+			}
+		})
+	}
+}
+
+func TestVerifySignedCallback(t *testing.T) {
+	for _, format := range []string{model.SignatureFormatHex, model.SignatureFormatBase64, model.SignatureFormatGitHub, model.SignatureFormatStripe} {
+		t.Run(format, func(t *testing.T) {
+			signing := model.Signing{Secret: "secret", Algorithm: model.SigningSHA512, Format: format}
+			header, value, err := Sign(signing, []byte("body"), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := http.Header{}
+			headers.Set(header, value)
+			v := model.Verification{Provider: model.VerificationCustom, Secret: "secret", Algorithm: model.SigningSHA512, Format: format}
+
+			result := Verify(v, headers, []byte("body"), time.Now())
+
+			t_util.AssertStringEquals(t, result.Status, model.VerificationValid)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"path"
+	"strings"
+
+	"github.com/jesusnoseq/request-inbox/pkg/collection"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// Filter selects the requests delivered to a live subscriber. Empty fields match everything.
+type Filter struct {
+	Method string `json:"method,omitempty"`
+	// Path is a glob (see path.Match) over the path sent after "/in", e.g. "/orders/*"
+	Path string `json:"path,omitempty"`
+	// Headers must be present in the request, an empty value only checks the presence
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (f Filter) Validate() error {
+	if _, err := path.Match(f.Path, ""); err != nil {
+		return fmt.Errorf("invalid path glob %q: %w", f.Path, err)
+	}
+	for k := range f.Headers {
+		if strings.TrimSpace(k) == "" {
+			return errors.New("header name cannot be empty")
+		}
+	}
+	return nil
+}
+
+func (f Filter) Match(req model.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, req.Method) {
+		return false
+	}
+	if f.Path != "" {
+		p := req.InboxPath()
+		if p == "" {
+			p = "/"
+		}
+		if ok, _ := path.Match(f.Path, p); !ok {
+			return false
+		}
+	}
+	for k, v := range f.Headers {
+		values, ok := req.Headers[textproto.CanonicalMIMEHeaderKey(k)]
+		if !ok || (v != "" && !collection.SliceContains(values, v)) {
+			return false
+		}
+	}
+	return true
+}
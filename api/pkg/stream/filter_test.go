@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestFilterMatch(t *testing.T) {
+	req := model.Request{
+		Method:  "POST",
+		URI:     "/api/v1/inboxes/c7f1/in/orders/42?expand=true",
+		Headers: map[string][]string{"X-Event": {"created"}, "Content-Type": {"application/json"}},
+	}
+	testCases := []struct {
+		desc   string
+		filter Filter
+		want   bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"method", Filter{Method: "post"}, true},
+		{"other method", Filter{Method: "GET"}, false},
+		{"path glob", Filter{Path: "/orders/*"}, true},
+		{"exact path", Filter{Path: "/orders/42"}, true},
+		{"glob does not cross segments", Filter{Path: "/*"}, false},
+		{"other path", Filter{Path: "/users/*"}, false},
+		{"header value", Filter{Headers: map[string]string{"x-event": "created"}}, true},
+		{"header presence", Filter{Headers: map[string]string{"content-type": ""}}, true},
+		{"other header value", Filter{Headers: map[string]string{"X-Event": "deleted"}}, false},
+		{"missing header", Filter{Headers: map[string]string{"X-Other": ""}}, false},
+		{"all filters", Filter{Method: "POST", Path: "/orders/*", Headers: map[string]string{"X-Event": "created"}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t_util.AssertEquals(t, tc.filter.Match(req), tc.want)
+		})
+	}
+}
+
+func TestFilterMatchInboxRoot(t *testing.T) {
+	req := model.Request{Method: "GET", URI: "/api/v1/inboxes/c7f1/in"}
+	t_util.AssertTrue(t, Filter{Path: "/"}.Match(req))
+}
+
+func TestFilterValidate(t *testing.T) {
+	t_util.AssertNoError(t, Filter{Path: "/orders/*"}.Validate())
+	t_util.AssertError(t, Filter{Path: "[a-"}.Validate())
+	t_util.AssertError(t, Filter{Headers: map[string]string{" ": "value"}}.Validate())
+}
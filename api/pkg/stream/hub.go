@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+)
+
+// SubscriptionBufferSize is the number of requests a subscriber can fall behind
+// before it is dropped. Dropped subscribers are expected to resume from storage.
+const SubscriptionBufferSize = 64
+
+// Hub is an in-process pub/sub of the requests received by each inbox.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	hub     *Hub
+	inboxID uuid.UUID
+	events  chan model.Request
+	once    sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[uuid.UUID]map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Subscribe(inboxID uuid.UUID) *Subscription {
+	s := &Subscription{
+		hub:     h,
+		inboxID: inboxID,
+		events:  make(chan model.Request, SubscriptionBufferSize),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[inboxID]; !ok {
+		h.subscribers[inboxID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[inboxID][s] = struct{}{}
+	return s
+}
+
+// Publish sends the request to every subscriber of the inbox without blocking.
+// Subscribers whose buffer is full are closed.
+func (h *Hub) Publish(inboxID uuid.UUID, req model.Request) {
+	h.mu.RLock()
+	slow := []*Subscription{}
+	for s := range h.subscribers[inboxID] {
+		select {
+		case s.events <- req:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		s.Close()
+	}
+}
+
+func (h *Hub) SubscriberCount(inboxID uuid.UUID) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[inboxID])
+}
+
+// Events returns the channel of published requests, it is closed when the subscription ends.
+func (s *Subscription) Events() <-chan model.Request {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+		delete(s.hub.subscribers[s.inboxID], s)
+		if len(s.hub.subscribers[s.inboxID]) == 0 {
+			delete(s.hub.subscribers, s.inboxID)
+		}
+		close(s.events)
+	})
+}
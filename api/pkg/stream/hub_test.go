@@ -0,0 +1,58 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jesusnoseq/request-inbox/pkg/model"
+	"github.com/jesusnoseq/request-inbox/pkg/t_util"
+)
+
+func TestHubPublish(t *testing.T) {
+	h := NewHub()
+	inboxID := uuid.New()
+	s1 := h.Subscribe(inboxID)
+	defer s1.Close()
+	s2 := h.Subscribe(inboxID)
+	defer s2.Close()
+	other := h.Subscribe(uuid.New())
+	defer other.Close()
+
+	h.Publish(inboxID, model.GenerateRequest(7))
+
+	t_util.AssertEquals(t, (<-s1.Events()).ID, 7)
+	t_util.AssertEquals(t, (<-s2.Events()).ID, 7)
+	t_util.AssertEquals(t, len(other.Events()), 0)
+}
+
+func TestHubSubscriptionClose(t *testing.T) {
+	h := NewHub()
+	inboxID := uuid.New()
+	s := h.Subscribe(inboxID)
+	t_util.AssertEquals(t, h.SubscriberCount(inboxID), 1)
+
+	s.Close()
+	s.Close()
+
+	t_util.AssertEquals(t, h.SubscriberCount(inboxID), 0)
+	_, ok := <-s.Events()
+	t_util.AssertFalse(t, ok, "events channel should be closed")
+	h.Publish(inboxID, model.GenerateRequest(1))
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	h := NewHub()
+	inboxID := uuid.New()
+	s := h.Subscribe(inboxID)
+
+	for i := 0; i <= SubscriptionBufferSize; i++ {
+		h.Publish(inboxID, model.GenerateRequest(i))
+	}
+
+	t_util.AssertEquals(t, h.SubscriberCount(inboxID), 0)
+	received := 0
+	for range s.Events() {
+		received++
+	}
+	t_util.AssertEquals(t, received, SubscriptionBufferSize)
+}
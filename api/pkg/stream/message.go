@@ -0,0 +1,25 @@
+package stream
+
+import "github.com/jesusnoseq/request-inbox/pkg/model"
+
+type MessageType string
+
+const (
+	// Sent by clients
+	SubscribeMessage   MessageType = "subscribe"
+	UnsubscribeMessage MessageType = "unsubscribe"
+	// Sent by the server
+	SubscribedMessage   MessageType = "subscribed"
+	UnsubscribedMessage MessageType = "unsubscribed"
+	RequestMessage      MessageType = "request"
+	HeartbeatMessage    MessageType = "heartbeat"
+	ErrorMessage        MessageType = "error"
+)
+
+// Message is the envelope exchanged over the live-tail WebSocket.
+type Message struct {
+	Type    MessageType    `json:"type"`
+	Filter  *Filter        `json:"filter,omitempty"`
+	Request *model.Request `json:"request,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}